@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -171,9 +172,14 @@ func (b *dockerBuilder) copyBuild(workDir, dstDir string) error {
 		return fmt.Errorf("create output directory: %w", err)
 	}
 
-	matches, err := filepathx.WalkMatch(src, "*.deb")
-	if err != nil {
-		return fmt.Errorf("glob built extensions: %w", err)
+	var matches []string
+	for _, f := range SupportedFormats {
+		m, err := filepathx.WalkMatch(src, formatFilePattern(f))
+		if err != nil {
+			return fmt.Errorf("glob built extensions: %w", err)
+		}
+
+		matches = append(matches, m...)
 	}
 
 	for _, match := range matches {
@@ -200,7 +206,7 @@ func (b *dockerBuilder) dockerBakeArgs(ext Extension, targets []string, extraArg
 	)
 
 	for _, builder := range ext.Builders.Available() {
-		bakeTargetName := dockerBakeTargetFromBuilderID(builder.Type)
+		bakeTargetName := dockerBakeTarget(builder.Type)
 
 		buildTargetArgs = append(
 			buildTargetArgs,
@@ -257,6 +263,15 @@ func (b *dockerBuilder) dockerBakeArgs(ext Extension, targets []string, extraArg
 	return args
 }
 
+// formatFilePattern returns the file name pattern of the built artifacts of a format.
+func formatFilePattern(f Format) string {
+	if f == FormatTar {
+		return "*.tar.gz"
+	}
+
+	return "*." + string(f)
+}
+
 func dockerPlatforms(ext Extension) string {
 	var platform []string
 	for _, arch := range ext.Arch {
@@ -280,49 +295,44 @@ func buildSHA(ext Extension) string {
 }
 
 func dockerDebugImage(p Platform, ext Extension) string {
-	imagePath := strings.ReplaceAll(string(p), "_", "/")
-	return fmt.Sprintf("pgxman/%s/%s:debug", imagePath, ext.Name)
+	info, _ := p.Info()
+	return fmt.Sprintf("pgxman/%s/%s:debug", info.ArtifactDir(), ext.Name)
 }
 
 func dockerBakeTargets(ext Extension) []string {
 	var result []string
 	for _, builder := range ext.Builders.Available() {
-		result = append(result, dockerBakeTargetFromBuilderID(builder.Type))
+		result = append(result, dockerBakeTarget(builder.Type))
 	}
 
 	return result
 }
 
-func dockerBakeTargetFromBuilderID(p Platform) string {
-	return strings.ReplaceAll(string(p), "_", "-")
+func dockerBakeTarget(p Platform) string {
+	info, _ := p.Info()
+	return info.BakeTarget
 }
 
 type dockerFileExtension struct {
 	Extension
 }
 
-func (e dockerFileExtension) ExportDebianBookwormArtifacts() bool {
-	if builders := e.Builders; builders != nil {
-		return builders.HasBuilder(PlatformDebianBookworm)
-	}
-
-	return false
-}
-
-func (e dockerFileExtension) ExportUbuntuJammyArtifacts() bool {
+// ExportPlatforms returns the platforms whose packages are exported.
+func (e dockerFileExtension) ExportPlatforms() []PlatformInfo {
+	var result []PlatformInfo
 	if builders := e.Builders; builders != nil {
-		return builders.HasBuilder(PlatformUbuntuJammy)
+		for _, info := range Platforms {
+			if builders.HasBuilder(info.Platform) {
+				result = append(result, info)
+			}
+		}
 	}
 
-	return false
+	return result
 }
 
-func (e dockerFileExtension) ExportUbuntuNobleArtifacts() bool {
-	if builders := e.Builders; builders != nil {
-		return builders.HasBuilder(PlatformUbuntuNoble)
-	}
-
-	return false
+func (e dockerFileExtension) ExportTarArtifacts() bool {
+	return slices.Contains(e.Formats, FormatTar)
 }
 
 type dockerFileTemplater struct {
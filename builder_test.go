@@ -0,0 +1,55 @@
+package pgxman
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pgxman/pgxman/internal/template/docker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerFileTemplater(t *testing.T) {
+	assert := assert.New(t)
+
+	ext := NewDefaultExtension()
+	ext.PGVersions = []PGVersion{PGVersion16}
+	ext.Formats = []Format{FormatDeb, FormatRpm, FormatTar}
+	ext.Builders = &ExtensionBuilders{
+		DebianBullseye: &AptExtensionBuilder{},
+		RockyLinux9:    &RpmExtensionBuilder{},
+	}
+
+	content, err := docker.FS.ReadFile("Dockerfile.export")
+	assert.NoError(err)
+
+	var out bytes.Buffer
+	assert.NoError(dockerFileTemplater{ext}.Render(content, &out))
+	assert.Equal(`# syntax=docker/dockerfile:1
+
+FROM ubuntu AS merge
+
+ARG WORKSPACE_DIR
+COPY --from=debian-bullseye ${WORKSPACE_DIR}/target/16/*.deb  /out/debian/bullseye/
+COPY --from=debian-bullseye ${WORKSPACE_DIR}/target/16/pgxman-*.tar.gz  /out/debian/bullseye/
+COPY --from=rockylinux-9 ${WORKSPACE_DIR}/target/16/*.rpm  /out/rockylinux/9/
+COPY --from=rockylinux-9 ${WORKSPACE_DIR}/target/16/pgxman-*.tar.gz  /out/rockylinux/9/
+
+FROM ubuntu AS export
+
+COPY --from=merge /out/ .
+`, out.String())
+
+	content, err = docker.FS.ReadFile("docker-bake.hcl")
+	assert.NoError(err)
+
+	out.Reset()
+	assert.NoError(dockerFileTemplater{ext}.Render(content, &out))
+	assert.Contains(out.String(), `        debian-bullseye = "target:debian-bullseye"
+        rockylinux-9 = "target:rockylinux-9"
+    }`)
+	assert.Contains(out.String(), `target "debian-bullseye" {
+    args = {
+        BUILD_IMAGE = "ghcr.io/pgxman/builder/debian/bullseye:${TAG}"
+    }`)
+	assert.NotContains(out.String(), "debian-bookworm")
+}
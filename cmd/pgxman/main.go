@@ -14,8 +14,9 @@ import (
 type exitCode int
 
 const (
-	exitOK    exitCode = 0
-	exitError exitCode = 1
+	exitOK       exitCode = 0
+	exitError    exitCode = 1
+	exitOutdated exitCode = 2
 )
 
 func main() {
@@ -32,6 +33,10 @@ func mainRun() exitCode {
 			return exitError
 		}
 
+		if errors.Is(err, cmdutil.OutdatedError) {
+			return exitOutdated
+		}
+
 		printError(os.Stderr, err)
 		return exitError
 	}
@@ -8,13 +8,25 @@ group "default" {
 }
 
 group "builder" {
-    targets = ["builder-debian-bookworm", "builder-ubuntu-jammy", "builder-ubuntu-noble"]
+    targets = ["builder-debian-bullseye", "builder-debian-bookworm", "builder-ubuntu-focal", "builder-ubuntu-jammy", "builder-ubuntu-noble", "builder-rockylinux-9"]
 }
 
 group "runner" {
     targets = ["runner-postgres-16", "runner-postgres-15", "runner-postgres-14", "runner-postgres-13"]
 }
 
+target "builder-debian-bullseye" {
+    inherits = ["docker-metadata-action", "base-builder-debian"]
+
+    contexts = {
+        debian_base = "docker-image://postgres:16-bullseye"
+    }
+
+    args = {
+        CLANG_VERSION = "13"
+    }
+}
+
 target "builder-debian-bookworm" {
     inherits = ["docker-metadata-action", "base-builder-debian"]
 
@@ -27,6 +39,18 @@ target "builder-debian-bookworm" {
     }
 }
 
+target "builder-ubuntu-focal" {
+    inherits = ["docker-metadata-action", "base-builder-debian"]
+
+    contexts = {
+        debian_base = "docker-image://ubuntu:focal"
+    }
+
+    args = {
+        CLANG_VERSION = "12"
+    }
+}
+
 target "builder-ubuntu-jammy" {
     inherits = ["docker-metadata-action", "base-builder-debian"]
 
@@ -51,6 +75,18 @@ target "builder-ubuntu-noble" {
     }
 }
 
+target "builder-rockylinux-9" {
+    inherits = ["docker-metadata-action", "base-builder-rockylinux"]
+
+    contexts = {
+        rockylinux_base = "docker-image://rockylinux:9"
+    }
+
+    args = {
+        EL_VERSION = "9"
+    }
+}
+
 target "runner-postgres-16" {
     inherits = ["docker-metadata-action", "base-runner"]
 
@@ -100,6 +136,14 @@ target "base-builder-debian" {
     dockerfile = "dockerfiles/builder/Dockerfile.debian"
 }
 
+target "base-builder-rockylinux" {
+    contexts = {
+        pgxman = "target:pgxman"
+    }
+
+    dockerfile = "dockerfiles/builder/Dockerfile.rockylinux"
+}
+
 target "base-runner" {
     contexts = {
         pgxman = "target:pgxman"
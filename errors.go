@@ -3,6 +3,8 @@ package pgxman
 import "fmt"
 
 var (
-	ErrRootAccessRequired = fmt.Errorf("root access is required")
-	ErrConflictExtension  = fmt.Errorf("conflict extension")
+	ErrRootAccessRequired    = fmt.Errorf("root access is required")
+	ErrConflictExtension     = fmt.Errorf("conflict extension")
+	ErrExtensionNotInstalled = fmt.Errorf("extension not installed")
+	ErrRolledBack            = fmt.Errorf("changes are rolled back")
 )
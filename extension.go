@@ -1,13 +1,18 @@
 package pgxman
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 
@@ -15,7 +20,6 @@ import (
 	"github.com/Masterminds/semver/v3"
 	"github.com/github/go-spdx/v2/spdxexp"
 	"github.com/mholt/archiver/v3"
-	"github.com/pgxman/pgxman/internal/osx"
 	"golang.org/x/exp/slices"
 	"sigs.k8s.io/yaml"
 )
@@ -25,28 +29,9 @@ func NewDefaultExtension() Extension {
 		APIVersion: DefaultExtensionAPIVersion,
 		PGVersions: SupportedPGVersions,
 		ExtensionOverridable: ExtensionOverridable{
-			Arch:    []Arch{Arch(runtime.GOARCH)},
-			Formats: SupportedFormats,
-			Builders: &ExtensionBuilders{
-				DebianBookworm: &AptExtensionBuilder{
-					ExtensionBuilder: ExtensionBuilder{
-						Type:  PlatformDebianBookworm,
-						Image: fmt.Sprintf("%s:%s", extensionBuilderImages[PlatformDebianBookworm], ImageTag()),
-					},
-				},
-				UbuntuJammy: &AptExtensionBuilder{
-					ExtensionBuilder: ExtensionBuilder{
-						Type:  PlatformUbuntuJammy,
-						Image: fmt.Sprintf("%s:%s", extensionBuilderImages[PlatformUbuntuJammy], ImageTag()),
-					},
-				},
-				UbuntuNoble: &AptExtensionBuilder{
-					ExtensionBuilder: ExtensionBuilder{
-						Type:  PlatformUbuntuNoble,
-						Image: fmt.Sprintf("%s:%s", extensionBuilderImages[PlatformUbuntuNoble], ImageTag()),
-					},
-				},
-			},
+			Arch:     []Arch{Arch(runtime.GOARCH)},
+			Formats:  DefaultFormats,
+			Builders: defaultExtensionBuilders(),
 		},
 	}
 }
@@ -114,6 +99,10 @@ type ExtensionOverridable struct {
 	Readme            string             `json:"readme,omitempty"`
 	BuildDependencies []string           `json:"buildDependencies,omitempty"`
 	RunDependencies   []string           `json:"runDependencies,omitempty"`
+	// SharedPreloadLibraries are the libraries that must be added to shared_preload_libraries for the extension to work
+	SharedPreloadLibraries []string `json:"sharedPreloadLibraries,omitempty"`
+	// SourceSha256 is the SHA-256 checksum of the HTTP source. The build fails if the downloaded source doesn't match.
+	SourceSha256 string `json:"sourceSha256,omitempty"`
 
 	// internal
 	Path string `json:"-"`
@@ -122,8 +111,14 @@ type ExtensionOverridable struct {
 func (ext ExtensionOverridable) Validate() error {
 	var err error
 
-	if _, e := ext.ParseSource(); e != nil {
+	if source, e := ext.ParseSource(); e != nil {
 		err = errors.Join(err, fmt.Errorf("invalid source: %w", e))
+	} else if ext.SourceSha256 != "" {
+		if _, ok := source.(*httpExtensionSource); !ok {
+			err = errors.Join(err, fmt.Errorf("sourceSha256 is only supported for http sources"))
+		} else if !sha256Regexp.MatchString(ext.SourceSha256) {
+			err = errors.Join(err, fmt.Errorf("invalid sourceSha256: %s", ext.SourceSha256))
+		}
 	}
 
 	if ext.Version == "" {
@@ -150,18 +145,21 @@ func (ext ExtensionOverridable) Validate() error {
 		}
 	}
 
+	for _, lib := range ext.SharedPreloadLibraries {
+		if lib == "" || strings.ContainsAny(lib, ",'\"") {
+			err = errors.Join(err, fmt.Errorf("invalid shared preload library: %q", lib))
+		}
+	}
+
 	if ext.Builders == nil {
 		err = errors.Join(err, fmt.Errorf("at least one extension builder is required"))
 	} else {
-		builders := ext.Builders.Available()
-		if len(builders) == 0 {
+		if len(ext.Builders.Available()) == 0 {
 			err = errors.Join(err, fmt.Errorf("at least one extension builder is required"))
 		}
 
-		for _, builder := range builders {
-			if e := builder.Validate(); e != nil {
-				err = errors.Join(err, fmt.Errorf("builders.%s has errors: %w", builder.Type, e))
-			}
+		if e := ext.Builders.Validate(); e != nil {
+			err = errors.Join(err, e)
 		}
 	}
 
@@ -177,6 +175,10 @@ func (ext ExtensionOverridable) ParseSource() (ExtensionSource, error) {
 		return nil, fmt.Errorf("source is required")
 	}
 
+	if strings.HasPrefix(ext.Source, gitSourcePrefix) {
+		return ext.parseGitSource()
+	}
+
 	u, err := url.ParseRequestURI(ext.Source)
 	if err != nil {
 		return nil, err
@@ -184,15 +186,15 @@ func (ext ExtensionOverridable) ParseSource() (ExtensionSource, error) {
 
 	supportedScheme := []string{"http", "https", "file"}
 	if !slices.Contains(supportedScheme, u.Scheme) {
-		return nil, fmt.Errorf("source only supports %s", strings.Join(supportedScheme, ", "))
+		return nil, fmt.Errorf("source only supports %s, and git+ of %s", strings.Join(supportedScheme, ", "), strings.Join(supportedGitSourceSchemes, ", "))
 	}
 
 	if u.Scheme == "http" || u.Scheme == "https" {
-		if !strings.HasSuffix(u.Path, ".tar.gz") {
-			return nil, fmt.Errorf("http source only supports tar.gz format: %s", u.Path)
+		if !isSourceArchive(u.Path) {
+			return nil, fmt.Errorf("http source must be an archive, e.g. tar.gz, tgz, tar.xz, tar.bz2 or zip: %s", u.Path)
 		}
 
-		return &httpExtensionSource{URL: u.String()}, nil
+		return &httpExtensionSource{URL: u.String(), Sha256: ext.SourceSha256}, nil
 	}
 
 	var path string
@@ -206,6 +208,105 @@ func (ext ExtensionOverridable) ParseSource() (ExtensionSource, error) {
 	return &fileExtensionSource{Dir: filepath.Clean(path)}, nil
 }
 
+// parseGitSource parses a git source in the format of git+URL#REF, e.g. git+https://github.com/org/repo.git#v1.0.0.
+// REF is a branch, a tag or a commit, and defaults to the HEAD of the repository.
+func (ext ExtensionOverridable) parseGitSource() (ExtensionSource, error) {
+	u, err := url.Parse(strings.TrimPrefix(ext.Source, gitSourcePrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(supportedGitSourceSchemes, u.Scheme) {
+		return nil, fmt.Errorf("git source only supports %s", strings.Join(supportedGitSourceSchemes, ", "))
+	}
+
+	ref := u.Fragment
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.Scheme == "file" && !filepath.IsAbs(u.Path) {
+		// relative path to the buildkit file
+		u.Path = filepath.Join(filepath.Dir(ext.Path), u.Path)
+	}
+
+	return &gitExtensionSource{URL: u.String(), Ref: ref}, nil
+}
+
+// ArchiveSource archives the source into dst. An archive that is already in dst is only reused
+// when it was archived from a source verified against the same SourceSha256. The checksum is
+// recorded in a file next to dst. The returned source is nil when the archive is reused.
+func (ext ExtensionOverridable) ArchiveSource(dst string) (ExtensionSource, error) {
+	checksumFile := dst + sourceChecksumSuffix
+	if b, err := os.ReadFile(checksumFile); err == nil && strings.EqualFold(string(b), ext.SourceSha256) {
+		if _, err := os.Stat(dst); err == nil {
+			return nil, nil
+		}
+	}
+
+	// the archive is not reused if archiving is interrupted
+	for _, f := range []string{checksumFile, dst} {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	source, err := ext.ParseSource()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := source.Archive(dst); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(checksumFile, []byte(ext.SourceSha256), 0644); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// SourceChecksum downloads the HTTP source and returns its SHA-256 checksum to be used as SourceSha256.
+func (ext ExtensionOverridable) SourceChecksum() (string, error) {
+	source, err := ext.ParseSource()
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := source.(*httpExtensionSource)
+	if !ok {
+		return "", fmt.Errorf("checksum is only supported for http sources: %s", ext.Source)
+	}
+
+	dir, err := os.MkdirTemp("", "pgxman-source-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, s.fileName())
+	if err := s.download(file); err != nil {
+		return "", err
+	}
+
+	return fileSha256(file)
+}
+
+func fileSha256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 type Extension struct {
 	ExtensionCommon
 	ExtensionOverridable
@@ -306,6 +407,14 @@ type ExtensionSource interface {
 	Archive(dst string) error
 }
 
+// RevisionSource is implemented by sources that resolve to an exact revision when they are archived,
+// e.g. a git tag resolves to a commit.
+type RevisionSource interface {
+	ExtensionSource
+	// Revision returns the revision of the last archive.
+	Revision() string
+}
+
 const DefaultExtensionAPIVersion = "v1"
 
 type Arch string
@@ -339,10 +448,15 @@ func (f Format) Validate() error {
 
 const (
 	FormatDeb Format = "deb"
+	FormatRpm Format = "rpm"
+	// FormatTar is a relocatable tarball of the installed files of the extension that is built in addition to
+	// the package of the platform, see Platform.Format
+	FormatTar Format = "tar"
 )
 
 var (
-	SupportedFormats = []Format{FormatDeb}
+	SupportedFormats = []Format{FormatDeb, FormatRpm, FormatTar}
+	DefaultFormats   = []Format{FormatDeb, FormatRpm}
 )
 
 const (
@@ -420,59 +534,130 @@ func (s BuildScript) Validate() error {
 	return nil
 }
 
-var (
-	extensionBuilderImages = map[Platform]string{
-		PlatformDebianBookworm: "ghcr.io/pgxman/builder/debian/bookworm",
-		PlatformUbuntuJammy:    "ghcr.io/pgxman/builder/ubuntu/jammy",
-		PlatformUbuntuNoble:    "ghcr.io/pgxman/builder/ubuntu/noble",
+type ExtensionBuilders struct {
+	DebianBullseye *AptExtensionBuilder `json:"debian:bullseye,omitempty"`
+	DebianBookworm *AptExtensionBuilder `json:"debian:bookworm,omitempty"`
+	UbuntuFocal    *AptExtensionBuilder `json:"ubuntu:focal,omitempty"`
+	UbuntuJammy    *AptExtensionBuilder `json:"ubuntu:jammy,omitempty"`
+	UbuntuNoble    *AptExtensionBuilder `json:"ubuntu:noble,omitempty"`
+	// RockyLinux9 builds RPM packages that are installable on RHEL-compatible 9 releases.
+	RockyLinux9 *RpmExtensionBuilder `json:"rockylinux:9,omitempty"`
+}
+
+// defaultExtensionBuilders returns the builders of the default platforms.
+func defaultExtensionBuilders() *ExtensionBuilders {
+	ebs := &ExtensionBuilders{}
+	for _, info := range Platforms {
+		if !info.Default {
+			continue
+		}
+
+		ebs.setBuilder(info.Platform, ExtensionBuilder{
+			Type:  info.Platform,
+			Image: fmt.Sprintf("%s:%s", info.BuilderImage, ImageTag()),
+		})
 	}
-)
 
-type ErrUnsupportedPlatform struct {
-	osVendor  string
-	osVersion string
+	return ebs
 }
 
-func (e *ErrUnsupportedPlatform) Error() string {
-	builder := e.osVendor
-	if e.osVersion != "" {
-		builder += ":" + e.osVersion
+// Builder returns the declared builder of the platform or nil.
+func (ebs ExtensionBuilders) Builder(p Platform) *ExtensionBuilder {
+	info, ok := p.Info()
+	if !ok {
+		return nil
 	}
 
-	return fmt.Sprintf("Unsupported platform: %s", builder)
+	switch {
+	case info.aptBuilder != nil:
+		if b := *info.aptBuilder(&ebs); b != nil {
+			return &b.ExtensionBuilder
+		}
+	case info.rpmBuilder != nil:
+		if b := *info.rpmBuilder(&ebs); b != nil {
+			return &b.ExtensionBuilder
+		}
+	}
+
+	return nil
 }
 
-type ExtensionBuilders struct {
-	DebianBookworm *AptExtensionBuilder `json:"debian:bookworm,omitempty"`
-	UbuntuJammy    *AptExtensionBuilder `json:"ubuntu:jammy,omitempty"`
-	UbuntuNoble    *AptExtensionBuilder `json:"ubuntu:noble,omitempty"`
+// AptBuilder returns the declared builder of the platform if it's a deb platform or nil.
+func (ebs ExtensionBuilders) AptBuilder(p Platform) *AptExtensionBuilder {
+	info, ok := p.Info()
+	if !ok || info.aptBuilder == nil {
+		return nil
+	}
+
+	return *info.aptBuilder(&ebs)
 }
 
-func (ebs ExtensionBuilders) HasBuilder(p Platform) bool {
-	switch p {
-	case PlatformDebianBookworm:
-		return ebs.DebianBookworm != nil
-	case PlatformUbuntuJammy:
-		return ebs.UbuntuJammy != nil
-	case PlatformUbuntuNoble:
-		return ebs.UbuntuNoble != nil
+// AptRepositories returns the apt repositories of the declared builder of the platform.
+// Builders of platforms other than deb platforms have none.
+func (ebs ExtensionBuilders) AptRepositories(p Platform) []AptRepository {
+	if b := ebs.AptBuilder(p); b != nil {
+		return b.AptRepositories
 	}
 
-	return false
+	return nil
 }
 
-// Available returns all available extension builders.
-func (ebs ExtensionBuilders) Available() []AptExtensionBuilder {
-	var result []AptExtensionBuilder
+// setBuilder declares the builder of the platform. It is a no-op for unsupported platforms.
+func (ebs *ExtensionBuilders) setBuilder(p Platform, builder ExtensionBuilder) {
+	info, ok := p.Info()
+	if !ok {
+		return
+	}
+
+	switch {
+	case info.aptBuilder != nil:
+		*info.aptBuilder(ebs) = &AptExtensionBuilder{ExtensionBuilder: builder}
+	case info.rpmBuilder != nil:
+		*info.rpmBuilder(ebs) = &RpmExtensionBuilder{ExtensionBuilder: builder}
+	}
+}
+
+// RemoveBuilder removes the declared builder of the platform. It is a no-op for unsupported platforms.
+func (ebs *ExtensionBuilders) RemoveBuilder(p Platform) {
+	info, ok := p.Info()
+	if !ok {
+		return
+	}
 
-	if builder := ebs.DebianBookworm; builder != nil {
-		result = append(result, ebs.newBuilder(PlatformDebianBookworm, builder))
+	switch {
+	case info.aptBuilder != nil:
+		*info.aptBuilder(ebs) = nil
+	case info.rpmBuilder != nil:
+		*info.rpmBuilder(ebs) = nil
 	}
-	if builder := ebs.UbuntuJammy; builder != nil {
-		result = append(result, ebs.newBuilder(PlatformUbuntuJammy, builder))
+}
+
+func (ebs ExtensionBuilders) HasBuilder(p Platform) bool {
+	return ebs.Builder(p) != nil
+}
+
+// Validate validates the declared builders.
+func (ebs ExtensionBuilders) Validate() error {
+	var err error
+	for _, info := range Platforms {
+		if b := ebs.AptBuilder(info.Platform); b != nil {
+			if e := b.Validate(); e != nil {
+				err = errors.Join(err, fmt.Errorf("builders.%s has errors: %w", info.Platform, e))
+			}
+		}
 	}
-	if builder := ebs.UbuntuNoble; builder != nil {
-		result = append(result, ebs.newBuilder(PlatformUbuntuNoble, builder))
+
+	return err
+}
+
+// Available returns all available extension builders.
+func (ebs ExtensionBuilders) Available() []ExtensionBuilder {
+	var result []ExtensionBuilder
+
+	for _, info := range Platforms {
+		if builder := ebs.Builder(info.Platform); builder != nil {
+			result = append(result, ebs.newBuilder(info, builder))
+		}
 	}
 
 	return result
@@ -480,41 +665,31 @@ func (ebs ExtensionBuilders) Available() []AptExtensionBuilder {
 
 // Current returns the extension builder for the current os.
 // It panics if no extension builder is available.
-func (ebs ExtensionBuilders) Current() AptExtensionBuilder {
+func (ebs ExtensionBuilders) Current() ExtensionBuilder {
 	p, err := DetectPlatform()
 	if err != nil {
 		panic(err.Error())
 	}
 
-	var builder *AptExtensionBuilder
-	switch p {
-	case PlatformDebianBookworm:
-		builder = ebs.DebianBookworm
-	case PlatformUbuntuJammy:
-		builder = ebs.UbuntuJammy
-	case PlatformUbuntuNoble:
-		builder = ebs.UbuntuNoble
-	default:
+	info, ok := p.Info()
+	if !ok {
 		panic("unsupported platform: " + p)
 	}
 
-	return ebs.newBuilder(p, builder)
+	return ebs.newBuilder(info, ebs.Builder(p))
 }
 
-func (ebs ExtensionBuilders) newBuilder(p Platform, builder *AptExtensionBuilder) AptExtensionBuilder {
+func (ebs ExtensionBuilders) newBuilder(info PlatformInfo, builder *ExtensionBuilder) ExtensionBuilder {
 	image := builder.Image
 	if image == "" {
-		image = extensionBuilderImages[p]
+		image = info.BuilderImage
 	}
 
-	return AptExtensionBuilder{
-		ExtensionBuilder: ExtensionBuilder{
-			Type:              p,
-			Image:             image,
-			BuildDependencies: builder.BuildDependencies,
-			RunDependencies:   builder.RunDependencies,
-		},
-		AptRepositories: builder.AptRepositories,
+	return ExtensionBuilder{
+		Type:              info.Platform,
+		Image:             image,
+		BuildDependencies: builder.BuildDependencies,
+		RunDependencies:   builder.RunDependencies,
 	}
 }
 
@@ -525,6 +700,7 @@ type ExtensionBuilder struct {
 	RunDependencies   []string `json:"runDependencies,omitempty"`
 }
 
+// AptExtensionBuilder is the builder of a deb platform.
 type AptExtensionBuilder struct {
 	ExtensionBuilder
 
@@ -541,6 +717,11 @@ func (builder AptExtensionBuilder) Validate() error {
 	return nil
 }
 
+// RpmExtensionBuilder is the builder of an rpm platform.
+type RpmExtensionBuilder struct {
+	ExtensionBuilder
+}
+
 // Ref: https://manpages.ubuntu.com/manpages/lunar/en/man5/sources.list.5.html
 type AptRepository struct {
 	SignedKey  AptRepositorySignedKey `json:"signedKey"`
@@ -612,6 +793,8 @@ func (repo AptRepository) Validate() error {
 type AptRepositorySignedKey struct {
 	URL    string                       `json:"url"`
 	Format AptRepositorySignedKeyFormat `json:"format"`
+	// Fingerprint is the OpenPGP fingerprint of the key. The downloaded key is rejected if it doesn't match.
+	Fingerprint string `json:"fingerprint,omitempty"`
 }
 
 func (k AptRepositorySignedKey) Validate() error {
@@ -623,9 +806,20 @@ func (k AptRepositorySignedKey) Validate() error {
 		return fmt.Errorf("unsupported format: %s", k.Format)
 	}
 
+	if k.Fingerprint != "" && !fingerprintRegexp.MatchString(k.NormalizedFingerprint()) {
+		return fmt.Errorf("invalid fingerprint: %s", k.Fingerprint)
+	}
+
 	return nil
 }
 
+// NormalizedFingerprint returns the fingerprint in upper case without spaces and the 0x prefix,
+// e.g. "5bb9 2c09 db6e 8b3f" becomes "5BB92C09DB6E8B3F".
+func (k AptRepositorySignedKey) NormalizedFingerprint() string {
+	fp := strings.ToUpper(strings.Join(strings.Fields(k.Fingerprint), ""))
+	return strings.TrimPrefix(fp, "0X")
+}
+
 var (
 	SupportedAptRepositoryTypes = []AptRepositoryType{AptRepositoryTypeDeb, AptRepositoryTypeDebSrc}
 )
@@ -646,6 +840,11 @@ const (
 )
 
 var (
+	sha256Regexp = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+	// fingerprintRegexp matches a v4 fingerprint of 40 hex digits or a v5 or v6 fingerprint of 64 hex digits
+	fingerprintRegexp = regexp.MustCompile(`^([0-9A-F]{40}|[0-9A-F]{64})$`)
+
 	SupportedAptRepositorySignedKeyFormats = []AptRepositorySignedKeyFormat{AptRepositorySignedKeyFormatAsc, AptRepositorySignedKeyFormatGpg}
 )
 
@@ -656,66 +855,86 @@ const (
 	AptRepositorySignedKeyFormatGpg AptRepositorySignedKeyFormat = "gpg"
 )
 
-type Platform string
-
 const (
-	PlatformUnsupported    Platform = "unsupported"
-	PlatformDebianBookworm Platform = "debian_bookworm"
-	PlatformUbuntuJammy    Platform = "ubuntu_jammy"
-	PlatformUbuntuNoble    Platform = "ubuntu_noble"
-	PlatformDarwin         Platform = "darwin"
+	// sourceChecksumSuffix is the suffix of the file that records the SourceSha256 an archived source is verified against
+	sourceChecksumSuffix = ".sha256"
 )
 
-func DetectPlatform() (Platform, error) {
-	info := osx.Sysinfo()
-
-	var (
-		vendor  = info.OS.Vendor
-		version = info.OS.Version
-	)
+type fileExtensionSource struct {
+	Dir string
+}
 
-	if vendor == "" {
-		vendor = runtime.GOOS
+func (s *fileExtensionSource) Archive(dst string) error {
+	fi, err := os.Stat(s.Dir)
+	if err != nil {
+		return err
 	}
 
-	if vendor == "debian" && version == "12" {
-		return PlatformDebianBookworm, nil
-	}
+	// a local archive is repackaged like a downloaded one
+	if !fi.IsDir() {
+		if !isSourceArchive(s.Dir) {
+			return fmt.Errorf("file source must be a directory or an archive: %s", s.Dir)
+		}
 
-	if vendor == "ubuntu" && version == "22.04" {
-		return PlatformUbuntuJammy, nil
+		return repackageSource(s.Dir, dst)
 	}
 
-	if vendor == "ubuntu" && version == "24.04" {
-		return PlatformUbuntuNoble, nil
+	return archiver.Archive([]string{s.Dir}, dst)
+}
+
+type httpExtensionSource struct {
+	URL string
+	// Sha256 is the checksum of the downloaded archive before it's repackaged
+	Sha256 string
+}
+
+func (s *httpExtensionSource) Archive(dst string) error {
+	dir, err := os.MkdirTemp("", "pgxman-source-")
+	if err != nil {
+		return err
 	}
+	defer os.RemoveAll(dir)
 
-	if vendor == "darwin" {
-		return PlatformDarwin, nil
+	file := filepath.Join(dir, s.fileName())
+	if err := s.download(file); err != nil {
+		return err
 	}
 
-	return PlatformUnsupported, &ErrUnsupportedPlatform{osVendor: vendor, osVersion: version}
-}
+	if s.Sha256 != "" {
+		sum, err := fileSha256(file)
+		if err != nil {
+			return err
+		}
 
-type fileExtensionSource struct {
-	Dir string
-}
+		if !strings.EqualFold(sum, s.Sha256) {
+			return fmt.Errorf("source checksum mismatch: want sha256 %s, got %s", s.Sha256, sum)
+		}
+	}
 
-func (s *fileExtensionSource) Archive(dst string) error {
-	return archiver.Archive([]string{s.Dir}, dst)
+	return repackageSource(file, dst)
 }
 
-type httpExtensionSource struct {
-	URL string
+// fileName returns the file name of the URL so that the archive format is detected by the extension.
+func (s *httpExtensionSource) fileName() string {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return filepath.Base(s.URL)
+	}
+
+	return filepath.Base(u.Path)
 }
 
-func (s *httpExtensionSource) Archive(dst string) error {
+func (s *httpExtensionSource) download(dst string) error {
 	resp, err := http.Get(s.URL)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: %s", s.URL, resp.Status)
+	}
+
 	f, err := os.Create(dst)
 	if err != nil {
 		return err
@@ -728,3 +947,144 @@ func (s *httpExtensionSource) Archive(dst string) error {
 
 	return nil
 }
+
+// isSourceArchive reports whether the file is an archive format that can be unarchived, e.g. tar.gz, tar.xz or zip.
+// Compressed single files, e.g. gz, are not archives.
+func isSourceArchive(file string) bool {
+	ar, err := archiver.ByExtension(file)
+	if err != nil {
+		return false
+	}
+
+	_, ok := ar.(archiver.Unarchiver)
+	return ok
+}
+
+// repackageSource unarchives the source archive and archives it again into dst, the orig tar.gz that debuild expects,
+// so that it always has a single top-level directory that is stripped when the source is unarchived.
+// A source archive without a top-level directory is put into one.
+func repackageSource(src, dst string) error {
+	ar, err := archiver.ByExtension(src)
+	if err != nil {
+		return err
+	}
+
+	unarchiver, ok := ar.(archiver.Unarchiver)
+	if !ok {
+		return fmt.Errorf("source is not an archive format: %s", src)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "pgxman-source-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir := filepath.Join(tmpDir, "source")
+	if err := unarchiver.Unarchive(src, dir); err != nil {
+		return fmt.Errorf("unarchive %s: %w", filepath.Base(src), err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		dir = filepath.Join(dir, entries[0].Name())
+	}
+
+	if err := archiver.Archive([]string{dir}, dst); err != nil {
+		// don't leave a partial file that would be taken as downloaded
+		_ = os.Remove(dst)
+		return err
+	}
+
+	return nil
+}
+
+const (
+	gitSourcePrefix = "git+"
+)
+
+var (
+	supportedGitSourceSchemes = []string{"https", "http", "ssh", "file"}
+)
+
+// gitExtensionSource is a shallow clone of a ref of a git repository.
+type gitExtensionSource struct {
+	URL    string
+	Ref    string
+	commit string
+}
+
+func (s *gitExtensionSource) Archive(dst string) error {
+	tmpDir, err := os.MkdirTemp("", "pgxman-git-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// the checkout directory is the top-level directory of the archive
+	name := strings.TrimSuffix(filepath.Base(s.URL), ".git")
+	if name == "" || name == "." || name == "/" {
+		name = "source"
+	}
+	dir := filepath.Join(tmpDir, name)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return err
+	}
+
+	ref := s.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	// fetching a single ref works for branches, tags and commits, which git clone --branch doesn't
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth", "1", s.URL, ref},
+		{"-c", "advice.detachedHead=false", "checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		if _, err := runGit(dir, args...); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".gitmodules")); err == nil {
+		if _, err := runGit(dir, "submodule", "update", "--quiet", "--init", "--recursive", "--depth", "1"); err != nil {
+			return err
+		}
+	}
+
+	commit, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	s.commit = commit
+
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		return err
+	}
+
+	return archiver.Archive([]string{dir}, dst)
+}
+
+func (s *gitExtensionSource) Revision() string {
+	return s.commit
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	// fail instead of waiting for credentials
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
@@ -1,8 +1,17 @@
 package pgxman
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/mholt/archiver/v3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -33,6 +42,33 @@ func TestExtension_ParseSource(t *testing.T) {
 			},
 			wantExt: &fileExtensionSource{Dir: "/tmp/test.tar.gz"},
 		},
+		{
+			name: "git source",
+			ext: Extension{
+				ExtensionOverridable: ExtensionOverridable{
+					Source: "git+https://github.com/org/repo.git#v1.0.0",
+				},
+			},
+			wantExt: &gitExtensionSource{URL: "https://github.com/org/repo.git", Ref: "v1.0.0"},
+		},
+		{
+			name: "git source without ref",
+			ext: Extension{
+				ExtensionOverridable: ExtensionOverridable{
+					Source: "git+file:///tmp/repo.git",
+				},
+			},
+			wantExt: &gitExtensionSource{URL: "file:///tmp/repo.git"},
+		},
+		{
+			name: "invalid git source",
+			ext: Extension{
+				ExtensionOverridable: ExtensionOverridable{
+					Source: "git+ftp://example.com/repo.git",
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid source",
 			ext: Extension{
@@ -192,4 +228,278 @@ func TestExtension_Validate(t *testing.T) {
 	err = ext.Validate()
 	assert.Error(err)
 	assert.Contains(err.Error(), "overriding PostgreSQL 16 config but \"16\" is not in `pgVersions`")
+
+	ext = Extension{
+		PGVersions: []PGVersion{PGVersion16},
+		ExtensionOverridable: ExtensionOverridable{
+			Source:       "file:///tmp/source",
+			SourceSha256: "41cf6794ba4200b839c53531555f0f3998df4cbb01a4d5cb0b94e3ca5e23947d",
+		},
+	}
+	err = ext.Validate()
+	assert.Error(err)
+	assert.Contains(err.Error(), "sourceSha256 is only supported for http sources")
+
+	ext.Source = "https://example.com/v1.0.0.tar.gz"
+	ext.SourceSha256 = "41cf6794"
+	err = ext.Validate()
+	assert.Error(err)
+	assert.Contains(err.Error(), "invalid sourceSha256")
+
+	ext = Extension{
+		PGVersions: []PGVersion{PGVersion16},
+		ExtensionOverridable: ExtensionOverridable{
+			Builders: &ExtensionBuilders{
+				DebianBookworm: &AptExtensionBuilder{
+					AptRepositories: []AptRepository{{ID: "example"}},
+				},
+				RockyLinux9: &RpmExtensionBuilder{},
+			},
+		},
+	}
+	err = ext.Validate()
+	assert.Error(err)
+	assert.Contains(err.Error(), "builders.debian_bookworm has errors: aptRepositories[0] has errors")
+}
+
+func TestAptRepositorySignedKey_Validate(t *testing.T) {
+	assert := assert.New(t)
+
+	key := AptRepositorySignedKey{
+		URL:         "https://example.com/key.asc",
+		Format:      AptRepositorySignedKeyFormatAsc,
+		Fingerprint: "0x2968 b35a 9777 3063 8d63  85b0 ea59 2431 0f97 2706",
+	}
+	assert.NoError(key.Validate())
+	assert.Equal("2968B35A977730638D6385B0EA5924310F972706", key.NormalizedFingerprint())
+
+	key.Fingerprint = "2968B35A97773063"
+	assert.ErrorContains(key.Validate(), "invalid fingerprint")
+}
+
+func TestHTTPExtensionSource_Archive(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "repo-1.0.0")
+	assert.NoError(os.Mkdir(srcDir, 0755))
+	assert.NoError(os.WriteFile(filepath.Join(srcDir, "VERSION"), []byte("1.0.0"), 0644))
+
+	archivesDir := filepath.Join(dir, "archives")
+	assert.NoError(os.Mkdir(archivesDir, 0755))
+	for _, name := range []string{"v1.0.0.tar.gz", "v1.0.0.tgz", "v1.0.0.tar.xz", "v1.0.0.tar.bz2", "v1.0.0.zip"} {
+		assert.NoError(archiver.Archive([]string{srcDir}, filepath.Join(archivesDir, name)))
+	}
+	// an archive without a top-level directory
+	assert.NoError(archiver.Archive([]string{filepath.Join(srcDir, "VERSION")}, filepath.Join(archivesDir, "flat.zip")))
+
+	ts := httptest.NewServer(http.FileServer(http.Dir(archivesDir)))
+	defer ts.Close()
+
+	for _, name := range []string{"v1.0.0.tar.gz", "v1.0.0.tgz", "v1.0.0.tar.xz", "v1.0.0.tar.bz2", "v1.0.0.zip", "flat.zip"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ext := ExtensionOverridable{Source: ts.URL + "/" + name}
+			source, err := ext.ParseSource()
+			assert.NoError(err)
+
+			dst := filepath.Join(t.TempDir(), "repo_1.0.0.orig.tar.gz")
+			assert.NoError(source.Archive(dst))
+
+			// the orig tar.gz is unarchived by stripping the top-level directory
+			out := t.TempDir()
+			targz := archiver.NewTarGz()
+			targz.StripComponents = 1
+			assert.NoError(targz.Unarchive(dst, out))
+
+			b, err := os.ReadFile(filepath.Join(out, "VERSION"))
+			assert.NoError(err)
+			assert.Equal("1.0.0", string(b))
+		})
+	}
+
+	// a local archive is repackaged the same way
+	dst := filepath.Join(t.TempDir(), "repo_1.0.0.orig.tar.gz")
+	assert.NoError((&fileExtensionSource{Dir: filepath.Join(archivesDir, "v1.0.0.tar.xz")}).Archive(dst))
+	out := t.TempDir()
+	assert.NoError(archiver.Unarchive(dst, out))
+	assert.FileExists(filepath.Join(out, "repo-1.0.0", "VERSION"))
+
+	for _, name := range []string{"v1.0.0.gz", "v1.0.0.txt"} {
+		_, err := ExtensionOverridable{Source: ts.URL + "/" + name}.ParseSource()
+		assert.ErrorContains(err, "http source must be an archive", name)
+	}
+}
+
+func TestExtensionOverridable_SourceChecksum(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "VERSION"), []byte("1.0.0"), 0644))
+
+	archive := filepath.Join(t.TempDir(), "v1.0.0.zip")
+	assert.NoError(archiver.Archive([]string{dir}, archive))
+	content, err := os.ReadFile(archive)
+	assert.NoError(err)
+	sum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.0.0.zip" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write(content)
+	}))
+	defer ts.Close()
+
+	ext := ExtensionOverridable{Source: ts.URL + "/v1.0.0.zip"}
+	got, err := ext.SourceChecksum()
+	assert.NoError(err)
+	assert.Equal(sum, got)
+
+	// the checksum is of the downloaded archive, not the repackaged one
+	ext.SourceSha256 = strings.ToUpper(sum)
+	source, err := ext.ParseSource()
+	assert.NoError(err)
+	assert.NoError(source.Archive(filepath.Join(t.TempDir(), "repo_1.0.0.orig.tar.gz")))
+
+	ext.SourceSha256 = strings.Repeat("0", 64)
+	source, err = ext.ParseSource()
+	assert.NoError(err)
+	dst := filepath.Join(t.TempDir(), "repo_1.0.0.orig.tar.gz")
+	assert.ErrorContains(source.Archive(dst), "source checksum mismatch")
+	assert.NoFileExists(dst)
+
+	ext.Source = ts.URL + "/missing.tar.gz"
+	_, err = ext.SourceChecksum()
+	assert.ErrorContains(err, "404")
+
+	ext.Source = "file:///tmp/source"
+	_, err = ext.SourceChecksum()
+	assert.ErrorContains(err, "only supported for http sources")
+}
+
+func TestExtensionOverridable_ArchiveSource(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "VERSION"), []byte("1.0.0"), 0644))
+
+	archive := filepath.Join(t.TempDir(), "v1.0.0.zip")
+	assert.NoError(archiver.Archive([]string{dir}, archive))
+	content, err := os.ReadFile(archive)
+	assert.NoError(err)
+	sum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	var downloads int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write(content)
+	}))
+	defer ts.Close()
+
+	dst := filepath.Join(t.TempDir(), "repo_1.0.0.orig.tar.gz")
+	ext := ExtensionOverridable{Source: ts.URL + "/v1.0.0.zip", SourceSha256: sum}
+
+	source, err := ext.ArchiveSource(dst)
+	assert.NoError(err)
+	assert.NotNil(source)
+	assert.Equal(1, downloads)
+
+	// the archive verified against the same checksum is reused
+	source, err = ext.ArchiveSource(dst)
+	assert.NoError(err)
+	assert.Nil(source)
+	assert.Equal(1, downloads)
+
+	// the archive isn't reused when the checksum changes
+	ext.SourceSha256 = strings.Repeat("0", 64)
+	_, err = ext.ArchiveSource(dst)
+	assert.ErrorContains(err, "source checksum mismatch")
+	assert.Equal(2, downloads)
+	_, err = ext.ArchiveSource(dst)
+	assert.ErrorContains(err, "source checksum mismatch")
+	assert.Equal(3, downloads)
+
+	// an archive without a recorded checksum isn't reused
+	dst = filepath.Join(t.TempDir(), "repo_1.0.0.orig.tar.gz")
+	assert.NoError(os.WriteFile(dst, []byte("tampered"), 0644))
+	ext.SourceSha256 = sum
+	source, err = ext.ArchiveSource(dst)
+	assert.NoError(err)
+	assert.NotNil(source)
+	assert.Equal(4, downloads)
+}
+
+func TestGitExtensionSource_Archive(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	assert := assert.New(t)
+
+	var (
+		dir     = t.TempDir()
+		workDir = filepath.Join(dir, "work")
+		bareDir = filepath.Join(dir, "repo.git")
+	)
+	git := func(args ...string) string {
+		out, err := runGit(dir, append([]string{"-c", "user.name=pgxman", "-c", "user.email=test@pgxman.com"}, args...)...)
+		assert.NoError(err)
+		return out
+	}
+
+	git("init", "--quiet", workDir)
+	assert.NoError(os.WriteFile(filepath.Join(workDir, "VERSION"), []byte("1.0.0"), 0644))
+	git("-C", workDir, "add", "VERSION")
+	git("-C", workDir, "commit", "--quiet", "-m", "1.0.0")
+	git("-C", workDir, "tag", "v1.0.0")
+	v1 := git("-C", workDir, "rev-parse", "HEAD")
+
+	assert.NoError(os.WriteFile(filepath.Join(workDir, "VERSION"), []byte("2.0.0"), 0644))
+	git("-C", workDir, "commit", "--quiet", "-am", "2.0.0")
+	v2 := git("-C", workDir, "rev-parse", "HEAD")
+
+	git("clone", "--quiet", "--bare", workDir, bareDir)
+
+	cases := []struct {
+		Name        string
+		Ref         string
+		WantVersion string
+		WantCommit  string
+		WantErr     bool
+	}{
+		{Name: "tag", Ref: "v1.0.0", WantVersion: "1.0.0", WantCommit: v1},
+		{Name: "commit", Ref: v1, WantVersion: "1.0.0", WantCommit: v1},
+		{Name: "HEAD", WantVersion: "2.0.0", WantCommit: v2},
+		{Name: "missing ref", Ref: "v3.0.0", WantErr: true},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			source, err := ExtensionOverridable{Source: "git+file://" + bareDir + "#" + c.Ref}.ParseSource()
+			assert.NoError(err)
+
+			dst := filepath.Join(t.TempDir(), "repo_1.0.0.orig.tar.gz")
+			err = source.Archive(dst)
+			if c.WantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(c.WantCommit, source.(RevisionSource).Revision())
+
+			out := t.TempDir()
+			assert.NoError(archiver.Unarchive(dst, out))
+
+			b, err := os.ReadFile(filepath.Join(out, "repo", "VERSION"))
+			assert.NoError(err)
+			assert.Equal(c.WantVersion, string(b))
+
+			_, err = os.Stat(filepath.Join(out, "repo", ".git"))
+			assert.True(os.IsNotExist(err))
+		})
+	}
 }
@@ -3,11 +3,16 @@ package pgxman
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/pgxman/pgxman/internal/iostreams"
 )
 
-const DefaultPackAPIVersion = "v1"
+const (
+	DefaultPackAPIVersion     = "v1"
+	DefaultPackLockAPIVersion = "v1"
+	PackLockFileName          = "pgxman.lock"
+)
 
 type Pack struct {
 	Postgres   Postgres        `json:"postgres"`
@@ -33,21 +38,165 @@ func (p Pack) Validate() error {
 	return nil
 }
 
+// InstallExtensions returns an extension to install for each PostgreSQL version of each pack extension.
 func (p Pack) InstallExtensions() []InstallExtension {
 	var exts []InstallExtension
 	for _, ext := range p.Extensions {
-		exts = append(exts, InstallExtension{
-			PackExtension: ext,
-			PGVersion:     p.Postgres.Version,
-		})
+		pgVers := ext.PGVersions
+		if len(pgVers) == 0 {
+			pgVers = []PGVersion{p.Postgres.Version}
+		}
+
+		ext.PGVersions = nil
+		for _, pgVer := range pgVers {
+			exts = append(exts, InstallExtension{
+				PackExtension: ext,
+				PGVersion:     pgVer,
+			})
+		}
 	}
 
 	return exts
 }
 
+// PGVersions returns the PostgreSQL versions that the extensions are installed for.
+// The PostgreSQL version of postgres is only included if an extension doesn't declare its own versions,
+// or if there are no extensions so that a pack without extensions still targets a PostgreSQL version.
+func (p Pack) PGVersions() []PGVersion {
+	if len(p.Extensions) == 0 {
+		return []PGVersion{p.Postgres.Version}
+	}
+
+	var pgVers []PGVersion
+	for _, ext := range p.InstallExtensions() {
+		if !slices.Contains(pgVers, ext.PGVersion) {
+			pgVers = append(pgVers, ext.PGVersion)
+		}
+	}
+
+	return pgVers
+}
+
+// PackLock records the exact extensions resolved from a pack so that
+// subsequent installs resolve to the same versions and apt repositories.
+type PackLock struct {
+	APIVersion string            `json:"apiVersion"`
+	Extensions []LockedExtension `json:"extensions"`
+}
+
+func (l PackLock) Validate() error {
+	if l.APIVersion != DefaultPackLockAPIVersion {
+		return fmt.Errorf("invalid lock api version: %s", l.APIVersion)
+	}
+
+	for _, ext := range l.Extensions {
+		if err := ext.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find returns the locked extension that is resolved from the pack extension for the platform.
+func (l PackLock) Find(ext InstallExtension, p Platform) (LockedExtension, bool) {
+	for _, locked := range l.Extensions {
+		if locked.Matches(ext, p) {
+			return locked, true
+		}
+	}
+
+	return LockedExtension{}, false
+}
+
+type LockedExtension struct {
+	Name            string          `json:"name"`
+	Version         string          `json:"version"`
+	Constraint      string          `json:"constraint"`
+	PGVersion       PGVersion       `json:"pgVersion"`
+	Platform        Platform        `json:"platform"`
+	AptRepositories []AptRepository `json:"aptRepositories,omitempty"`
+	// SharedPreloadLibraries are recorded so that locked extensions are configured without the registry
+	SharedPreloadLibraries []string `json:"sharedPreloadLibraries,omitempty"`
+	// Dependencies are recorded so that the dependencies of locked extensions are resolved without the registry
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+func NewLockedExtension(ext InstallExtension, constraint string, p Platform) LockedExtension {
+	return LockedExtension{
+		Name:            ext.Name,
+		Version:         ext.Version,
+		Constraint:      normalizeVersionConstraint(constraint),
+		PGVersion:       ext.PGVersion,
+		Platform:        p,
+		AptRepositories: ext.AptRepositories,
+
+		SharedPreloadLibraries: ext.SharedPreloadLibraries,
+		Dependencies:           ext.Dependencies,
+	}
+}
+
+// Matches reports whether the locked extension is resolved from the pack extension for the platform.
+func (e LockedExtension) Matches(ext InstallExtension, p Platform) bool {
+	return e.Name == ext.Name &&
+		e.PGVersion == ext.PGVersion &&
+		e.Platform == p &&
+		e.Constraint == normalizeVersionConstraint(ext.Version)
+}
+
+func (e LockedExtension) Validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("locked extension name is required")
+	}
+
+	if e.Version == "" {
+		return fmt.Errorf("locked extension %s version is required", e.Name)
+	}
+
+	if err := e.PGVersion.Validate(); err != nil {
+		return fmt.Errorf("locked extension %s: %w", e.Name, err)
+	}
+
+	for i, repo := range e.AptRepositories {
+		if err := repo.Validate(); err != nil {
+			return fmt.Errorf("locked extension %s aptRepositories[%d] has errors: %w", e.Name, i, err)
+		}
+	}
+
+	return nil
+}
+
+// InstallExtension returns the extension to install with the locked version and apt repositories.
+func (e LockedExtension) InstallExtension(ext PackExtension) InstallExtension {
+	ext.Version = e.Version
+
+	return InstallExtension{
+		PackExtension:          ext,
+		PGVersion:              e.PGVersion,
+		AptRepositories:        e.AptRepositories,
+		SharedPreloadLibraries: e.SharedPreloadLibraries,
+		Dependencies:           e.Dependencies,
+	}
+}
+
+// normalizeVersionConstraint treats an empty version the same as latest.
+func normalizeVersionConstraint(v string) string {
+	if v == "" {
+		return "latest"
+	}
+
+	return v
+}
+
 type InstallExtension struct {
-	PGVersion       PGVersion
-	AptRepositories []AptRepository
+	PGVersion       PGVersion       `json:"pgVersion"`
+	AptRepositories []AptRepository `json:"aptRepositories,omitempty"`
+	// SharedPreloadLibraries are the libraries that must be preloaded by PostgreSQL for the extension to work
+	SharedPreloadLibraries []string `json:"sharedPreloadLibraries,omitempty"`
+	// Dependencies are the pgxman extensions that the extension depends on, e.g. pgxman/pgvector
+	Dependencies []string `json:"dependencies,omitempty"`
+	// RequiredBy is the name of the extension that depends on the extension if it's installed as a dependency
+	RequiredBy string `json:"requiredBy,omitempty"`
 	PackExtension
 }
 
@@ -77,6 +226,9 @@ type PackExtension struct {
 	Path      string   `json:"path,omitempty"`
 	Options   []string `json:"options,omitempty"`
 	Overwrite bool     `json:"overwrite,omitempty"`
+	// PGVersions are the PostgreSQL versions to install the extension for.
+	// The PostgreSQL version of the pack is used if it's empty.
+	PGVersions []PGVersion `json:"pgVersions,omitempty"`
 }
 
 func (e PackExtension) Validate() error {
@@ -84,6 +236,17 @@ func (e PackExtension) Validate() error {
 		return fmt.Errorf("name or path is required")
 	}
 
+	for _, pgVer := range e.PGVersions {
+		if err := pgVer.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// a local package is built for a single PostgreSQL version
+	if e.Path != "" && len(e.PGVersions) > 1 {
+		return fmt.Errorf("path %s can't be installed for multiple PostgreSQL versions", e.Path)
+	}
+
 	return nil
 }
 
@@ -93,6 +256,8 @@ type Postgres struct {
 	Password string    `json:"password,omitempty"`
 	DBName   string    `json:"dbname,omitempty"`
 	Port     string    `json:"port,omitempty"`
+	// Databases are the databases in which the extensions are created or updated after installation
+	Databases []string `json:"databases,omitempty"`
 }
 
 func (p Postgres) Validate() error {
@@ -100,8 +265,73 @@ func (p Postgres) Validate() error {
 }
 
 type Installer interface {
-	Install(ctx context.Context, ext InstallExtension) error
-	Upgrade(ctx context.Context, ext InstallExtension) error
+	// Install installs the extensions in a single transaction.
+	Install(ctx context.Context, exts []InstallExtension) error
+	// Upgrade upgrades the extensions in a single transaction.
+	Upgrade(ctx context.Context, exts []InstallExtension) error
 	PreInstallCheck(ctx context.Context, exts []InstallExtension, io *iostreams.IOStreams) error
 	PreUpgradeCheck(ctx context.Context, exts []InstallExtension, io *iostreams.IOStreams) error
+	// Uninstall uninstalls the extensions in a single transaction.
+	Uninstall(ctx context.Context, exts []InstallExtension) error
+	PreUninstallCheck(ctx context.Context, exts []InstallExtension, io *iostreams.IOStreams) error
+	List(ctx context.Context) ([]InstalledExtension, error)
+	// Plan returns the changes of installing or upgrading the extensions without applying them.
+	Plan(ctx context.Context, exts []InstallExtension) (*InstallPlan, error)
+}
+
+// SQLExtensionFinder is implemented by installers that can find the SQL extensions,
+// i.e. the names used in CREATE EXTENSION, shipped by an installed extension.
+type SQLExtensionFinder interface {
+	SQLExtensions(ctx context.Context, ext InstallExtension) ([]string, error)
+}
+
+// Syncer is implemented by installers that can upgrade and uninstall extensions in a single transaction.
+type Syncer interface {
+	// Sync upgrades the extensions and uninstalls the extensions to remove in a single transaction.
+	Sync(ctx context.Context, exts []InstallExtension, remove []InstallExtension) error
+}
+
+// Bundler is implemented by installers that support offline bundles.
+// A bundle is an archive of the extension packages and their dependencies
+// that can be installed on a host without network access.
+type Bundler interface {
+	// Bundle downloads the packages of the extensions and their dependencies into the archive dst.
+	Bundle(ctx context.Context, exts []InstallExtension, dst string) error
+	PreInstallBundleCheck(ctx context.Context, src string, io *iostreams.IOStreams) error
+	// InstallBundle installs the archive src without network access and returns the installed extensions.
+	InstallBundle(ctx context.Context, src string) ([]InstallExtension, error)
+}
+
+// InstallPlan describes the packages and package sources that would be
+// changed by installing or upgrading extensions.
+type InstallPlan struct {
+	Packages []PlannedPackage `json:"packages"`
+	Sources  []PlannedSource  `json:"sources"`
+}
+
+type PlannedPackage struct {
+	Name      string    `json:"name,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	PGVersion PGVersion `json:"pgVersion"`
+	Package   string    `json:"package"`
+	// RequiredBy is the extension that depends on the package if it's installed as a dependency
+	RequiredBy string `json:"requiredBy,omitempty"`
+}
+
+type PlannedSource struct {
+	Name          string `json:"name"`
+	SourcePath    string `json:"sourcePath"`
+	SourceContent string `json:"sourceContent"`
+	KeyPath       string `json:"keyPath"`
+	KeyURL        string `json:"keyURL"`
+}
+
+type InstalledExtension struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	PGVersion PGVersion `json:"pgVersion"`
+	Arch      string    `json:"arch"`
+	Package   string    `json:"package"`
+	Held      bool      `json:"held"`
 }
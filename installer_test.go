@@ -0,0 +1,97 @@
+package pgxman
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPack_InstallExtensions(t *testing.T) {
+	assert := assert.New(t)
+
+	p := Pack{
+		APIVersion: DefaultPackAPIVersion,
+		Postgres: Postgres{
+			Version: PGVersion16,
+		},
+		Extensions: []PackExtension{
+			{
+				Name:    "pgvector",
+				Version: "0.5.1",
+			},
+			{
+				Name:       "pg_ivm",
+				PGVersions: []PGVersion{PGVersion15, PGVersion16},
+			},
+		},
+	}
+	assert.NoError(p.Validate())
+
+	assert.Equal([]InstallExtension{
+		{
+			PackExtension: PackExtension{Name: "pgvector", Version: "0.5.1"},
+			PGVersion:     PGVersion16,
+		},
+		{
+			PackExtension: PackExtension{Name: "pg_ivm"},
+			PGVersion:     PGVersion15,
+		},
+		{
+			PackExtension: PackExtension{Name: "pg_ivm"},
+			PGVersion:     PGVersion16,
+		},
+	}, p.InstallExtensions())
+	assert.Equal([]PGVersion{PGVersion16, PGVersion15}, p.PGVersions())
+}
+
+func TestPackExtension_Validate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Error(PackExtension{Name: "pgvector", PGVersions: []PGVersion{"12"}}.Validate())
+	assert.Error(PackExtension{Path: "/PATH_TO/ext.deb", PGVersions: []PGVersion{PGVersion15, PGVersion16}}.Validate())
+	assert.NoError(PackExtension{Path: "/PATH_TO/ext.deb", PGVersions: []PGVersion{PGVersion15}}.Validate())
+}
+
+func TestPack_PGVersions(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Extensions []PackExtension
+		Want       []PGVersion
+	}{
+		{
+			Name: "default and own versions",
+			Extensions: []PackExtension{
+				{Name: "pg_ivm", PGVersions: []PGVersion{PGVersion15, PGVersion16}},
+				{Name: "pgvector"},
+			},
+			Want: []PGVersion{PGVersion15, PGVersion16},
+		},
+		{
+			Name: "every extension sets its own versions",
+			Extensions: []PackExtension{
+				{Name: "pgvector", PGVersions: []PGVersion{PGVersion14}},
+				{Name: "pg_ivm", PGVersions: []PGVersion{PGVersion15, PGVersion14}},
+			},
+			Want: []PGVersion{PGVersion14, PGVersion15},
+		},
+		{
+			Name: "no extensions",
+			Want: []PGVersion{PGVersion16},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			p := Pack{
+				APIVersion: DefaultPackAPIVersion,
+				Postgres: Postgres{
+					Version: PGVersion16,
+				},
+				Extensions: c.Extensions,
+			}
+
+			assert.Equal(t, c.Want, p.PGVersions())
+		})
+	}
+}
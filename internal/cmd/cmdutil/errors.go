@@ -2,4 +2,9 @@ package cmdutil
 
 import "errors"
 
-var SilentError = errors.New("SilentError")
+var (
+	SilentError = errors.New("SilentError")
+	// OutdatedError is returned by `pgxman outdated` when extensions can be upgraded
+	// so that it exits with a status other than the one of failures
+	OutdatedError = errors.New("OutdatedError")
+)
@@ -0,0 +1,155 @@
+package pgxman
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/internal/pg"
+)
+
+// activateOptions configures the databases in which extensions are activated after installation.
+type activateOptions struct {
+	Databases    []string
+	AllDatabases bool
+	ConnString   string
+	// Create creates extensions that have not been created in a database.
+	// Otherwise, only created extensions are updated.
+	Create bool
+}
+
+func (o activateOptions) Enabled() bool {
+	return len(o.Databases) > 0 || o.AllDatabases
+}
+
+// activateExtensions creates or updates the extensions in each target database of the cluster of each PostgreSQL version
+// and reports the result of each database. It returns an error if the extensions fail to be activated in any database.
+func activateExtensions(ctx context.Context, i pgxman.Installer, exts []pgxman.InstallExtension, opts activateOptions) error {
+	if !opts.Enabled() || len(exts) == 0 {
+		return nil
+	}
+
+	var failed bool
+	pgVers, groups := groupByPGVersion(exts)
+	for _, pgVer := range pgVers {
+		if !activatePGExtensions(ctx, i, pgVer, groups[pgVer], opts) {
+			failed = true
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("failed to activate extensions")
+	}
+
+	return nil
+}
+
+// activatePGExtensions activates the extensions of a PostgreSQL version and reports whether all of them succeed.
+func activatePGExtensions(ctx context.Context, i pgxman.Installer, pgVer pgxman.PGVersion, exts []pgxman.InstallExtension, opts activateOptions) bool {
+	logger := log.NewTextLogger()
+	conn := pg.Conn{
+		PGVersion:  pgVer,
+		ConnString: opts.ConnString,
+	}
+
+	dbs := opts.Databases
+	if opts.AllDatabases {
+		var err error
+		dbs, err = pg.Databases(ctx, conn)
+		if err != nil {
+			logger.Debug("failed to list databases", "error", err, "pg", pgVer)
+			fmt.Printf("[%s] Failed to list databases of PostgreSQL %s: %s\n", errorMark, pgVer, err)
+			return false
+		}
+	}
+
+	fmt.Printf("Activating extensions for PostgreSQL %s in databases %s...\n", pgVer, strings.Join(dbs, ", "))
+
+	var failed bool
+	for _, ext := range exts {
+		names, err := sqlExtensionsToActivate(ctx, i, ext, opts.Create)
+		if err != nil {
+			logger.Debug("failed to find sql extensions", "error", err, "extension", ext)
+			fmt.Printf("[%s] %s: %s\n", errorMark, ext, err)
+			failed = true
+			continue
+		}
+
+		for _, db := range dbs {
+			for _, name := range names {
+				action, err := pg.ActivateExtension(ctx, conn, db, name, opts.Create)
+				if err != nil {
+					logger.Debug("failed to activate extension", "error", err, "extension", name, "database", db)
+					fmt.Printf("[%s] %s in %s: %s\n", errorMark, name, db, err)
+					failed = true
+					continue
+				}
+
+				mark := successMark
+				if action == pg.ActivationSkipped {
+					// only report skipped databases that are explicitly specified
+					if opts.AllDatabases {
+						continue
+					}
+
+					mark = infoMark
+					action = "skipped because it has not been created"
+				}
+				fmt.Printf("[%s] %s in %s: %s\n", mark, name, db, action)
+			}
+		}
+	}
+
+	return !failed
+}
+
+// sqlExtensionsToActivate returns the SQL extensions of an installed extension.
+// When creating, only the SQL extension matching the extension name, or the only SQL extension, is returned
+// because an extension may ship optional SQL extensions, e.g. postgis ships postgis_raster.
+func sqlExtensionsToActivate(ctx context.Context, i pgxman.Installer, ext pgxman.InstallExtension, create bool) ([]string, error) {
+	var names []string
+	if f, ok := i.(pgxman.SQLExtensionFinder); ok {
+		var err error
+		names, err = f.SQLExtensions(ctx, ext)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(names) == 0 && ext.Name != "" {
+		names = pg.ExtensionNames(ext.Name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no SQL extension is found")
+	}
+
+	if !create {
+		return names, nil
+	}
+
+	name, err := pickSQLExtension(ext.Name, names)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{name}, nil
+}
+
+func pickSQLExtension(extName string, names []string) (string, error) {
+	if len(names) == 1 {
+		return names[0], nil
+	}
+
+	// prefer the name with underscores because SQL extension names rarely contain dashes
+	candidates := pg.ExtensionNames(extName)
+	slices.Reverse(candidates)
+	for _, c := range candidates {
+		if slices.Contains(names, c) {
+			return c, nil
+		}
+	}
+
+	return "", fmt.Errorf("can't determine which SQL extension to create from %s", strings.Join(names, ", "))
+}
@@ -0,0 +1,58 @@
+package pgxman
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pickSQLExtension(t *testing.T) {
+	cases := []struct {
+		Name    string
+		ExtName string
+		Names   []string
+		Want    string
+		WantErr string
+	}{
+		{
+			Name:    "single sql extension",
+			ExtName: "pgvector",
+			Names:   []string{"vector"},
+			Want:    "vector",
+		},
+		{
+			Name:    "matching name",
+			ExtName: "postgis",
+			Names:   []string{"postgis", "postgis_raster", "postgis_topology"},
+			Want:    "postgis",
+		},
+		{
+			Name:    "matching name with underscores",
+			ExtName: "pg-ivm",
+			Names:   []string{"pg_ivm", "pg_ivm_extra"},
+			Want:    "pg_ivm",
+		},
+		{
+			Name:    "ambiguous",
+			ExtName: "pgx",
+			Names:   []string{"a", "b"},
+			WantErr: "can't determine which SQL extension to create from a, b",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := pickSQLExtension(c.ExtName, c.Names)
+			if c.WantErr != "" {
+				assert.EqualError(err, c.WantErr)
+				return
+			}
+
+			assert.NoError(err)
+			assert.Equal(c.Want, got)
+		})
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/pgxman/pgxman"
 	"github.com/pgxman/pgxman/internal/log"
 	"github.com/pgxman/pgxman/internal/registry"
@@ -22,6 +24,14 @@ func (e errInvalidExtensionFormat) Error() string {
 	return fmt.Sprintf("invalid extension format: %q. The format is NAME=VERSION...", e.Arg)
 }
 
+type errInvalidUninstallExtensionFormat struct {
+	Arg string
+}
+
+func (e errInvalidUninstallExtensionFormat) Error() string {
+	return fmt.Sprintf("invalid extension format: %q. The format is NAME...", e.Arg)
+}
+
 type ErrExtNotFound struct {
 	Name string
 }
@@ -67,11 +77,11 @@ func DefaultPlatformDetector() (pgxman.Platform, error) {
 	return pgxman.DetectPlatform()
 }
 
-func NewArgsParser(c registry.Client, d PlatformDetector, pgver pgxman.PGVersion, overwrite bool) *ArgsParser {
+func NewArgsParser(c registry.Client, d PlatformDetector, pgvers []pgxman.PGVersion, overwrite bool) *ArgsParser {
 	return &ArgsParser{
 		Client:           c,
 		PlatformDetector: d,
-		PGVer:            pgver,
+		PGVers:           pgvers,
 		Overwrite:        overwrite,
 		Logger:           log.NewTextLogger(),
 	}
@@ -83,16 +93,24 @@ type ArgsParser struct {
 	Client           registry.Client
 	PlatformDetector PlatformDetector
 	Logger           *log.Logger
-	PGVer            pgxman.PGVersion
+	PGVers           []pgxman.PGVersion
 	Overwrite        bool
 }
 
+// Parse resolves the extensions of the arguments for each PostgreSQL version.
+// The extensions are ordered by the PostgreSQL versions and then by the arguments.
 func (p *ArgsParser) Parse(ctx context.Context, args []string) ([]pgxman.InstallExtension, error) {
-	if err := p.PGVer.Validate(); err != nil {
-		return nil, err
+	if len(p.PGVers) == 0 {
+		return nil, fmt.Errorf("PostgreSQL version is required")
 	}
 
-	var exts []pgxman.InstallExtension
+	for _, pgVer := range p.PGVers {
+		if err := pgVer.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var packExts []pgxman.PackExtension
 	for _, arg := range args {
 		ext, err := parseInstallExtension(arg)
 		if err != nil {
@@ -100,10 +118,22 @@ func (p *ArgsParser) Parse(ctx context.Context, args []string) ([]pgxman.Install
 		}
 		ext.Overwrite = p.Overwrite
 
-		exts = append(exts, pgxman.InstallExtension{
-			PackExtension: *ext,
-			PGVersion:     p.PGVer,
-		})
+		// a local package is built for a single PostgreSQL version
+		if ext.Path != "" && len(p.PGVers) > 1 {
+			return nil, fmt.Errorf("local package %s can't be installed for multiple PostgreSQL versions", ext.Path)
+		}
+
+		packExts = append(packExts, *ext)
+	}
+
+	var exts []pgxman.InstallExtension
+	for _, pgVer := range p.PGVers {
+		for _, ext := range packExts {
+			exts = append(exts, pgxman.InstallExtension{
+				PackExtension: ext,
+				PGVersion:     pgVer,
+			})
+		}
 	}
 
 	locker := NewExtensionLocker(p.Client, p.PlatformDetector, p.Logger)
@@ -124,55 +154,308 @@ type ExtensionLocker struct {
 	Logger           *log.Logger
 }
 
+// Lock resolves the extensions and their pgxman extension dependencies from the registry.
+// Dependencies are placed before the extensions that depend on them.
 func (l *ExtensionLocker) Lock(ctx context.Context, exts []pgxman.InstallExtension) ([]pgxman.InstallExtension, error) {
 	p, err := l.PlatformDetector()
 	if err != nil {
 		return nil, fmt.Errorf("detect platform: %s", err)
 	}
 
-	var result []pgxman.InstallExtension
+	result, err := resolveDependencies(ctx, exts, func(ctx context.Context, ext pgxman.InstallExtension) (pgxman.InstallExtension, error) {
+		return l.lock(ctx, ext, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return trimRegistryNames(result), nil
+}
+
+// trimRegistryNames removes the registry prefix, e.g. internal/pgvector, from the names of the resolved extensions
+// since the prefix only selects the registry to resolve an extension from.
+func trimRegistryNames(exts []pgxman.InstallExtension) []pgxman.InstallExtension {
+	for i := range exts {
+		_, exts[i].Name = registry.SplitName(exts[i].Name)
+		_, exts[i].RequiredBy = registry.SplitName(exts[i].RequiredBy)
+	}
+
+	return exts
+}
+
+// lock resolves the version, the apt repositories and the dependencies of an extension for the platform.
+// Extensions from a local path are returned as is.
+func (l *ExtensionLocker) lock(ctx context.Context, ext pgxman.InstallExtension, p pgxman.Platform) (pgxman.InstallExtension, error) {
+	if ext.Name == "" {
+		return ext, nil
+	}
+
+	installablePkg, err := l.resolvePackage(ctx, ext, p)
+	if err != nil {
+		return ext, err
+	}
+	ext.Version = installablePkg.Version
+
+	platform, err := getPlatform(installablePkg, p)
+	if err != nil {
+		return ext, &ErrExtIncompatiblePlatform{Name: ext.Name, Version: ext.Version, Platform: p}
+	}
+	ext.AptRepositories = convertAptRepos(platform.AptRepositories)
+	ext.SharedPreloadLibraries = installablePkg.SharedPreloadLibraries
+
+	ext.Dependencies = nil
+	for _, dep := range platform.RunDependencies {
+		if _, _, ok := parseExtensionDependency(dep); ok {
+			ext.Dependencies = append(ext.Dependencies, dep)
+		}
+	}
+
+	return ext, nil
+}
+
+type extensionResolver func(ctx context.Context, ext pgxman.InstallExtension) (pgxman.InstallExtension, error)
+
+// resolveDependencies resolves the extensions and walks their dependencies, resolving each dependency once per PostgreSQL version.
+// The result is in dependency order. A dependency that is also requested is resolved as requested,
+// with or without a registry prefix.
+func resolveDependencies(ctx context.Context, exts []pgxman.InstallExtension, resolve extensionResolver) ([]pgxman.InstallExtension, error) {
+	key := func(name string, pgVer pgxman.PGVersion) string {
+		_, name = registry.SplitName(name)
+		return string(pgVer) + "/" + normalizeExtensionName(name)
+	}
+
+	var (
+		resolved []pgxman.InstallExtension
+		byKey    = make(map[string]pgxman.InstallExtension)
+	)
 	for _, ext := range exts {
-		if ext.Name != "" {
-			installableExt, err := l.Client.GetExtension(ctx, ext.Name)
-			if err != nil {
-				if errors.Is(err, registry.ErrExtensionNotFound) {
-					err = &ErrExtNotFound{Name: ext.Name}
-				}
+		r, err := resolve(ctx, ext)
+		if err != nil {
+			return nil, err
+		}
 
-				return nil, err
-			}
+		resolved = append(resolved, r)
+		if r.Name != "" {
+			byKey[key(r.Name, r.PGVersion)] = r
+		}
+	}
 
-			// if version is not specified, use the latest version
-			if ext.Version != "" && ext.Version != "latest" {
-				installableExt, err = l.Client.GetVersion(ctx, ext.Name, ext.Version)
-				if err != nil {
-					if errors.Is(err, registry.ErrExtensionNotFound) {
-						err = &ErrExtVerNotFound{Name: ext.Name, Version: ext.Version}
-					}
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	var (
+		result []pgxman.InstallExtension
+		state  = make(map[string]int)
+		visit  func(ext pgxman.InstallExtension) error
+	)
+	visit = func(ext pgxman.InstallExtension) error {
+		if ext.Name == "" {
+			result = append(result, ext)
+			return nil
+		}
 
-					return nil, err
-				}
-			}
+		k := key(ext.Name, ext.PGVersion)
+		switch state[k] {
+		case visiting:
+			return fmt.Errorf("extension %q has a circular dependency", ext.Name)
+		case visited:
+			return nil
+		}
+		state[k] = visiting
 
-			installablePkg, ok := installableExt.Packages[string(ext.PGVersion)]
+		for _, dep := range ext.Dependencies {
+			name, version, _ := parseExtensionDependency(dep)
+
+			depExt, ok := byKey[key(name, ext.PGVersion)]
 			if !ok {
-				return nil, &ErrExtIncompatiblePG{Name: ext.Name, PGVersion: ext.PGVersion}
+				var err error
+				depExt, err = resolve(ctx, pgxman.InstallExtension{
+					PackExtension: pgxman.PackExtension{
+						Name:    name,
+						Version: version,
+					},
+					PGVersion: ext.PGVersion,
+				})
+				if err != nil {
+					return fmt.Errorf("dependency %s of extension %q: %w", dep, ext.Name, err)
+				}
+				depExt.RequiredBy = ext.Name
+
+				byKey[key(name, ext.PGVersion)] = depExt
+			} else if !satisfiesVersionRange(depExt.Version, version) {
+				return fmt.Errorf("dependency %s of %q requires %s but %s is resolved", name, ext.Name, version, depExt.Version)
 			}
-			ext.Version = installablePkg.Version
 
-			platform, err := getPlatform(installablePkg, p)
-			if err != nil {
-				return nil, &ErrExtIncompatiblePlatform{Name: ext.Name, Version: ext.Version, Platform: p}
+			if err := visit(depExt); err != nil {
+				return err
 			}
-			ext.AptRepositories = convertAptRepos(platform.AptRepositories)
 		}
 
+		state[k] = visited
 		result = append(result, ext)
+
+		return nil
+	}
+
+	for _, ext := range resolved {
+		if err := visit(ext); err != nil {
+			return nil, err
+		}
 	}
 
 	return result, nil
 }
 
+// satisfiesVersionRange reports whether the resolved version of a dependency is in the version range of a dependent.
+// A dependency without a version range is satisfied by any version.
+func satisfiesVersionRange(version, versionRange string) bool {
+	if versionRange == "" || version == "" {
+		return true
+	}
+
+	constraint, err := semver.NewConstraint(versionRange)
+	if err != nil {
+		return false
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+
+	return constraint.Check(v)
+}
+
+var (
+	debianVersionOps = strings.NewReplacer(">>", ">", "<<", "<")
+)
+
+// parseExtensionDependency parses a pgxman extension dependency, e.g. pgxman/pgvector or pgxman/pgvector (>= 0.5.0),
+// into the extension name and the version range. The Debian version relation is converted to a semver range.
+func parseExtensionDependency(dep string) (string, string, bool) {
+	dep, ok := strings.CutPrefix(strings.TrimSpace(dep), extensionDepPrefix)
+	if !ok {
+		return "", "", false
+	}
+
+	name, rel, _ := strings.Cut(dep, "(")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", false
+	}
+
+	rel = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(rel), ")"))
+	rel = strings.ReplaceAll(debianVersionOps.Replace(rel), " ", "")
+
+	return name, rel, true
+}
+
+// resolvePackage returns the package of the extension for its PostgreSQL version.
+// The version of the extension is empty or latest for the latest version, an exact version,
+// or a semver range for the highest version in the range.
+func (l *ExtensionLocker) resolvePackage(ctx context.Context, ext pgxman.InstallExtension, p pgxman.Platform) (oapi.Package, error) {
+	if isVersionRange(ext.Version) {
+		return l.resolveVersionRange(ctx, ext, p)
+	}
+
+	installableExt, err := l.Client.GetExtension(ctx, ext.Name)
+	if err != nil {
+		if errors.Is(err, registry.ErrExtensionNotFound) {
+			err = &ErrExtNotFound{Name: ext.Name}
+		}
+
+		return oapi.Package{}, err
+	}
+
+	// if version is not specified, use the latest version
+	if ext.Version != "" && ext.Version != "latest" {
+		installableExt, err = l.Client.GetVersion(ctx, ext.Name, ext.Version)
+		if err != nil {
+			if errors.Is(err, registry.ErrExtensionNotFound) {
+				err = &ErrExtVerNotFound{Name: ext.Name, Version: ext.Version}
+			}
+
+			return oapi.Package{}, err
+		}
+	}
+
+	installablePkg, ok := installableExt.Packages[string(ext.PGVersion)]
+	if !ok {
+		return oapi.Package{}, &ErrExtIncompatiblePG{Name: ext.Name, PGVersion: ext.PGVersion}
+	}
+
+	return installablePkg, nil
+}
+
+// resolveVersionRange returns the package of the highest version in the version range of the extension
+// that is available for the platform.
+func (l *ExtensionLocker) resolveVersionRange(ctx context.Context, ext pgxman.InstallExtension, p pgxman.Platform) (oapi.Package, error) {
+	constraint, err := semver.NewConstraint(ext.Version)
+	if err != nil {
+		return oapi.Package{}, fmt.Errorf("invalid version range %q of extension %q: %w", ext.Version, ext.Name, err)
+	}
+
+	versions, err := l.Client.ListVersions(ctx, ext.Name)
+	if err != nil {
+		if errors.Is(err, registry.ErrExtensionNotFound) {
+			err = &ErrExtNotFound{Name: ext.Name}
+		}
+
+		return oapi.Package{}, err
+	}
+
+	pkgs, ok := versions[string(ext.PGVersion)]
+	if !ok || len(pkgs) == 0 {
+		return oapi.Package{}, &ErrExtIncompatiblePG{Name: ext.Name, PGVersion: ext.PGVersion}
+	}
+
+	var (
+		result      *oapi.Package
+		resultVer   *semver.Version
+		unsupported *semver.Version
+	)
+	for _, pkg := range pkgs {
+		pkg := pkg
+
+		v, err := semver.NewVersion(pkg.Version)
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+
+		if _, err := getPlatform(pkg, p); err != nil {
+			if unsupported == nil || v.GreaterThan(unsupported) {
+				unsupported = v
+			}
+			continue
+		}
+
+		if resultVer == nil || v.GreaterThan(resultVer) {
+			result = &pkg
+			resultVer = v
+		}
+	}
+
+	if result == nil {
+		if unsupported != nil {
+			return oapi.Package{}, &ErrExtIncompatiblePlatform{Name: ext.Name, Version: unsupported.Original(), Platform: p}
+		}
+
+		return oapi.Package{}, &ErrExtVerNotFound{Name: ext.Name, Version: ext.Version}
+	}
+
+	l.Logger.Debug("Resolved version range", "name", ext.Name, "range", ext.Version, "version", result.Version, "pg", ext.PGVersion)
+
+	return *result, nil
+}
+
+// isVersionRange reports whether the version is a semver range, e.g. ^0.5, ~3.4, >=1.2,<2 or 1.x,
+// rather than an exact version.
+func isVersionRange(v string) bool {
+	return regexpVersionRange.MatchString(v)
+}
+
 func getPlatform(pkg oapi.Package, p pgxman.Platform) (*oapi.Platform, error) {
 	for _, platform := range pkg.Platforms {
 		if string(platform.Os) == string(p) {
@@ -183,8 +466,14 @@ func getPlatform(pkg oapi.Package, p pgxman.Platform) (*oapi.Platform, error) {
 	return nil, fmt.Errorf("platform %q not found", p)
 }
 
+const (
+	extensionDepPrefix = "pgxman/"
+)
+
 var (
-	extRegexp = regexp.MustCompile(`^([^=@\s]+)(?:=([^@]*))?$`)
+	extRegexp          = regexp.MustCompile(`^([^=@\s]+)(?:=([^@]*))?$`)
+	uninstallExtRegexp = regexp.MustCompile(`^[^=@/\s]+$`)
+	regexpVersionRange = regexp.MustCompile(`[\^~<>=!*|, ]|(^|\.)[xX](\.|$)`)
 )
 
 func parseInstallExtension(arg string) (*pgxman.PackExtension, error) {
@@ -217,6 +506,14 @@ func parseInstallExtension(arg string) (*pgxman.PackExtension, error) {
 	return nil, errInvalidExtensionFormat{Arg: arg}
 }
 
+func parseUninstallExtension(arg string) (string, error) {
+	if !uninstallExtRegexp.MatchString(arg) {
+		return "", errInvalidUninstallExtensionFormat{Arg: arg}
+	}
+
+	return arg, nil
+}
+
 func convertAptRepos(aptRepos []oapi.AptRepository) []pgxman.AptRepository {
 	var result []pgxman.AptRepository
 	for _, aptRepo := range aptRepos {
@@ -267,7 +564,8 @@ func convertAptRepoSignedKey(signedKey oapi.SignedKey) pgxman.AptRepositorySigne
 	}
 
 	return pgxman.AptRepositorySignedKey{
-		URL:    signedKey.Url,
-		Format: format,
+		URL:         signedKey.Url,
+		Format:      format,
+		Fingerprint: signedKey.Fingerprint,
 	}
 }
@@ -2,6 +2,7 @@ package pgxman
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -58,6 +59,14 @@ func Test_parseInstallExtension(t *testing.T) {
 				Version: "latest",
 			},
 		},
+		{
+			Name: "valid with version range",
+			Arg:  "pgvector=>=0.5,<0.6",
+			GotExt: &pgxman.PackExtension{
+				Name:    "pgvector",
+				Version: ">=0.5,<0.6",
+			},
+		},
 		{
 			Name: "valid with only name",
 			Arg:  "pgvector",
@@ -92,6 +101,44 @@ func Test_parseInstallExtension(t *testing.T) {
 	}
 }
 
+func Test_parseUninstallExtension(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		Name    string
+		Arg     string
+		GotName string
+		Err     error
+	}{
+		{
+			Name:    "valid with name",
+			Arg:     "pgvector",
+			GotName: "pgvector",
+		},
+		{
+			Name: "invalid with version",
+			Arg:  "pgvector=0.5.0",
+			Err:  errInvalidUninstallExtensionFormat{Arg: "pgvector=0.5.0"},
+		},
+		{
+			Name: "invalid with path",
+			Arg:  "/PATH_TO/postgresql-15-pgxman-pgvector_0.5.0_arm64.deb",
+			Err:  errInvalidUninstallExtensionFormat{Arg: "/PATH_TO/postgresql-15-pgxman-pgvector_0.5.0_arm64.deb"},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			t.Parallel()
+
+			gotName, err := parseUninstallExtension(c.Arg)
+			assert.Equal(c.Err, err)
+			assert.Equal(c.GotName, gotName)
+		})
+	}
+}
+
 func Test_ExtensionLocker(t *testing.T) {
 	assert := assert.New(t)
 
@@ -120,6 +167,7 @@ func Test_ExtensionLocker(t *testing.T) {
 							AptRepositories: aptRepos,
 						},
 					},
+					SharedPreloadLibraries: []string{"vector"},
 				},
 			},
 		},
@@ -137,6 +185,26 @@ func Test_ExtensionLocker(t *testing.T) {
 				},
 			},
 		},
+		ExtVersions: oapi.Versions{
+			string(pgxman.PGVersion16): {
+				{
+					Version:   "0.4.4",
+					Platforms: []oapi.Platform{{Os: oapi.DebianBookworm, AptRepositories: aptRepos}},
+				},
+				{
+					Version:   "0.5.1",
+					Platforms: []oapi.Platform{{Os: oapi.DebianBookworm, AptRepositories: aptRepos}},
+				},
+				{
+					Version:   "0.5.0",
+					Platforms: []oapi.Platform{{Os: oapi.DebianBookworm, AptRepositories: aptRepos}},
+				},
+				{
+					Version:   "0.6.0",
+					Platforms: []oapi.Platform{{Os: oapi.UbuntuJammy, AptRepositories: aptRepos}},
+				},
+			},
+		},
 	}
 	stubbedPlatformDetector := func() (pgxman.Platform, error) {
 		return pgxman.PlatformDebianBookworm, nil
@@ -183,6 +251,30 @@ func Test_ExtensionLocker(t *testing.T) {
 					PGVersion: pgxman.PGVersion16,
 				},
 			},
+			WantInstallExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "0.5.1",
+					},
+					PGVersion:              pgxman.PGVersion16,
+					AptRepositories:        convertAptRepos(aptRepos),
+					SharedPreloadLibraries: []string{"vector"},
+				},
+			},
+		},
+		{
+			Name:             "install caret range",
+			PlatformDetector: stubbedPlatformDetector,
+			InstallExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "^0.5",
+					},
+					PGVersion: pgxman.PGVersion16,
+				},
+			},
 			WantInstallExts: []pgxman.InstallExtension{
 				{
 					PackExtension: pgxman.PackExtension{
@@ -194,6 +286,57 @@ func Test_ExtensionLocker(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:             "install bounded range",
+			PlatformDetector: stubbedPlatformDetector,
+			InstallExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: ">=0.4,<0.5.1",
+					},
+					PGVersion: pgxman.PGVersion16,
+				},
+			},
+			WantInstallExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "0.5.0",
+					},
+					PGVersion:       pgxman.PGVersion16,
+					AptRepositories: convertAptRepos(aptRepos),
+				},
+			},
+		},
+		{
+			Name:             "range without version for the platform",
+			PlatformDetector: stubbedPlatformDetector,
+			InstallExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "~0.6",
+					},
+					PGVersion: pgxman.PGVersion16,
+				},
+			},
+			WantErr: &ErrExtIncompatiblePlatform{Name: "pgvector", Version: "0.6.0", Platform: pgxman.PlatformDebianBookworm},
+		},
+		{
+			Name:             "range without matching version",
+			PlatformDetector: stubbedPlatformDetector,
+			InstallExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "^1",
+					},
+					PGVersion: pgxman.PGVersion16,
+				},
+			},
+			WantErr: &ErrExtVerNotFound{Name: "pgvector", Version: "^1"},
+		},
 		{
 			Name:             "version doesn't exist",
 			PlatformDetector: stubbedPlatformDetector,
@@ -264,9 +407,279 @@ func Test_ExtensionLocker(t *testing.T) {
 	}
 }
 
+func Test_ArgsParser(t *testing.T) {
+	assert := assert.New(t)
+
+	pkg := oapi.Package{
+		Version: "0.5.1",
+		Platforms: []oapi.Platform{
+			{
+				Os: oapi.DebianBookworm,
+			},
+		},
+	}
+	stubbedClient := StubbedRegistryClient{
+		ExtGetExtension: &oapi.Extension{
+			Name: "pgvector",
+			Packages: oapi.Packages{
+				string(pgxman.PGVersion15): pkg,
+				string(pgxman.PGVersion16): pkg,
+			},
+		},
+	}
+	stubbedPlatformDetector := func() (pgxman.Platform, error) {
+		return pgxman.PlatformDebianBookworm, nil
+	}
+
+	p := NewArgsParser(stubbedClient, stubbedPlatformDetector, []pgxman.PGVersion{pgxman.PGVersion16, pgxman.PGVersion15}, false)
+	exts, err := p.Parse(context.TODO(), []string{"pgvector"})
+	assert.NoError(err)
+	assert.Equal([]pgxman.InstallExtension{
+		{
+			PackExtension: pgxman.PackExtension{
+				Name:    "pgvector",
+				Version: "0.5.1",
+			},
+			PGVersion: pgxman.PGVersion16,
+		},
+		{
+			PackExtension: pgxman.PackExtension{
+				Name:    "pgvector",
+				Version: "0.5.1",
+			},
+			PGVersion: pgxman.PGVersion15,
+		},
+	}, exts)
+
+	dir := t.TempDir()
+	deb := filepath.Join(dir, "postgresql-15-pgxman-pgvector_0.5.1_amd64.deb")
+	assert.NoError(os.WriteFile(deb, nil, 0644))
+
+	_, err = p.Parse(context.TODO(), []string{deb})
+	assert.ErrorContains(err, "can't be installed for multiple PostgreSQL versions")
+
+	p = NewArgsParser(stubbedClient, stubbedPlatformDetector, nil, false)
+	_, err = p.Parse(context.TODO(), []string{"pgvector"})
+	assert.Error(err)
+}
+
+func Test_ExtensionLocker_Dependencies(t *testing.T) {
+	assert := assert.New(t)
+
+	newExt := func(name, version string, deps ...string) *oapi.Extension {
+		return &oapi.Extension{
+			Name: name,
+			Packages: oapi.Packages{
+				string(pgxman.PGVersion16): {
+					Version: version,
+					Platforms: []oapi.Platform{
+						{
+							Os:              oapi.DebianBookworm,
+							RunDependencies: deps,
+							AptRepositories: []oapi.AptRepository{
+								{
+									Id:         name,
+									Types:      []oapi.AptRepositoryType{oapi.Deb},
+									Uris:       []string{"https://example.com/" + name},
+									Suites:     []string{"bookworm"},
+									Components: []string{"main"},
+									SignedKey: oapi.SignedKey{
+										Format: oapi.Gpg,
+										Url:    "https://example.com/" + name + ".gpg",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	stubbedPlatformDetector := func() (pgxman.Platform, error) {
+		return pgxman.PlatformDebianBookworm, nil
+	}
+	installExt := func(name string) pgxman.InstallExtension {
+		return pgxman.InstallExtension{
+			PackExtension: pgxman.PackExtension{
+				Name: name,
+			},
+			PGVersion: pgxman.PGVersion16,
+		}
+	}
+
+	cases := []struct {
+		Name        string
+		Extensions  []*oapi.Extension
+		InstallExts []pgxman.InstallExtension
+		// WantExts are the names, versions and the dependents of the locked extensions
+		WantExts []string
+		WantErr  string
+	}{
+		{
+			Name: "dependencies before dependents",
+			Extensions: []*oapi.Extension{
+				newExt("pgvectorscale", "0.2.0", "libc6", "pgxman/pgvector (>= 0.5.0)"),
+				newExt("pgvector", "0.5.1", "pgxman/pg_hint_plan"),
+				newExt("pg_hint_plan", "1.6.0"),
+			},
+			InstallExts: []pgxman.InstallExtension{installExt("pgvectorscale")},
+			WantExts: []string{
+				"pg_hint_plan 1.6.0 pgvector",
+				"pgvector 0.5.1 pgvectorscale",
+				"pgvectorscale 0.2.0 ",
+			},
+		},
+		{
+			Name: "requested dependency",
+			Extensions: []*oapi.Extension{
+				newExt("pgvectorscale", "0.2.0", "pgxman/pgvector"),
+				newExt("pgvector", "0.5.1"),
+			},
+			InstallExts: []pgxman.InstallExtension{installExt("pgvectorscale"), installExt("pgvector")},
+			WantExts: []string{
+				"pgvector 0.5.1 ",
+				"pgvectorscale 0.2.0 ",
+			},
+		},
+		{
+			Name: "shared dependency in range",
+			Extensions: []*oapi.Extension{
+				newExt("pgvectorscale", "0.2.0", "pgxman/pgvector (>= 0.5.0)"),
+				newExt("pgvector", "0.5.1"),
+				newExt("pg_similarity", "1.0.0", "pgxman/pgvector (>= 0.4.0)"),
+			},
+			InstallExts: []pgxman.InstallExtension{installExt("pgvectorscale"), installExt("pg_similarity")},
+			WantExts: []string{
+				"pgvector 0.5.1 pgvectorscale",
+				"pgvectorscale 0.2.0 ",
+				"pg_similarity 1.0.0 ",
+			},
+		},
+		{
+			Name: "shared dependency out of range",
+			Extensions: []*oapi.Extension{
+				newExt("pgvectorscale", "0.2.0", "pgxman/pgvector (>= 0.6.0)"),
+				newExt("pgvector", "0.5.1"),
+			},
+			InstallExts: []pgxman.InstallExtension{installExt("pgvector"), installExt("pgvectorscale")},
+			WantErr:     `dependency pgvector of "pgvectorscale" requires >=0.6.0 but 0.5.1 is resolved`,
+		},
+		{
+			Name: "circular dependency",
+			Extensions: []*oapi.Extension{
+				newExt("pgvectorscale", "0.2.0", "pgxman/pgvector"),
+				newExt("pgvector", "0.5.1", "pgxman/pgvectorscale"),
+			},
+			InstallExts: []pgxman.InstallExtension{installExt("pgvectorscale")},
+			WantErr:     "circular dependency",
+		},
+		{
+			Name: "dependency not found",
+			Extensions: []*oapi.Extension{
+				newExt("pgvectorscale", "0.2.0", "pgxman/pgvector"),
+			},
+			InstallExts: []pgxman.InstallExtension{installExt("pgvectorscale")},
+			WantErr:     `dependency pgxman/pgvector of extension "pgvectorscale"`,
+		},
+		{
+			Name: "registry prefix",
+			Extensions: []*oapi.Extension{
+				newExt("pgvectorscale", "0.2.0", "pgxman/pgvector"),
+				newExt("pgvector", "0.5.1"),
+			},
+			InstallExts: []pgxman.InstallExtension{installExt("public/pgvectorscale"), installExt("public/pgvector")},
+			WantExts: []string{
+				"pgvector 0.5.1 ",
+				"pgvectorscale 0.2.0 ",
+			},
+		},
+		{
+			Name: "unknown registry",
+			Extensions: []*oapi.Extension{
+				newExt("pgvector", "0.5.1"),
+			},
+			InstallExts: []pgxman.InstallExtension{installExt("internal/pgvector")},
+			WantErr:     `unknown registry "internal"`,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			client := registry.Registries{
+				{
+					Name: "public",
+					Client: StubbedRegistryClient{
+						ExtGetExtension: c.Extensions[0],
+						OtherExtensions: c.Extensions[1:],
+					},
+				},
+			}
+			locker := NewExtensionLocker(client, stubbedPlatformDetector, log.NewTextLogger())
+			exts, err := locker.Lock(context.TODO(), c.InstallExts)
+			if c.WantErr != "" {
+				assert.ErrorContains(err, c.WantErr)
+				return
+			}
+			assert.NoError(err)
+
+			var got []string
+			for _, ext := range exts {
+				got = append(got, fmt.Sprintf("%s %s %s", ext.Name, ext.Version, ext.RequiredBy))
+				assert.Len(ext.AptRepositories, 1)
+				assert.Equal(ext.Name, ext.AptRepositories[0].ID)
+			}
+			assert.Equal(c.WantExts, got)
+		})
+	}
+}
+
+func Test_parseExtensionDependency(t *testing.T) {
+	cases := []struct {
+		Dep         string
+		WantName    string
+		WantVersion string
+		WantOK      bool
+	}{
+		{Dep: "pgxman/pgvector", WantName: "pgvector", WantOK: true},
+		{Dep: "pgxman/pgvector (>= 0.5.0)", WantName: "pgvector", WantVersion: ">=0.5.0", WantOK: true},
+		{Dep: "pgxman/pgvector (<< 0.6.0)", WantName: "pgvector", WantVersion: "<0.6.0", WantOK: true},
+		{Dep: "libc6"},
+		{Dep: "pgxman/"},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Dep, func(t *testing.T) {
+			assert := assert.New(t)
+
+			name, version, ok := parseExtensionDependency(c.Dep)
+			assert.Equal(c.WantOK, ok)
+			assert.Equal(c.WantName, name)
+			assert.Equal(c.WantVersion, version)
+		})
+	}
+}
+
+func Test_isVersionRange(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, v := range []string{"^0.5", "~3.4", ">=1.2,<2", ">= 1.2", "1.x", "1.2.*", "!=1.0.0"} {
+		assert.True(isVersionRange(v), v)
+	}
+
+	for _, v := range []string{"", "latest", "0.5.0", "1.0.0-beta.1", "5298b7f0254923f52d15e554ec8a5fdc0474f059"} {
+		assert.False(isVersionRange(v), v)
+	}
+}
+
 type StubbedRegistryClient struct {
 	ExtGetExtension *oapi.Extension
 	ExtGetVersion   *oapi.Extension
+	ExtVersions     oapi.Versions
+	// OtherExtensions are returned by GetExtension in addition to ExtGetExtension
+	OtherExtensions []*oapi.Extension
 }
 
 func (s StubbedRegistryClient) GetUser(ctx context.Context) (*oapi.User, error) {
@@ -278,6 +691,12 @@ func (s StubbedRegistryClient) GetExtension(ctx context.Context, name string) (*
 		return s.ExtGetExtension, nil
 	}
 
+	for _, ext := range s.OtherExtensions {
+		if name == ext.Name {
+			return ext, nil
+		}
+	}
+
 	return nil, registry.ErrExtensionNotFound
 }
 
@@ -303,3 +722,22 @@ func (s StubbedRegistryClient) GetVersion(ctx context.Context, name, version str
 	return nil, registry.ErrExtensionNotFound
 
 }
+
+func (s StubbedRegistryClient) ListVersions(ctx context.Context, name string) (oapi.Versions, error) {
+	if name == s.ExtGetExtension.Name {
+		return s.ExtVersions, nil
+	}
+
+	for _, ext := range s.OtherExtensions {
+		if name == ext.Name {
+			versions := make(oapi.Versions)
+			for pgVer, pkg := range ext.Packages {
+				versions[pgVer] = append(versions[pgVer], pkg)
+			}
+
+			return versions, nil
+		}
+	}
+
+	return nil, registry.ErrExtensionNotFound
+}
@@ -131,7 +131,7 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	client, err := newReigstryClient()
+	client, err := newSingleRegistryClient(flagRegistryURL)
 	if err != nil {
 		return err
 	}
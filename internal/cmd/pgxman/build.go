@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pgxman/pgxman"
 	"github.com/pgxman/pgxman/internal/cmd"
@@ -36,9 +37,73 @@ func newBuildCmd() *cobra.Command {
 	cmd.PersistentFlags().IntVar(&flagBuildParallel, "parallel", 2, "Number of parrallel builds to run")
 	cmd.PersistentFlags().BoolVar(&flagBuildPull, "pull", false, "Always attempt to pull all referenced images")
 
+	cmd.AddCommand(newBuildChecksumCmd())
+
+	return cmd
+}
+
+func newBuildChecksumCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "checksum",
+		Short: "Compute the checksum of the extension source",
+		Long: `Download the HTTP source of each PostgreSQL version in the extension manifest file and print its
+SHA-256 checksum to be set as sourceSha256. The build fails if the downloaded source doesn't match sourceSha256.`,
+		Example: `  # Compute the checksum of the source in extension.yaml
+  pgxman build checksum
+
+  # Compute the checksum of a new version
+  pgxman build checksum --set source=https://github.com/pgvector/pgvector/archive/refs/tags/v0.5.1.tar.gz`,
+		Args: cobra.NoArgs,
+		RunE: runBuildChecksum,
+	}
+
 	return cmd
 }
 
+func runBuildChecksum(c *cobra.Command, args []string) error {
+	extFile, err := filepath.Abs(flagBuildExtensionFile)
+	if err != nil {
+		return err
+	}
+
+	ext, err := pgxman.ReadExtension(extFile, cmd.ParseMapFlag(flagBuildSet))
+	if err != nil {
+		return err
+	}
+
+	// packages of PostgreSQL versions without overrides share the same source
+	var (
+		sources []string
+		pgVers  = make(map[string][]string)
+	)
+	for _, pkg := range ext.Packages() {
+		if _, ok := pgVers[pkg.Source]; !ok {
+			sources = append(sources, pkg.Source)
+		}
+		pgVers[pkg.Source] = append(pgVers[pkg.Source], string(pkg.PGVersion))
+	}
+
+	var failed bool
+	for _, source := range sources {
+		overridable := pgxman.ExtensionOverridable{Source: source, Path: extFile}
+		sum, err := overridable.SourceChecksum()
+		if err != nil {
+			fmt.Printf("[%s] PostgreSQL %s: %s\n", errorMark, strings.Join(pgVers[source], ", "), err)
+			failed = true
+			continue
+		}
+
+		fmt.Printf("[%s] PostgreSQL %s: %s\n", successMark, strings.Join(pgVers[source], ", "), source)
+		fmt.Printf("    sourceSha256: %s\n", sum)
+	}
+
+	if failed {
+		return fmt.Errorf("failed to compute the checksum of the source")
+	}
+
+	return nil
+}
+
 func runBuild(c *cobra.Command, args []string) error {
 	if flagBuildParallel < 1 {
 		return fmt.Errorf("invalid parallel value: %d", flagBuildParallel)
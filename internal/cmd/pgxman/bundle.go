@@ -0,0 +1,239 @@
+package pgxman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/cmd/cmdutil"
+	"github.com/pgxman/pgxman/internal/errorsx"
+	"github.com/pgxman/pgxman/internal/iostreams"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/internal/plugin"
+	"github.com/pgxman/pgxman/internal/tui/spinner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagBundleCreateFile   string
+	flagBundleCreateOutput string
+	flagBundleInstallYes   bool
+)
+
+func newBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Manage offline bundles of PostgreSQL extensions",
+		Long: `Manage offline bundles of PostgreSQL extensions. A bundle is an archive of the
+extension packages, their dependencies and the apt repository keyrings. It installs
+extensions on hosts that can't access the pgxman registry or apt repositories.`,
+	}
+
+	cmd.AddCommand(newBundleCreateCmd())
+	cmd.AddCommand(newBundleInstallCmd())
+
+	return cmd
+}
+
+func newBundleCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an offline bundle from a pack file",
+		Long: `Create an offline bundle from a pack file (e.g., pgxman.yaml). The extensions are
+resolved with the pgxman.lock file next to the pack file if it exists. Dependencies that are
+already installed on this host are not included in the bundle, so create the bundle on a host
+with the same platform and installed packages as the target hosts.`,
+		Example: `  # Create bundle.tar from the pgxman.yaml file in the current directory
+  pgxman bundle create -o bundle.tar
+
+  # Specify a different location for the pgxman.yaml file
+  pgxman bundle create -f /PATH_TO/pgxman.yaml -o bundle.tar`,
+		RunE: runBundleCreate,
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	cmd.PersistentFlags().StringVarP(&flagBundleCreateFile, "file", "f", filepath.Join(pwd, "pgxman.yaml"), "The pack file to use.")
+	cmd.PersistentFlags().StringVarP(&flagBundleCreateOutput, "output", "o", filepath.Join(pwd, "bundle.tar"), "The path of the bundle. It must have a .tar extension.")
+
+	return cmd
+}
+
+func newBundleInstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install PostgreSQL extensions from an offline bundle",
+		Long: `Install PostgreSQL extensions from an offline bundle created by pgxman bundle create.
+The installation doesn't need network access.`,
+		Example: `  # Install extensions from bundle.tar
+  pgxman bundle install bundle.tar
+
+  # Suppress prompts for automatic installation
+  pgxman bundle install bundle.tar -y`,
+		RunE: runBundleInstall,
+		Args: cobra.ExactArgs(1),
+	}
+
+	cmd.PersistentFlags().BoolVarP(&flagBundleInstallYes, "yes", "y", false, `Automatic yes to prompts and run install non-interactively.`)
+
+	return cmd
+}
+
+func runBundleCreate(cmd *cobra.Command, args []string) error {
+	b, err := getBundler(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	p, err := pgxman.ReadPackFile(flagBundleCreateFile)
+	if err != nil {
+		return err
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	client, err := newReigstryClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	lockFile, err := pgxman.PackLockFilePath(flagBundleCreateFile)
+	if err != nil {
+		return err
+	}
+
+	lock, err := pgxman.ReadPackLockFile(lockFile)
+	if err != nil {
+		return err
+	}
+
+	locker := NewPackLocker(client, DefaultPlatformDetector, false, log.NewTextLogger())
+	exts, _, err := locker.Lock(cmd.Context(), lock, p.InstallExtensions())
+	if err != nil {
+		return err
+	}
+
+	logger := log.NewTextLogger()
+	fmt.Printf("Bundling extensions for PostgreSQL %s...\n", joinPGVersions(p.PGVersions()))
+	if err := bundle(cmd.Context(), b, exts, flagBundleCreateOutput); err != nil {
+		logger.Debug("failed to bundle extensions", "error", err)
+		return cmdutil.SilentError
+	}
+
+	return nil
+}
+
+func runBundleInstall(cmd *cobra.Command, args []string) error {
+	b, err := getBundler(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	src := args[0]
+	if !flagBundleInstallYes {
+		if err := b.PreInstallBundleCheck(cmd.Context(), src, iostreams.NewIOStreams()); err != nil {
+			return err
+		}
+	}
+
+	logger := log.NewTextLogger()
+	fmt.Printf("Installing extensions from %s...\n", src)
+	exts, err := installBundle(cmd.Context(), b, src)
+	if err != nil {
+		logger.Debug("failed to install bundle", "error", err, "bundle", src)
+		return cmdutil.SilentError
+	}
+
+	if err := configurePreloadLibraries(exts); err != nil {
+		logger.Debug("failed to configure shared preload libraries", "error", err, "extensions", exts)
+		return cmdutil.SilentError
+	}
+
+	return nil
+}
+
+func getBundler(ctx context.Context) (pgxman.Bundler, error) {
+	i, err := plugin.GetInstaller(ctx)
+	if err != nil {
+		return nil, errorsx.Pretty(err)
+	}
+
+	b, ok := i.(pgxman.Bundler)
+	if !ok {
+		return nil, fmt.Errorf("offline bundles are not supported on this platform")
+	}
+
+	return b, nil
+}
+
+func bundle(ctx context.Context, b pgxman.Bundler, exts []pgxman.InstallExtension, dst string) error {
+	var names []string
+	for _, ext := range exts {
+		names = append(names, ext.String())
+	}
+
+	s := spinner.New(flagDebug)
+	s.WithIndicator(fmt.Sprintf("Downloading %s...\n", strings.Join(names, ", ")))
+	defer s.Stop()
+
+	s.Start()
+	if err := b.Bundle(ctx, exts, dst); err != nil {
+		err = handleBundleErr(err, "bundle")
+		s.WithDone(fmt.Sprintf("[%s] %s: %s\n", errorMark, dst, err))
+		return err
+	}
+
+	s.WithDone(fmt.Sprintf("[%s] %s\n", successMark, dst))
+
+	return nil
+}
+
+func installBundle(ctx context.Context, b pgxman.Bundler, src string) ([]pgxman.InstallExtension, error) {
+	s := spinner.New(flagDebug)
+	s.WithIndicator(fmt.Sprintf("Installing %s...\n", src))
+	defer s.Stop()
+
+	s.Start()
+	exts, err := b.InstallBundle(ctx, src)
+	if err != nil {
+		err = handleBundleErr(err, "install")
+		s.WithDone(fmt.Sprintf("[%s] %s: %s\n", errorMark, src, err))
+		return nil, err
+	}
+
+	var done []string
+	for _, ext := range exts {
+		if ext.Name != "" {
+			done = append(done, fmt.Sprintf("[%s] %s: https://pgx.sh/%s\n", successMark, ext, ext.Name))
+		} else {
+			done = append(done, fmt.Sprintf("[%s] %s\n", successMark, ext))
+		}
+	}
+	s.WithDone(strings.Join(done, ""))
+
+	return exts, nil
+}
+
+func handleBundleErr(err error, action string) error {
+	if errors.Is(err, pgxman.ErrRootAccessRequired) {
+		return fmt.Errorf("must run command as root: sudo %s", strings.Join(os.Args, " "))
+	}
+
+	if errors.Is(err, pgxman.ErrConflictExtension) {
+		return fmt.Errorf("has already been installed outside of pgxman, set `overwrite: true` in the pack file to overwrite it")
+	}
+
+	if errors.Is(err, pgxman.ErrRolledBack) {
+		return fmt.Errorf("failed to %s and rolled back to the previous state, run with `--debug` to see the full error: %w", action, err)
+	}
+
+	return fmt.Errorf("failed to %s, run with `--debug` to see the full error: %w", action, err)
+}
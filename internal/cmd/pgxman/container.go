@@ -114,7 +114,7 @@ is NAME=VERSION.`, action),
 
 func runContainerInstall(upgrade bool) func(c *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		client, err := newReigstryClient()
+		client, err := newReigstryClient(cmd)
 		if err != nil {
 			return err
 		}
@@ -122,7 +122,7 @@ func runContainerInstall(upgrade bool) func(c *cobra.Command, args []string) err
 		p := NewArgsParser(
 			client,
 			ContainerPlatformDetector,
-			pgxman.PGVersion(flagContainerInstallPGVersion),
+			[]pgxman.PGVersion{pgxman.PGVersion(flagContainerInstallPGVersion)},
 			true,
 		)
 		exts, err := p.Parse(cmd.Context(), args)
@@ -6,11 +6,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"os"
 	"strings"
 	"text/template"
 
 	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/cmd/cmdutil"
 	"github.com/pgxman/pgxman/internal/errorsx"
 	"github.com/pgxman/pgxman/internal/iostreams"
 	"github.com/pgxman/pgxman/internal/log"
@@ -33,6 +33,12 @@ var (
 	flagInstallOrUpgradeYes       bool
 	flagInstallOrUpgradePGVersion string
 	flagInstallOrUpgradeOverwrite bool
+	flagInstallOrUpgradeDryRun    bool
+	flagInstallOrUpgradeOutput    string
+	flagInstallOrUpgradeDatabases []string
+	flagInstallOrUpgradeAllDBs    bool
+	flagInstallOrUpgradeConnStr   string
+	flagInstallOrUpgradePGConfig  string
 )
 
 func newInstallOrUpgradeCmd(upgrade bool) *cobra.Command {
@@ -65,11 +71,35 @@ func newInstallOrUpgradeCmd(upgrade bool) *cobra.Command {
   # {{ title .Action }} pgvector 0.5.0 for PostgreSQL {{ .PGVer }} with sudo
   pgxman {{ .Action }} pgvector=0.5.0 --pg {{ .PGVer }} --sudo
 
+  # {{ title .Action }} the highest pgvector 0.5.x for PostgreSQL {{ .PGVer }}
+  pgxman {{ .Action }} "pgvector=^0.5" --pg {{ .PGVer }}
+
+  # {{ title .Action }} the highest postgis in a version range for PostgreSQL {{ .PGVer }}
+  pgxman {{ .Action }} "postgis=>=3.3,<3.5" --pg {{ .PGVer }}
+
   # {{ title .Action }} pgvector 0.5.0 and postgis 3.3.3 for PostgreSQL {{ .PGVer }}
   pgxman {{ .Action }} pgvector=0.5.0 postgis=3.3.3 --pg {{ .PGVer }}
 
+  # {{ title .Action }} the latest pgvector for PostgreSQL 15 and 16
+  pgxman {{ .Action }} pgvector --pg 15,16
+
+  # {{ title .Action }} the latest pgvector for all installed PostgreSQL versions
+  pgxman {{ .Action }} pgvector --pg all-installed
+
+  # {{ title .Action }} pgvector and {{ if eq .Action "install" }}create{{ else }}update{{ end }} it in the app database
+  pgxman {{ .Action }} pgvector --database app
+
+  # {{ title .Action }} pgvector and {{ if eq .Action "install" }}create{{ else }}update{{ end }} it in all databases of a PostgreSQL server
+  pgxman {{ .Action }} pgvector --all-databases --connection-string "postgres://postgres@localhost:5432/postgres"
+
+  # Show the plan to {{ .Action }} pgvector 0.5.0 without changing the system
+  pgxman {{ .Action }} pgvector=0.5.0 --dry-run
+
   # {{ title .Action }} from a local Debian package
-  pgxman {{ .Action }} /PATH_TO/postgresql-15-pgxman-pgvector_0.5.0_arm64.deb`
+  pgxman {{ .Action }} /PATH_TO/postgresql-15-pgxman-pgvector_0.5.0_arm64.deb
+
+  # {{ title .Action }} from a local tarball into PostgreSQL under a custom prefix
+  pgxman {{ .Action }} /PATH_TO/pgxman-pgvector_0.5.0_pg15_arm64.tar.gz --pg-config /opt/pgsql/bin/pg_config`
 
 	type data struct {
 		Action string
@@ -100,50 +130,92 @@ func newInstallOrUpgradeCmd(upgrade bool) *cobra.Command {
 		Aliases: []string{alias},
 		Short:   c.String(action) + " PostgreSQL extensions",
 		Long: c.String(action) + ` PostgreSQL extensions from commandline arguments. The argument
-format is NAME=VERSION. The PostgreSQL version is detected from pg_config
-if it exists, or can be specified with the --pg flag.`,
+format is NAME=VERSION where VERSION is an exact version, latest, or a semver
+range such as ^0.5, ~3.4 or >=1.2,<2 that resolves to the highest version in
+the range. The PostgreSQL version is detected from pg_config
+if it exists, or can be specified with the --pg flag. Multiple PostgreSQL
+versions can be specified with the --pg flag as a comma-separated list, or
+all-installed for all installed versions.`,
 		Example: buf.String(),
 		RunE:    runInstallOrUpgrade(upgrade),
 		Args:    cobra.MinimumNArgs(1),
 	}
 
 	cmd.PersistentFlags().BoolVarP(&flagInstallOrUpgradeYes, "yes", "y", false, `Automatic yes to prompts and run install non-interactively.`)
-	cmd.PersistentFlags().StringVar(&flagInstallOrUpgradePGVersion, "pg", defPGVer, fmt.Sprintf("%s the extension for the PostgreSQL version. It detects the version by pg_config if it exists. Supported values are %s.", c.String(action), strings.Join(supportedPGVersions(), ", ")))
+	cmd.PersistentFlags().StringVar(&flagInstallOrUpgradePGVersion, "pg", defPGVer, fmt.Sprintf("%s the extension for the comma-separated PostgreSQL versions, or %s for all installed versions. It detects the version by pg_config if it exists. Supported values are %s.", c.String(action), pgVersionAllInstalled, strings.Join(supportedPGVersions(), ", ")))
 	cmd.PersistentFlags().BoolVar(&flagInstallOrUpgradeOverwrite, "overwrite", false, "Overwrite the existing extension if it is installed outside of pgxman.")
+	cmd.PersistentFlags().BoolVar(&flagInstallOrUpgradeDryRun, "dry-run", false, "Print the packages and package sources that would be changed without changing the system.")
+	cmd.PersistentFlags().StringVarP(&flagInstallOrUpgradeOutput, "output", "o", outputText, fmt.Sprintf("Output format of --dry-run. Supported values are %s.", strings.Join(supportedOutputs, ", ")))
+	if upgrade {
+		cmd.PersistentFlags().StringSliceVar(&flagInstallOrUpgradeDatabases, "database", nil, "Run ALTER EXTENSION UPDATE in the databases after upgrading. It can be specified multiple times.")
+		cmd.PersistentFlags().BoolVar(&flagInstallOrUpgradeAllDBs, "all-databases", false, "Run ALTER EXTENSION UPDATE in all databases where the extensions are created after upgrading.")
+	} else {
+		cmd.PersistentFlags().StringSliceVar(&flagInstallOrUpgradeDatabases, "database", nil, "Run CREATE EXTENSION IF NOT EXISTS in the databases after installing. It can be specified multiple times.")
+		cmd.PersistentFlags().BoolVar(&flagInstallOrUpgradeAllDBs, "all-databases", false, "Run CREATE EXTENSION IF NOT EXISTS in all databases after installing.")
+	}
+	cmd.PersistentFlags().StringVar(&flagInstallOrUpgradeConnStr, "connection-string", "", "The libpq connection string or URI of the PostgreSQL server for --database and --all-databases. The local PostgreSQL server is used by default.")
+	cmd.PersistentFlags().StringVar(&flagInstallOrUpgradePGConfig, "pg-config", "", fmt.Sprintf("%s local tarballs into the directories reported by the pg_config. The PostgreSQL version is detected from the pg_config.", c.String(action)))
+	cmd.MarkFlagsMutuallyExclusive("database", "all-databases")
+	cmd.MarkFlagsMutuallyExclusive("pg", "pg-config")
 
 	return cmd
 }
 
 func runInstallOrUpgrade(upgrade bool) func(c *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		i, err := plugin.GetInstaller()
-		if err != nil {
-			return errorsx.Pretty(err)
-		}
-
 		if len(args) == 0 {
 			return fmt.Errorf("need at least one extension")
 		}
 
-		pgVer := parsePGVersion(flagInstallOrUpgradePGVersion)
-		if err := checkPGVerExists(cmd.Context(), pgVer); err != nil {
+		if err := validateOutput(flagInstallOrUpgradeOutput); err != nil {
 			return err
 		}
 
-		client, err := newReigstryClient()
-		if err != nil {
-			return err
+		var (
+			i      pgxman.Installer
+			pgVers []pgxman.PGVersion
+			exts   []pgxman.InstallExtension
+			err    error
+		)
+		if flagInstallOrUpgradePGConfig != "" {
+			i, pgVers, exts, err = parseTarballArgs(cmd.Context(), flagInstallOrUpgradePGConfig, args, flagInstallOrUpgradeOverwrite)
+			if err != nil {
+				return err
+			}
+		} else {
+			i, err = plugin.GetInstaller(cmd.Context())
+			if err != nil {
+				return errorsx.Pretty(err)
+			}
+
+			pgVers, err = parsePGVersions(cmd.Context(), flagInstallOrUpgradePGVersion)
+			if err != nil {
+				return err
+			}
+
+			if flagInstallOrUpgradeConnStr != "" && len(pgVers) > 1 {
+				return fmt.Errorf("--connection-string can't be used with multiple PostgreSQL versions")
+			}
+
+			client, err := newReigstryClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			p := NewArgsParser(
+				client,
+				DefaultPlatformDetector,
+				pgVers,
+				flagInstallOrUpgradeOverwrite,
+			)
+			exts, err = p.Parse(cmd.Context(), args)
+			if err != nil {
+				return err
+			}
 		}
 
-		p := NewArgsParser(
-			client,
-			DefaultPlatformDetector,
-			pgVer,
-			flagInstallOrUpgradeOverwrite,
-		)
-		exts, err := p.Parse(cmd.Context(), args)
-		if err != nil {
-			return err
+		if flagInstallOrUpgradeDryRun {
+			return printInstallPlan(cmd.Context(), i, exts, upgrade, flagInstallOrUpgradeOutput)
 		}
 
 		if !flagInstallOrUpgradeYes {
@@ -163,33 +235,38 @@ func runInstallOrUpgrade(upgrade bool) func(c *cobra.Command, args []string) err
 		}
 
 		logger := log.NewTextLogger()
-		fmt.Printf("%s extensions for PostgreSQL %s...\n", action, pgVer)
-		for _, ext := range exts {
-			if err := installOrUpgrade(cmd.Context(), i, ext, upgrade); err != nil {
-				logger.Debug("failed to install extension", "error", err, "extension", ext)
-				os.Exit(1)
-			}
+		fmt.Printf("%s extensions for PostgreSQL %s...\n", action, joinPGVersions(pgVers))
+		if err := installOrUpgrade(cmd.Context(), i, exts, upgrade); err != nil {
+			logger.Debug("failed to install extensions", "error", err, "extensions", exts)
+			return cmdutil.SilentError
 		}
 
-		if upgrade {
-			fmt.Println(`After restarting PostgreSQL, update extensions in each database by running in the psql shell:
+		if err := configurePreloadLibraries(exts); err != nil {
+			logger.Debug("failed to configure shared preload libraries", "error", err, "extensions", exts)
+			return cmdutil.SilentError
+		}
 
-    ALTER EXTENSION name UPDATE`)
+		opts := activateOptions{
+			Databases:    flagInstallOrUpgradeDatabases,
+			AllDatabases: flagInstallOrUpgradeAllDBs,
+			ConnString:   flagInstallOrUpgradeConnStr,
+			Create:       !upgrade,
 		}
+		if !opts.Enabled() {
+			if upgrade {
+				fmt.Println(`After restarting PostgreSQL, update extensions in each database by running in the psql shell:
 
-		return nil
-	}
-}
+    ALTER EXTENSION name UPDATE`)
+			}
 
-func parsePGVersion(ver string) pgxman.PGVersion {
-	if ver == "" {
-		return pgxman.PGVersionUnknown
-	}
+			return nil
+		}
 
-	v := pgxman.PGVersion(ver)
-	if v.Validate() != nil {
-		return pgxman.PGVersionUnknown
-	}
+		if err := activateExtensions(cmd.Context(), i, exts, opts); err != nil {
+			logger.Debug("failed to activate extensions", "error", err, "extensions", exts)
+			return cmdutil.SilentError
+		}
 
-	return v
+		return nil
+	}
 }
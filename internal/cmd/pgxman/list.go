@@ -0,0 +1,168 @@
+package pgxman
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/errorsx"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/internal/pg"
+	"github.com/pgxman/pgxman/internal/plugin"
+	"github.com/pgxman/pgxman/internal/registry"
+	"github.com/pgxman/pgxman/internal/tui/tableprinter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagListPGVersion string
+	flagListOutput    string
+	flagListPGConfig  string
+)
+
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List installed PostgreSQL extensions",
+		Long: `List PostgreSQL extensions that are installed by pgxman for all PostgreSQL versions.
+Extensions are listed by their names in the registry so that they can be passed to install and uninstall.
+Held extensions are pinned to the installed version and are only changed by pgxman.`,
+		Example: fmt.Sprintf(`  # List extensions installed for all PostgreSQL versions
+  pgxman list
+
+  # List extensions installed for PostgreSQL %[1]s
+  pgxman list --pg %[1]s
+
+  # List extension tarballs installed in the PostgreSQL installation of a pg_config
+  pgxman list --pg-config /opt/pgsql/bin/pg_config
+
+  # List extensions in JSON
+  pgxman list --output json`, pgxman.DefaultPGVersion),
+		RunE: runList,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.PersistentFlags().StringVar(&flagListPGVersion, "pg", "", fmt.Sprintf("Only list extensions for the PostgreSQL version. Supported values are %s.", strings.Join(supportedPGVersions(), ", ")))
+	cmd.PersistentFlags().StringVarP(&flagListOutput, "output", "o", outputText, fmt.Sprintf("Output format. Supported values are %s.", strings.Join(supportedOutputs, ", ")))
+	cmd.PersistentFlags().StringVar(&flagListPGConfig, "pg-config", "", "List extension tarballs installed in the PostgreSQL installation of the pg_config.")
+	cmd.MarkFlagsMutuallyExclusive("pg", "pg-config")
+
+	return cmd
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	if err := validateOutput(flagListOutput); err != nil {
+		return err
+	}
+
+	if flagListPGVersion != "" {
+		if err := pgxman.PGVersion(flagListPGVersion).Validate(); err != nil {
+			return err
+		}
+	}
+
+	var (
+		i   pgxman.Installer
+		err error
+	)
+	if flagListPGConfig != "" {
+		i, _, err = newTarballInstaller(cmd.Context(), flagListPGConfig)
+		if err != nil {
+			return err
+		}
+	} else {
+		i, err = plugin.GetInstaller(cmd.Context())
+		if err != nil {
+			return errorsx.Pretty(err)
+		}
+	}
+
+	installed, err := i.List(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	exts := []pgxman.InstalledExtension{}
+	for _, ext := range installed {
+		if flagListPGVersion != "" && ext.PGVersion != pgxman.PGVersion(flagListPGVersion) {
+			continue
+		}
+
+		exts = append(exts, ext)
+	}
+
+	client, err := newReigstryClient(cmd)
+	if err != nil {
+		return err
+	}
+	exts = resolveRegistryNames(cmd.Context(), client, exts, log.NewTextLogger())
+
+	sort.Slice(exts, func(i, j int) bool {
+		if exts[i].PGVersion != exts[j].PGVersion {
+			return exts[i].PGVersion < exts[j].PGVersion
+		}
+
+		return exts[i].Name < exts[j].Name
+	})
+
+	if flagListOutput == outputJSON {
+		return printJSON(exts)
+	}
+
+	if len(exts) == 0 {
+		fmt.Println("No extensions installed.")
+		return nil
+	}
+
+	tp := tableprinter.New(term.FromEnv())
+	tp.SetHeader("Name", "Version", "PG", "Arch", "Held")
+
+	var rows [][]string
+	for _, ext := range exts {
+		held := "no"
+		if ext.Held {
+			held = "yes"
+		}
+
+		rows = append(rows, []string{ext.Name, ext.Version, string(ext.PGVersion), ext.Arch, held})
+	}
+	tp.AppendBluk(rows)
+
+	return tp.Render()
+}
+
+// resolveRegistryNames replaces the names of the installed extensions with their names in the registry
+// so that they can be passed to install and uninstall. Packages normalize the names, e.g. pg-ivm for pg_ivm.
+// Only the names that are ambiguous are looked up, and a name is kept if it can't be resolved.
+func resolveRegistryNames(ctx context.Context, c registry.Client, exts []pgxman.InstalledExtension, logger *log.Logger) []pgxman.InstalledExtension {
+	resolved := make(map[string]string)
+	for idx, ext := range exts {
+		if len(pg.ExtensionNames(ext.Name)) == 1 {
+			continue
+		}
+
+		name, ok := resolved[ext.Name]
+		if !ok {
+			regExt, err := getInstalledExtension(ctx, c, ext.Name)
+			if err != nil {
+				// the registry is unreachable, don't wait for it for every extension
+				logger.Debug("Failed to resolve extension names in the registry", "name", ext.Name, "error", err)
+				return exts
+			}
+
+			name = ext.Name
+			if regExt != nil {
+				name = regExt.Name
+			}
+			resolved[ext.Name] = name
+		}
+
+		exts[idx].Name = name
+	}
+
+	return exts
+}
@@ -0,0 +1,80 @@
+package pgxman
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/oapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_resolveRegistryNames(t *testing.T) {
+	stubbedClient := StubbedRegistryClient{
+		ExtGetExtension: &oapi.Extension{
+			Name: "pg_ivm",
+		},
+		OtherExtensions: []*oapi.Extension{
+			{
+				Name: "pg-hint-plan",
+			},
+		},
+	}
+
+	cases := []struct {
+		Name     string
+		Ext      pgxman.InstalledExtension
+		WantName string
+	}{
+		{
+			Name: "name with underscores",
+			Ext: pgxman.InstalledExtension{
+				Name:      "pg-ivm",
+				PGVersion: pgxman.PGVersion16,
+				Package:   "postgresql-16-pgxman-pg-ivm",
+			},
+			WantName: "pg_ivm",
+		},
+		{
+			Name: "name with dashes",
+			Ext: pgxman.InstalledExtension{
+				Name:      "pg-hint-plan",
+				PGVersion: pgxman.PGVersion16,
+				Package:   "postgresql-16-pgxman-pg-hint-plan",
+			},
+			WantName: "pg-hint-plan",
+		},
+		{
+			Name: "unambiguous name",
+			Ext: pgxman.InstalledExtension{
+				Name:      "pgvector",
+				PGVersion: pgxman.PGVersion16,
+				Package:   "postgresql-16-pgxman-pgvector",
+			},
+			WantName: "pgvector",
+		},
+		{
+			Name: "not in the registry",
+			Ext: pgxman.InstalledExtension{
+				Name:      "pg-private",
+				PGVersion: pgxman.PGVersion16,
+				Package:   "postgresql-16-pgxman-pg-private",
+			},
+			WantName: "pg-private",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			exts := resolveRegistryNames(context.TODO(), stubbedClient, []pgxman.InstalledExtension{c.Ext}, log.NewTextLogger())
+			assert.Len(exts, 1)
+			assert.Equal(c.WantName, exts[0].Name)
+			// the package is kept to tell which package is installed
+			assert.Equal(c.Ext.Package, exts[0].Package)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package pgxman
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/internal/registry"
+)
+
+func NewPackLocker(c registry.Client, d PlatformDetector, update bool, logger *log.Logger) *PackLocker {
+	return &PackLocker{
+		Locker:           NewExtensionLocker(c, d, logger),
+		PlatformDetector: d,
+		Update:           update,
+		Logger:           logger,
+	}
+}
+
+// PackLocker resolves the extensions of a pack with a lock file.
+// Extensions that are in the lock file are not resolved again unless Update is set.
+type PackLocker struct {
+	Locker           *ExtensionLocker
+	PlatformDetector PlatformDetector
+	Logger           *log.Logger
+	Update           bool
+}
+
+// Lock resolves the extensions and returns them together with the updated lock.
+// Locked extensions of other platforms are kept so that a lock file can be shared by hosts of different platforms.
+// The lock keeps the names as requested, including the registry prefix.
+func (l *PackLocker) Lock(ctx context.Context, lock *pgxman.PackLock, exts []pgxman.InstallExtension) ([]pgxman.InstallExtension, *pgxman.PackLock, error) {
+	p, err := l.PlatformDetector()
+	if err != nil {
+		return nil, nil, fmt.Errorf("detect platform: %s", err)
+	}
+
+	if lock == nil || l.Update {
+		lock = &pgxman.PackLock{}
+	}
+
+	newLock := &pgxman.PackLock{
+		APIVersion: pgxman.DefaultPackLockAPIVersion,
+	}
+
+	// inputs are the requested extensions and dependencies that are resolved,
+	// locked entries of other platforms are kept if they are resolved from any of them
+	var inputs []pgxman.InstallExtension
+	result, err := resolveDependencies(ctx, exts, func(ctx context.Context, ext pgxman.InstallExtension) (pgxman.InstallExtension, error) {
+		if ext.Name == "" {
+			return ext, nil
+		}
+		inputs = append(inputs, ext)
+
+		resolved, ok := l.find(newLock, ext, p)
+		if !ok {
+			if locked, found := lock.Find(ext, p); found {
+				l.Logger.Debug("Using locked extension", "name", ext.Name, "version", locked.Version)
+				resolved = locked.InstallExtension(ext.PackExtension)
+			} else {
+				var err error
+				resolved, err = l.Locker.lock(ctx, ext, p)
+				if err != nil {
+					return resolved, err
+				}
+			}
+
+			newLock.Extensions = append(newLock.Extensions, pgxman.NewLockedExtension(resolved, ext.Version, p))
+		}
+
+		return resolved, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, locked := range lock.Extensions {
+		if locked.Platform == p {
+			continue
+		}
+
+		for _, ext := range inputs {
+			if locked.Matches(ext, locked.Platform) {
+				newLock.Extensions = append(newLock.Extensions, locked)
+				break
+			}
+		}
+	}
+
+	return trimRegistryNames(result), newLock, nil
+}
+
+// find returns the extension that is already resolved into the new lock, e.g. a dependency shared by extensions.
+func (l *PackLocker) find(lock *pgxman.PackLock, ext pgxman.InstallExtension, p pgxman.Platform) (pgxman.InstallExtension, bool) {
+	locked, ok := lock.Find(ext, p)
+	if !ok {
+		return ext, false
+	}
+
+	return locked.InstallExtension(ext.PackExtension), true
+}
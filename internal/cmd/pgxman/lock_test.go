@@ -0,0 +1,269 @@
+package pgxman
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/oapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PackLocker(t *testing.T) {
+	assert := assert.New(t)
+
+	stubbedClient := StubbedRegistryClient{
+		ExtGetExtension: &oapi.Extension{
+			Name: "pgvector",
+			Packages: oapi.Packages{
+				string(pgxman.PGVersion16): {
+					Version: "0.5.1",
+					Platforms: []oapi.Platform{
+						{
+							Os: oapi.DebianBookworm,
+						},
+					},
+				},
+			},
+		},
+		ExtGetVersion: &oapi.Extension{
+			Name: "pgvector",
+		},
+	}
+	stubbedPlatformDetector := func() (pgxman.Platform, error) {
+		return pgxman.PlatformDebianBookworm, nil
+	}
+
+	exts := []pgxman.InstallExtension{
+		{
+			PackExtension: pgxman.PackExtension{
+				Name: "pgvector",
+			},
+			PGVersion: pgxman.PGVersion16,
+		},
+		{
+			PackExtension: pgxman.PackExtension{
+				Path: "/PATH_TO/postgresql-16-pgxman-pg-ivm_1.7.0_amd64.deb",
+			},
+			PGVersion: pgxman.PGVersion16,
+		},
+	}
+
+	lockedPgvector := pgxman.LockedExtension{
+		Name:       "pgvector",
+		Version:    "0.5.0",
+		Constraint: "latest",
+		PGVersion:  pgxman.PGVersion16,
+		Platform:   pgxman.PlatformDebianBookworm,
+	}
+	lockedOtherPlatform := pgxman.LockedExtension{
+		Name:       "pgvector",
+		Version:    "0.4.4",
+		Constraint: "latest",
+		PGVersion:  pgxman.PGVersion16,
+		Platform:   pgxman.PlatformUbuntuJammy,
+	}
+	lockedRemoved := pgxman.LockedExtension{
+		Name:       "postgis",
+		Version:    "3.4.0",
+		Constraint: "latest",
+		PGVersion:  pgxman.PGVersion16,
+		Platform:   pgxman.PlatformUbuntuJammy,
+	}
+
+	cases := []struct {
+		Name     string
+		Lock     *pgxman.PackLock
+		Update   bool
+		WantExts []pgxman.InstallExtension
+		WantLock *pgxman.PackLock
+	}{
+		{
+			Name: "no lock file",
+			WantExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "0.5.1",
+					},
+					PGVersion: pgxman.PGVersion16,
+				},
+				exts[1],
+			},
+			WantLock: &pgxman.PackLock{
+				APIVersion: pgxman.DefaultPackLockAPIVersion,
+				Extensions: []pgxman.LockedExtension{
+					{
+						Name:       "pgvector",
+						Version:    "0.5.1",
+						Constraint: "latest",
+						PGVersion:  pgxman.PGVersion16,
+						Platform:   pgxman.PlatformDebianBookworm,
+					},
+				},
+			},
+		},
+		{
+			Name: "locked",
+			Lock: &pgxman.PackLock{
+				APIVersion: pgxman.DefaultPackLockAPIVersion,
+				Extensions: []pgxman.LockedExtension{lockedPgvector, lockedOtherPlatform, lockedRemoved},
+			},
+			WantExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "0.5.0",
+					},
+					PGVersion: pgxman.PGVersion16,
+				},
+				exts[1],
+			},
+			WantLock: &pgxman.PackLock{
+				APIVersion: pgxman.DefaultPackLockAPIVersion,
+				Extensions: []pgxman.LockedExtension{lockedPgvector, lockedOtherPlatform},
+			},
+		},
+		{
+			Name: "update",
+			Lock: &pgxman.PackLock{
+				APIVersion: pgxman.DefaultPackLockAPIVersion,
+				Extensions: []pgxman.LockedExtension{lockedPgvector, lockedOtherPlatform},
+			},
+			Update: true,
+			WantExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "0.5.1",
+					},
+					PGVersion: pgxman.PGVersion16,
+				},
+				exts[1],
+			},
+			WantLock: &pgxman.PackLock{
+				APIVersion: pgxman.DefaultPackLockAPIVersion,
+				Extensions: []pgxman.LockedExtension{
+					{
+						Name:       "pgvector",
+						Version:    "0.5.1",
+						Constraint: "latest",
+						PGVersion:  pgxman.PGVersion16,
+						Platform:   pgxman.PlatformDebianBookworm,
+					},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			locker := NewPackLocker(stubbedClient, stubbedPlatformDetector, c.Update, log.NewTextLogger())
+			gotExts, gotLock, err := locker.Lock(context.TODO(), c.Lock, exts)
+			assert.NoError(err)
+			assert.Equal(c.WantExts, gotExts)
+			assert.Equal(c.WantLock, gotLock)
+		})
+	}
+}
+
+func Test_PackLocker_Dependencies(t *testing.T) {
+	assert := assert.New(t)
+
+	stubbedClient := StubbedRegistryClient{
+		ExtGetExtension: &oapi.Extension{
+			Name: "pgvectorscale",
+			Packages: oapi.Packages{
+				string(pgxman.PGVersion16): {
+					Version: "0.2.0",
+					Platforms: []oapi.Platform{
+						{
+							Os:              oapi.DebianBookworm,
+							RunDependencies: []string{"pgxman/pgvector"},
+						},
+					},
+				},
+			},
+		},
+		OtherExtensions: []*oapi.Extension{
+			{
+				Name: "pgvector",
+				Packages: oapi.Packages{
+					string(pgxman.PGVersion16): {
+						Version: "0.5.1",
+						Platforms: []oapi.Platform{
+							{
+								Os: oapi.DebianBookworm,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	stubbedPlatformDetector := func() (pgxman.Platform, error) {
+		return pgxman.PlatformDebianBookworm, nil
+	}
+
+	exts := []pgxman.InstallExtension{
+		{
+			PackExtension: pgxman.PackExtension{
+				Name: "pgvectorscale",
+			},
+			PGVersion: pgxman.PGVersion16,
+		},
+	}
+	wantExts := []pgxman.InstallExtension{
+		{
+			PackExtension: pgxman.PackExtension{
+				Name:    "pgvector",
+				Version: "0.5.1",
+			},
+			PGVersion:  pgxman.PGVersion16,
+			RequiredBy: "pgvectorscale",
+		},
+		{
+			PackExtension: pgxman.PackExtension{
+				Name:    "pgvectorscale",
+				Version: "0.2.0",
+			},
+			PGVersion:    pgxman.PGVersion16,
+			Dependencies: []string{"pgxman/pgvector"},
+		},
+	}
+	wantLock := &pgxman.PackLock{
+		APIVersion: pgxman.DefaultPackLockAPIVersion,
+		Extensions: []pgxman.LockedExtension{
+			{
+				Name:         "pgvectorscale",
+				Version:      "0.2.0",
+				Constraint:   "latest",
+				PGVersion:    pgxman.PGVersion16,
+				Platform:     pgxman.PlatformDebianBookworm,
+				Dependencies: []string{"pgxman/pgvector"},
+			},
+			{
+				Name:       "pgvector",
+				Version:    "0.5.1",
+				Constraint: "latest",
+				PGVersion:  pgxman.PGVersion16,
+				Platform:   pgxman.PlatformDebianBookworm,
+			},
+		},
+	}
+
+	locker := NewPackLocker(stubbedClient, stubbedPlatformDetector, false, log.NewTextLogger())
+	gotExts, gotLock, err := locker.Lock(context.TODO(), nil, exts)
+	assert.NoError(err)
+	assert.Equal(wantExts, gotExts)
+	assert.Equal(wantLock, gotLock)
+
+	// dependencies are resolved from the lock file without the registry
+	locker = NewPackLocker(StubbedRegistryClient{ExtGetExtension: &oapi.Extension{}}, stubbedPlatformDetector, false, log.NewTextLogger())
+	gotExts, gotLock, err = locker.Lock(context.TODO(), gotLock, exts)
+	assert.NoError(err)
+	assert.Equal(wantExts, gotExts)
+	assert.Equal(wantLock, gotLock)
+}
@@ -0,0 +1,315 @@
+package pgxman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/cmd/cmdutil"
+	"github.com/pgxman/pgxman/internal/errorsx"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/internal/pg"
+	"github.com/pgxman/pgxman/internal/plugin"
+	"github.com/pgxman/pgxman/internal/registry"
+	"github.com/pgxman/pgxman/internal/tui/tableprinter"
+	"github.com/pgxman/pgxman/oapi"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagOutdatedPGVersion string
+	flagOutdatedOutput    string
+)
+
+func newOutdatedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "outdated",
+		Short: "List installed PostgreSQL extensions that have newer versions",
+		Long: `List PostgreSQL extensions installed by pgxman that have newer versions in the registry
+for the PostgreSQL version and the platform. It exits with status 2 if any extension can be upgraded
+and with status 1 if it fails so that it can be used in scripts and monitoring.`,
+		Example: fmt.Sprintf(`  # List outdated extensions for all PostgreSQL versions
+  pgxman outdated
+
+  # List outdated extensions for PostgreSQL %[1]s
+  pgxman outdated --pg %[1]s
+
+  # List outdated extensions in JSON
+  pgxman outdated --output json`, pgxman.DefaultPGVersion),
+		RunE: runOutdated,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.PersistentFlags().StringVar(&flagOutdatedPGVersion, "pg", "", fmt.Sprintf("Only check extensions for the PostgreSQL version. Supported values are %s.", strings.Join(supportedPGVersions(), ", ")))
+	cmd.PersistentFlags().StringVarP(&flagOutdatedOutput, "output", "o", outputText, fmt.Sprintf("Output format. Supported values are %s.", strings.Join(supportedOutputs, ", ")))
+
+	return cmd
+}
+
+func runOutdated(cmd *cobra.Command, args []string) error {
+	if err := validateOutput(flagOutdatedOutput); err != nil {
+		return err
+	}
+
+	if flagOutdatedPGVersion != "" {
+		if err := pgxman.PGVersion(flagOutdatedPGVersion).Validate(); err != nil {
+			return err
+		}
+	}
+
+	i, err := plugin.GetInstaller(cmd.Context())
+	if err != nil {
+		return errorsx.Pretty(err)
+	}
+
+	installed, err := i.List(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	var exts []pgxman.InstalledExtension
+	for _, ext := range installed {
+		if flagOutdatedPGVersion != "" && ext.PGVersion != pgxman.PGVersion(flagOutdatedPGVersion) {
+			continue
+		}
+
+		exts = append(exts, ext)
+	}
+
+	client, err := newReigstryClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	checker := NewOutdatedChecker(client, DefaultPlatformDetector, log.NewTextLogger())
+	outdated, err := checker.Check(cmd.Context(), exts)
+	if err != nil {
+		return err
+	}
+
+	if flagOutdatedOutput == outputJSON {
+		if err := printJSON(outdated); err != nil {
+			return err
+		}
+	} else if len(outdated) == 0 {
+		fmt.Println("All extensions are up to date.")
+	} else {
+		tp := tableprinter.New(term.FromEnv())
+		tp.SetHeader("Name", "PG", "Current", "Latest")
+
+		var rows [][]string
+		for _, ext := range outdated {
+			rows = append(rows, []string{ext.Name, string(ext.PGVersion), ext.Current, ext.Latest})
+		}
+		tp.AppendBluk(rows)
+
+		if err := tp.Render(); err != nil {
+			return err
+		}
+	}
+
+	if len(outdated) > 0 {
+		return cmdutil.OutdatedError
+	}
+
+	return nil
+}
+
+type OutdatedExtension struct {
+	Name      string           `json:"name"`
+	PGVersion pgxman.PGVersion `json:"pgVersion"`
+	Current   string           `json:"current"`
+	Latest    string           `json:"latest"`
+}
+
+func NewOutdatedChecker(c registry.Client, d PlatformDetector, logger *log.Logger) *OutdatedChecker {
+	return &OutdatedChecker{
+		Client:           c,
+		PlatformDetector: d,
+		Logger:           logger,
+	}
+}
+
+// OutdatedChecker finds installed extensions that have newer versions in the registry.
+type OutdatedChecker struct {
+	Client           registry.Client
+	PlatformDetector PlatformDetector
+	Logger           *log.Logger
+}
+
+// Check returns the installed extensions whose latest versions for their PostgreSQL versions are newer.
+// Extensions that are not in the registry or whose latest versions are not available for the platform are skipped.
+func (c *OutdatedChecker) Check(ctx context.Context, installed []pgxman.InstalledExtension) ([]OutdatedExtension, error) {
+	p, err := c.PlatformDetector()
+	if err != nil {
+		return nil, fmt.Errorf("detect platform: %s", err)
+	}
+
+	var (
+		result []OutdatedExtension
+		cache  = make(map[string]*oapi.Extension)
+	)
+	for _, ext := range installed {
+		regExt, ok := cache[ext.Name]
+		if !ok {
+			regExt, err = getInstalledExtension(ctx, c.Client, ext.Name)
+			if err != nil {
+				return nil, err
+			}
+			cache[ext.Name] = regExt
+		}
+
+		logger := c.Logger.With("name", ext.Name, "pg", ext.PGVersion)
+		if regExt == nil {
+			logger.Debug("Skipping extension that is not in the registry")
+			continue
+		}
+
+		pkg, ok := regExt.Packages[string(ext.PGVersion)]
+		if !ok {
+			logger.Debug("Skipping extension that is not available for the PostgreSQL version")
+			continue
+		}
+
+		if _, err := getPlatform(pkg, p); err != nil {
+			logger.Debug("Skipping extension that is not available for the platform", "version", pkg.Version, "platform", p)
+			continue
+		}
+
+		if !isNewerVersion(pkg.Version, ext.Version) {
+			continue
+		}
+
+		result = append(result, OutdatedExtension{
+			Name:      ext.Name,
+			PGVersion: ext.PGVersion,
+			Current:   ext.Version,
+			Latest:    pkg.Version,
+		})
+	}
+
+	return result, nil
+}
+
+// getInstalledExtension returns the extension of an installed extension from the registry or nil if it's not found.
+// Installed extensions are named after their packages, so the name with underscores is also tried.
+func getInstalledExtension(ctx context.Context, c registry.Client, name string) (*oapi.Extension, error) {
+	for _, n := range pg.ExtensionNames(name) {
+		ext, err := c.GetExtension(ctx, n)
+		if err != nil {
+			if errors.Is(err, registry.ErrExtensionNotFound) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		return ext, nil
+	}
+
+	return nil, nil
+}
+
+// isNewerVersion reports whether latest is newer than current.
+// The installed versions are Debian package versions, so the versions are compared in Debian version order.
+func isNewerVersion(latest, current string) bool {
+	return compareDebianVersions(latest, current) > 0
+}
+
+// compareDebianVersions compares two Debian versions, [epoch:]upstream[-revision], as dpkg does.
+// It returns a negative number if a is older than b, a positive number if a is newer than b, and 0 if they are equal.
+func compareDebianVersions(a, b string) int {
+	splitVersion := func(v string) (int, string, string) {
+		var epoch int
+		if e, rest, ok := strings.Cut(v, ":"); ok {
+			epoch, _ = strconv.Atoi(e)
+			v = rest
+		}
+
+		upstream, revision := v, ""
+		if i := strings.LastIndex(v, "-"); i >= 0 {
+			upstream, revision = v[:i], v[i+1:]
+		}
+
+		return epoch, upstream, revision
+	}
+
+	aEpoch, aUpstream, aRevision := splitVersion(a)
+	bEpoch, bUpstream, bRevision := splitVersion(b)
+	if aEpoch != bEpoch {
+		return aEpoch - bEpoch
+	}
+	if c := compareDebianVersionPart(aUpstream, bUpstream); c != 0 {
+		return c
+	}
+
+	return compareDebianVersionPart(aRevision, bRevision)
+}
+
+// compareDebianVersionPart compares the upstream versions or the revisions of two Debian versions.
+// Non-digit parts are compared with letters sorting before other characters and ~ sorting before anything,
+// and digit parts are compared numerically.
+func compareDebianVersionPart(a, b string) int {
+	isDigit := func(c byte) bool {
+		return c >= '0' && c <= '9'
+	}
+	order := func(s string, i int) int {
+		if i >= len(s) {
+			return 0
+		}
+
+		c := s[i]
+		switch {
+		case isDigit(c):
+			return 0
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			return int(c)
+		case c == '~':
+			return -1
+		default:
+			return int(c) + 256
+		}
+	}
+
+	var i, j int
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			if ac, bc := order(a, i), order(b, j); ac != bc {
+				return ac - bc
+			}
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+
+		var firstDiff int
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+
+	return 0
+}
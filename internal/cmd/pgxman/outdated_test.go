@@ -0,0 +1,93 @@
+package pgxman
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/oapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OutdatedChecker(t *testing.T) {
+	assert := assert.New(t)
+
+	stubbedClient := StubbedRegistryClient{
+		ExtGetExtension: &oapi.Extension{
+			Name: "pg_ivm",
+			Packages: oapi.Packages{
+				string(pgxman.PGVersion15): {
+					Version: "1.8.0",
+					Platforms: []oapi.Platform{
+						{
+							Os: oapi.DebianBookworm,
+						},
+					},
+				},
+				string(pgxman.PGVersion16): {
+					Version: "1.8.0",
+					Platforms: []oapi.Platform{
+						{
+							Os: oapi.UbuntuJammy,
+						},
+					},
+				},
+			},
+		},
+	}
+	stubbedPlatformDetector := func() (pgxman.Platform, error) {
+		return pgxman.PlatformDebianBookworm, nil
+	}
+
+	installed := []pgxman.InstalledExtension{
+		{
+			Name:      "pg-ivm",
+			Version:   "1.7.0",
+			PGVersion: pgxman.PGVersion15,
+		},
+		{
+			Name:      "pg-ivm",
+			Version:   "1.7.0",
+			PGVersion: pgxman.PGVersion16,
+		},
+		{
+			Name:      "pg-ivm",
+			Version:   "1.7.0",
+			PGVersion: pgxman.PGVersion14,
+		},
+		{
+			Name:      "local-ext",
+			Version:   "1.0.0",
+			PGVersion: pgxman.PGVersion15,
+		},
+	}
+
+	checker := NewOutdatedChecker(stubbedClient, stubbedPlatformDetector, log.NewTextLogger())
+	got, err := checker.Check(context.TODO(), installed)
+	assert.NoError(err)
+	assert.Equal([]OutdatedExtension{
+		{
+			Name:      "pg-ivm",
+			PGVersion: pgxman.PGVersion15,
+			Current:   "1.7.0",
+			Latest:    "1.8.0",
+		},
+	}, got)
+}
+
+func Test_isNewerVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isNewerVersion("0.5.1", "0.5.0"))
+	assert.False(isNewerVersion("0.5.0", "0.5.0"))
+	assert.False(isNewerVersion("0.4.4", "0.5.0"))
+	assert.True(isNewerVersion("2023.1", "2022.4-rc"))
+	assert.True(isNewerVersion("0.10.0", "0.9.0"))
+	assert.False(isNewerVersion("0.5.1", "0.5.1-1"))
+	assert.False(isNewerVersion("0.5.1", "1:0.4.0"))
+	assert.False(isNewerVersion("0.5.1~rc1", "0.5.1"))
+	assert.True(isNewerVersion("0.5.1-2", "0.5.1-1"))
+	assert.True(isNewerVersion("0.5.1+b1", "0.5.1"))
+	assert.False(isNewerVersion("1.0", "1.0.0"))
+}
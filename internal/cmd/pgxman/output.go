@@ -0,0 +1,33 @@
+package pgxman
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var (
+	supportedOutputs = []string{outputText, outputJSON}
+)
+
+func validateOutput(output string) error {
+	if slices.Contains(supportedOutputs, output) {
+		return nil
+	}
+
+	return fmt.Errorf("unsupported output format %q, supported values are %s", output, strings.Join(supportedOutputs, ", "))
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
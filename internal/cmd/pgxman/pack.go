@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
+	"github.com/eiannone/keyboard"
 	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/cmd/cmdutil"
 	"github.com/pgxman/pgxman/internal/errorsx"
 	"github.com/pgxman/pgxman/internal/iostreams"
 	"github.com/pgxman/pgxman/internal/log"
@@ -18,8 +22,16 @@ import (
 )
 
 var (
-	flagPackInstallYes  bool
-	flagPackInstallFile string
+	flagPackInstallYes    bool
+	flagPackInstallFile   string
+	flagPackInstallUpdate bool
+	flagPackInstallDryRun bool
+	flagPackInstallOutput string
+
+	flagPackSyncYes    bool
+	flagPackSyncFile   string
+	flagPackSyncUpdate bool
+	flagPackSyncPrune  bool
 )
 
 func newPackCmd() *cobra.Command {
@@ -29,6 +41,7 @@ func newPackCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(newPackInstallCmd())
+	cmd.AddCommand(newPackSyncCmd())
 
 	return cmd
 }
@@ -38,7 +51,10 @@ func newPackInstallCmd() *cobra.Command {
 		Use:   "install",
 		Short: "Install PostgreSQL extensions from a pack file",
 		Long: `Install PostgreSQL extensions based on a specified pack file (e.g., pgxman.yaml).
-This ensures consistency across extensions by synchronizing them with the definitions provided in the pack file.`,
+This ensures consistency across extensions by synchronizing them with the definitions provided in the pack file.
+The resolved extensions are recorded in a pgxman.lock file next to the pack file. Subsequent installs
+use the versions and apt repositories in the lock file unless --update is specified.
+Extensions that are not in the pack file are kept, run pgxman pack sync to remove them.`,
 		Example: `  # Install extensions from the pgxman.yaml file in the current directory
   pgxman pack install
 
@@ -48,6 +64,12 @@ This ensures consistency across extensions by synchronizing them with the defini
   # Specify a different location for the pgxman.yaml file
   pgxman pack install -f /PATH_TO/pgxman.yaml
 
+  # Resolve extensions again and update the pgxman.lock file
+  pgxman pack install --update
+
+  # Show what would be installed in JSON without changing the system
+  pgxman pack install --dry-run --output json
+
   # Read the pgxman.yaml file from STDIN
   cat <<EOF | pgxman pack install -f -
     apiVersion: v1
@@ -69,12 +91,19 @@ This ensures consistency across extensions by synchronizing them with the defini
 
 	cmd.PersistentFlags().StringVarP(&flagPackInstallFile, "file", "f", filepath.Join(pwd, "pgxman.yaml"), "The pack file to use.")
 	cmd.PersistentFlags().BoolVarP(&flagPackInstallYes, "yes", "y", false, `Automatic yes to prompts and run install non-interactively.`)
+	cmd.PersistentFlags().BoolVar(&flagPackInstallUpdate, "update", false, "Ignore the pgxman.lock file and resolve extensions from the registry again.")
+	cmd.PersistentFlags().BoolVar(&flagPackInstallDryRun, "dry-run", false, "Print the packages and package sources that would be changed without changing the system or the pgxman.lock file.")
+	cmd.PersistentFlags().StringVarP(&flagPackInstallOutput, "output", "o", outputText, fmt.Sprintf("Output format of --dry-run. Supported values are %s.", strings.Join(supportedOutputs, ", ")))
 
 	return cmd
 }
 
 func runPackInstall(cmd *cobra.Command, args []string) error {
-	i, err := plugin.GetInstaller()
+	if err := validateOutput(flagPackInstallOutput); err != nil {
+		return err
+	}
+
+	i, err := plugin.GetInstaller(cmd.Context())
 	if err != nil {
 		return errorsx.Pretty(err)
 	}
@@ -87,18 +116,34 @@ func runPackInstall(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	pgVer := p.Postgres.Version
-	if err := checkPGVerExists(cmd.Context(), pgVer); err != nil {
+	pgVers := p.PGVersions()
+	for _, pgVer := range pgVers {
+		if err := checkPGVerExists(cmd.Context(), pgVer); err != nil {
+			return err
+		}
+	}
+
+	client, err := newReigstryClient(cmd)
+	if err != nil {
 		return err
 	}
 
-	client, err := newReigstryClient()
+	lockFile, err := pgxman.PackLockFilePath(flagPackInstallFile)
 	if err != nil {
 		return err
 	}
 
-	locker := NewExtensionLocker(client, DefaultPlatformDetector, log.NewTextLogger())
-	exts, err := locker.Lock(cmd.Context(), p.InstallExtensions())
+	locker := NewPackLocker(client, DefaultPlatformDetector, flagPackInstallUpdate, log.NewTextLogger())
+	if flagPackInstallDryRun {
+		return dryRunPackInstall(cmd.Context(), i, locker, p, lockFile, flagPackInstallOutput)
+	}
+
+	lock, err := pgxman.ReadPackLockFile(lockFile)
+	if err != nil {
+		return err
+	}
+
+	exts, newLock, err := locker.Lock(cmd.Context(), lock, p.InstallExtensions())
 	if err != nil {
 		return err
 	}
@@ -110,47 +155,439 @@ func runPackInstall(cmd *cobra.Command, args []string) error {
 	}
 
 	logger := log.NewTextLogger()
-	fmt.Printf("Bundling extensions for PostgreSQL %s...\n", pgVer)
+	fmt.Printf("Installing extensions for PostgreSQL %s...\n", joinPGVersions(pgVers))
+	if err := installOrUpgrade(cmd.Context(), i, exts, true); err != nil {
+		logger.Debug("failed to install extensions", "error", err)
+		return cmdutil.SilentError
+	}
+
+	// the lock file is written once the extensions are installed and before they are configured, as in pack sync,
+	// so that it matches the installed extensions even if configuring them fails
+	if err := pgxman.WritePackLockFile(lockFile, *newLock); err != nil {
+		return fmt.Errorf("write lock file: %w", err)
+	}
+
+	if err := configurePreloadLibraries(exts); err != nil {
+		logger.Debug("failed to configure shared preload libraries", "error", err)
+		return cmdutil.SilentError
+	}
+
+	if err := activateExtensions(cmd.Context(), i, exts, packActivateOptions(p)); err != nil {
+		logger.Debug("failed to activate extensions", "error", err)
+		return cmdutil.SilentError
+	}
+
+	return nil
+}
+
+// dryRunPackInstall prints the changes of installing the extensions of the pack
+// without changing the system or the lock file.
+func dryRunPackInstall(ctx context.Context, i pgxman.Installer, locker *PackLocker, p *pgxman.Pack, lockFile, output string) error {
+	lock, err := pgxman.ReadPackLockFile(lockFile)
+	if err != nil {
+		return err
+	}
+
+	exts, _, err := locker.Lock(ctx, lock, p.InstallExtensions())
+	if err != nil {
+		return err
+	}
+
+	return printInstallPlan(ctx, i, exts, true, output)
+}
+
+// packActivateOptions creates or updates the extensions in the databases of the pack file.
+func packActivateOptions(p *pgxman.Pack) activateOptions {
+	return activateOptions{
+		Databases: p.Postgres.Databases,
+		Create:    true,
+	}
+}
+
+func newPackSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync PostgreSQL extensions with a pack file",
+		Long: `Sync PostgreSQL extensions with a pack file (e.g., pgxman.yaml). Extensions in the pack file
+are installed or upgraded, and extensions that are installed by pgxman for the PostgreSQL version of
+the pack file but are not in the pack file are removed unless --prune=false is specified.
+The resolved extensions are recorded in a pgxman.lock file next to the pack file.`,
+		Example: `  # Sync extensions with the pgxman.yaml file in the current directory
+  pgxman pack sync
+
+  # Suppress prompts for automatic sync
+  pgxman pack sync -y
+
+  # Install and upgrade extensions without removing those that are not in the pack file
+  pgxman pack sync --prune=false
+
+  # Specify a different location for the pgxman.yaml file
+  pgxman pack sync -f /PATH_TO/pgxman.yaml`,
+		RunE: runPackSync,
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	cmd.PersistentFlags().StringVarP(&flagPackSyncFile, "file", "f", filepath.Join(pwd, "pgxman.yaml"), "The pack file to use.")
+	cmd.PersistentFlags().BoolVarP(&flagPackSyncYes, "yes", "y", false, `Automatic yes to prompts and run sync non-interactively.`)
+	cmd.PersistentFlags().BoolVar(&flagPackSyncUpdate, "update", false, "Ignore the pgxman.lock file and resolve extensions from the registry again.")
+	cmd.PersistentFlags().BoolVar(&flagPackSyncPrune, "prune", true, "Remove extensions that are not in the pack file.")
+
+	return cmd
+}
+
+func runPackSync(cmd *cobra.Command, args []string) error {
+	i, err := plugin.GetInstaller(cmd.Context())
+	if err != nil {
+		return errorsx.Pretty(err)
+	}
+
+	p, err := pgxman.ReadPackFile(flagPackSyncFile)
+	if err != nil {
+		return err
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	pgVers := p.PGVersions()
+	for _, pgVer := range pgVers {
+		if err := checkPGVerExists(cmd.Context(), pgVer); err != nil {
+			return err
+		}
+	}
+
+	client, err := newReigstryClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	lockFile, err := pgxman.PackLockFilePath(flagPackSyncFile)
+	if err != nil {
+		return err
+	}
+
+	lock, err := pgxman.ReadPackLockFile(lockFile)
+	if err != nil {
+		return err
+	}
+
+	locker := NewPackLocker(client, DefaultPlatformDetector, flagPackSyncUpdate, log.NewTextLogger())
+	exts, newLock, err := locker.Lock(cmd.Context(), lock, p.InstallExtensions())
+	if err != nil {
+		return err
+	}
+
+	installed, err := i.List(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	plan := newPackSyncPlan(installed, exts, pgVers, flagPackSyncPrune)
+	if plan.Empty() {
+		fmt.Printf("Extensions for PostgreSQL %s are in sync with the pack file.\n", joinPGVersions(pgVers))
+		return pgxman.WritePackLockFile(lockFile, *newLock)
+	}
+
+	if !flagPackSyncYes {
+		if err := promptPackSync(iostreams.NewIOStreams(), plan); err != nil {
+			return err
+		}
+	}
+
+	removed := plan.RemoveExtensions()
+	warnExtensionsInUse(cmd.Context(), removed)
+
+	logger := log.NewTextLogger()
+	fmt.Printf("Syncing extensions for PostgreSQL %s...\n", joinPGVersions(pgVers))
+	if s, ok := i.(pgxman.Syncer); ok {
+		if err := syncExtensions(cmd.Context(), s, plan.InstallExtensions(), removed); err != nil {
+			logger.Debug("failed to sync extensions", "error", err)
+			return cmdutil.SilentError
+		}
+
+		if err := pgxman.WritePackLockFile(lockFile, *newLock); err != nil {
+			return fmt.Errorf("write lock file: %w", err)
+		}
+	} else {
+		if err := installOrUpgrade(cmd.Context(), i, plan.InstallExtensions(), true); err != nil {
+			logger.Debug("failed to install extensions", "error", err)
+			return cmdutil.SilentError
+		}
+
+		// the lock file only records the extensions of the pack,
+		// so it matches the host once they are upgraded even if a removal fails
+		if err := pgxman.WritePackLockFile(lockFile, *newLock); err != nil {
+			return fmt.Errorf("write lock file: %w", err)
+		}
+
+		if err := uninstall(cmd.Context(), i, removed); err != nil {
+			logger.Debug("failed to uninstall extensions", "error", err, "extensions", removed)
+			return cmdutil.SilentError
+		}
+	}
+
+	if err := configurePreloadLibraries(plan.InstallExtensions()); err != nil {
+		logger.Debug("failed to configure shared preload libraries", "error", err)
+		return cmdutil.SilentError
+	}
+
+	if err := activateExtensions(cmd.Context(), i, plan.InstallExtensions(), packActivateOptions(p)); err != nil {
+		logger.Debug("failed to activate extensions", "error", err)
+		return cmdutil.SilentError
+	}
+
+	return nil
+}
+
+// packSyncPlan is the difference between the installed extensions and the extensions of a pack.
+type packSyncPlan struct {
+	Install []pgxman.InstallExtension
+	Upgrade []packSyncUpgrade
+	Remove  []pgxman.InstalledExtension
+}
+
+type packSyncUpgrade struct {
+	From pgxman.InstalledExtension
+	To   pgxman.InstallExtension
+}
+
+func (p packSyncPlan) Empty() bool {
+	return len(p.Install) == 0 && len(p.Upgrade) == 0 && len(p.Remove) == 0
+}
+
+// InstallExtensions returns the extensions to install or upgrade.
+func (p packSyncPlan) InstallExtensions() []pgxman.InstallExtension {
+	exts := append([]pgxman.InstallExtension{}, p.Install...)
+	for _, u := range p.Upgrade {
+		exts = append(exts, u.To)
+	}
+
+	return exts
+}
+
+// RemoveExtensions returns the extensions to remove.
+func (p packSyncPlan) RemoveExtensions() []pgxman.InstallExtension {
+	var exts []pgxman.InstallExtension
+	for _, ext := range p.Remove {
+		exts = append(exts, pgxman.InstallExtension{
+			PackExtension: pgxman.PackExtension{
+				Name: ext.Name,
+			},
+			PGVersion: ext.PGVersion,
+		})
+	}
+
+	return exts
+}
+
+// newPackSyncPlan compares the installed extensions for the PostgreSQL versions with the extensions of a pack.
+// Extensions from a local path are always installed because their versions are unknown.
+// Installed extensions that aren't in the pack are removed if prune is set.
+func newPackSyncPlan(installed []pgxman.InstalledExtension, exts []pgxman.InstallExtension, pgVers []pgxman.PGVersion, prune bool) packSyncPlan {
+	installedByName := make(map[pgxman.PGVersion]map[string]pgxman.InstalledExtension)
+	for _, ext := range installed {
+		if !slices.Contains(pgVers, ext.PGVersion) {
+			continue
+		}
+
+		if installedByName[ext.PGVersion] == nil {
+			installedByName[ext.PGVersion] = make(map[string]pgxman.InstalledExtension)
+		}
+		installedByName[ext.PGVersion][normalizeExtensionName(ext.Name)] = ext
+	}
+
+	var (
+		plan = packSyncPlan{}
+		keep = make(map[string]struct{})
+	)
 	for _, ext := range exts {
-		if err := installOrUpgrade(cmd.Context(), i, ext, true); err != nil {
-			logger.Debug("failed to install extension", "error", err)
-			os.Exit(1)
+		if ext.Path != "" {
+			plan.Install = append(plan.Install, ext)
+			// package files are in the format of PACKAGE_VERSION_ARCH.deb
+			pkg, _, _ := strings.Cut(filepath.Base(ext.Path), "_")
+			keep[pkg] = struct{}{}
+			continue
+		}
+
+		cur, ok := installedByName[ext.PGVersion][normalizeExtensionName(ext.Name)]
+		if !ok {
+			plan.Install = append(plan.Install, ext)
+			continue
+		}
+
+		keep[cur.Package] = struct{}{}
+		if cur.Version != ext.Version {
+			plan.Upgrade = append(plan.Upgrade, packSyncUpgrade{From: cur, To: ext})
+		}
+	}
+
+	if prune {
+		for _, ext := range installed {
+			if !slices.Contains(pgVers, ext.PGVersion) {
+				continue
+			}
+
+			if _, ok := keep[ext.Package]; !ok {
+				plan.Remove = append(plan.Remove, ext)
+			}
+		}
+		sort.Slice(plan.Remove, func(i, j int) bool {
+			if plan.Remove[i].PGVersion != plan.Remove[j].PGVersion {
+				return plan.Remove[i].PGVersion < plan.Remove[j].PGVersion
+			}
+
+			return plan.Remove[i].Name < plan.Remove[j].Name
+		})
+	}
+
+	return plan
+}
+
+// normalizeExtensionName normalizes the name of an extension for comparison
+// because installed extensions are named after their packages, e.g. pg_ivm is installed as pg-ivm.
+func normalizeExtensionName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+func promptPackSync(io *iostreams.IOStreams, plan packSyncPlan) error {
+	if !io.IsTerminal() {
+		return nil
+	}
+
+	var out []string
+	if len(plan.Install) > 0 {
+		out = append(out, "The following extensions will be installed:")
+		for _, ext := range plan.Install {
+			if ext.RequiredBy != "" {
+				out = append(out, fmt.Sprintf("  %s (PostgreSQL %s, required by %s)", ext, ext.PGVersion, ext.RequiredBy))
+			} else {
+				out = append(out, fmt.Sprintf("  %s (PostgreSQL %s)", ext, ext.PGVersion))
+			}
 		}
 	}
+	if len(plan.Upgrade) > 0 {
+		out = append(out, "The following extensions will be upgraded:")
+		for _, u := range plan.Upgrade {
+			out = append(out, fmt.Sprintf("  %s %s -> %s (PostgreSQL %s)", u.To.Name, u.From.Version, u.To.Version, u.To.PGVersion))
+		}
+	}
+	if len(plan.Remove) > 0 {
+		out = append(out, "The following extensions will be removed:")
+		for _, ext := range plan.Remove {
+			out = append(out, fmt.Sprintf("  %s %s (PostgreSQL %s)", ext.Name, ext.Version, ext.PGVersion))
+		}
+	}
+
+	out = append(out, "Do you want to continue? [Y/n]")
+
+	err := io.Prompt(strings.Join(out, "\n"), []rune{'y', 'Y'}, []keyboard.Key{keyboard.KeyEnter})
+	if err != nil {
+		if errors.Is(err, iostreams.ErrAbortPrompt) {
+			return fmt.Errorf("sync aborted")
+		}
+
+		return err
+	}
 
 	return nil
 }
 
-func installOrUpgrade(ctx context.Context, i pgxman.Installer, ext pgxman.InstallExtension, upgrade bool) error {
+// installOrUpgrade installs or upgrades the extensions in a single transaction
+// and reports the result for each extension.
+func installOrUpgrade(ctx context.Context, i pgxman.Installer, exts []pgxman.InstallExtension, upgrade bool) error {
+	if len(exts) == 0 {
+		return nil
+	}
+
+	var names []string
+	for _, ext := range exts {
+		names = append(names, ext.String())
+	}
+
+	action, verb, f := "Installing", "install", i.Install
+	if upgrade {
+		action, verb, f = "Upgrading", "upgrade", i.Upgrade
+	}
+
 	s := spinner.New(flagDebug)
-	s.WithIndicator(fmt.Sprintf("Installing %s...\n", ext))
+	s.WithIndicator(fmt.Sprintf("%s %s...\n", action, strings.Join(names, ", ")))
 	defer s.Stop()
 
-	f := i.Install
-	if upgrade {
-		f = i.Upgrade
+	s.Start()
+	if err := f(ctx, exts); err != nil {
+		err = handleInstallErr(err, verb)
+
+		// the installer reports one error for the transaction, so it is printed once for all extensions
+		s.WithDone(fmt.Sprintf("[%s] %s: %s\n", errorMark, strings.Join(names, ", "), err))
+
+		return err
 	}
 
-	handleErr := func(err error) error {
-		if errors.Is(err, pgxman.ErrRootAccessRequired) {
-			return fmt.Errorf("must run command as root: sudo %s", strings.Join(os.Args, " "))
-		}
+	s.WithDone(formatResults(exts, func(ext pgxman.InstallExtension) string {
+		return fmt.Sprintf("[%s] %s: https://pgx.sh/%s\n", successMark, ext, ext.Name)
+	}))
 
-		if errors.Is(err, pgxman.ErrConflictExtension) {
-			return fmt.Errorf("has already been installed outside of pgxman, run with `--overwrite` to overwrite it")
-		}
+	return nil
+}
 
-		return fmt.Errorf("failed to install, run with `--debug` to see the full error: %w", err)
+// syncExtensions upgrades the extensions and uninstalls the removed extensions in a single transaction
+// and reports the result for each extension.
+func syncExtensions(ctx context.Context, syncer pgxman.Syncer, exts []pgxman.InstallExtension, removed []pgxman.InstallExtension) error {
+	var names []string
+	for _, ext := range exts {
+		names = append(names, ext.String())
+	}
+	for _, ext := range removed {
+		names = append(names, ext.Name)
 	}
 
+	s := spinner.New(flagDebug)
+	s.WithIndicator(fmt.Sprintf("Syncing %s...\n", strings.Join(names, ", ")))
+	defer s.Stop()
+
 	s.Start()
-	if err := f(ctx, ext); err != nil {
-		err = handleErr(err)
-		s.WithDone(fmt.Sprintf("[%s] %s: %s\n", errorMark, ext, err))
+	if err := syncer.Sync(ctx, exts, removed); err != nil {
+		err = handleInstallErr(err, "sync")
+
+		// the installer reports one error for the transaction, so it is printed once for all extensions
+		s.WithDone(fmt.Sprintf("[%s] %s: %s\n", errorMark, strings.Join(names, ", "), err))
+
 		return err
 	}
 
-	s.WithDone(fmt.Sprintf("[%s] %s: https://pgx.sh/%s\n", successMark, ext, ext.Name))
+	done := formatResults(exts, func(ext pgxman.InstallExtension) string {
+		return fmt.Sprintf("[%s] %s: https://pgx.sh/%s\n", successMark, ext, ext.Name)
+	})
+	for _, ext := range removed {
+		done += fmt.Sprintf("[%s] %s: removed\n", successMark, ext.Name)
+	}
+	s.WithDone(done)
 
 	return nil
 }
+
+// handleInstallErr converts an error of an installer into a message for the user.
+// The verb is the failed action, e.g. install.
+func handleInstallErr(err error, verb string) error {
+	if errors.Is(err, pgxman.ErrRootAccessRequired) {
+		return fmt.Errorf("must run command as root: sudo %s", strings.Join(os.Args, " "))
+	}
+
+	if errors.Is(err, pgxman.ErrConflictExtension) {
+		return fmt.Errorf("an extension has already been installed outside of pgxman, run with `--overwrite` to overwrite it")
+	}
+
+	if errors.Is(err, pgxman.ErrExtensionNotInstalled) {
+		return fmt.Errorf("is not installed by pgxman: %w", err)
+	}
+
+	if errors.Is(err, pgxman.ErrRolledBack) {
+		return fmt.Errorf("failed to %s and rolled back to the previous state, run with `--debug` to see the full error: %w", verb, err)
+	}
+
+	return fmt.Errorf("failed to %s, run with `--debug` to see the full error: %w", verb, err)
+}
@@ -0,0 +1,115 @@
+package pgxman
+
+import (
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newPackSyncPlan(t *testing.T) {
+	installed := []pgxman.InstalledExtension{
+		{
+			Name:      "pgvector",
+			Version:   "0.5.0",
+			PGVersion: pgxman.PGVersion15,
+			Package:   "postgresql-15-pgxman-pgvector",
+		},
+		{
+			Name:      "pg-ivm",
+			Version:   "1.7.0",
+			PGVersion: pgxman.PGVersion15,
+			Package:   "postgresql-15-pgxman-pg-ivm",
+		},
+		{
+			Name:      "postgis",
+			Version:   "3.4.0",
+			PGVersion: pgxman.PGVersion15,
+			Package:   "postgresql-15-pgxman-postgis",
+		},
+		{
+			Name:      "hypopg",
+			Version:   "1.4.0",
+			PGVersion: pgxman.PGVersion15,
+			Package:   "postgresql-15-pgxman-hypopg",
+		},
+		{
+			Name:      "pgvector",
+			Version:   "0.4.4",
+			PGVersion: pgxman.PGVersion14,
+			Package:   "postgresql-14-pgxman-pgvector",
+		},
+	}
+
+	pgvector := pgxman.InstallExtension{
+		PackExtension: pgxman.PackExtension{
+			Name:    "pgvector",
+			Version: "0.5.1",
+		},
+		PGVersion: pgxman.PGVersion15,
+	}
+	pgIVM := pgxman.InstallExtension{
+		PackExtension: pgxman.PackExtension{
+			Name:    "pg_ivm",
+			Version: "1.7.0",
+		},
+		PGVersion: pgxman.PGVersion15,
+	}
+	hypopg := pgxman.InstallExtension{
+		PackExtension: pgxman.PackExtension{
+			Path: "/PATH_TO/postgresql-15-pgxman-hypopg_1.4.0_amd64.deb",
+		},
+		PGVersion: pgxman.PGVersion15,
+	}
+	pgPartman := pgxman.InstallExtension{
+		PackExtension: pgxman.PackExtension{
+			Name:    "pg_partman",
+			Version: "5.0.0",
+		},
+		PGVersion: pgxman.PGVersion15,
+	}
+	exts := []pgxman.InstallExtension{pgvector, pgIVM, hypopg, pgPartman}
+
+	cases := []struct {
+		Name       string
+		PGVersions []pgxman.PGVersion
+		Prune      bool
+		Want       packSyncPlan
+	}{
+		{
+			Name:       "prune",
+			PGVersions: []pgxman.PGVersion{pgxman.PGVersion15},
+			Prune:      true,
+			Want: packSyncPlan{
+				Install: []pgxman.InstallExtension{hypopg, pgPartman},
+				Upgrade: []packSyncUpgrade{{From: installed[0], To: pgvector}},
+				Remove:  []pgxman.InstalledExtension{installed[2]},
+			},
+		},
+		{
+			Name:       "no prune",
+			PGVersions: []pgxman.PGVersion{pgxman.PGVersion15},
+			Want: packSyncPlan{
+				Install: []pgxman.InstallExtension{hypopg, pgPartman},
+				Upgrade: []packSyncUpgrade{{From: installed[0], To: pgvector}},
+			},
+		},
+		{
+			Name:       "prune multiple versions",
+			PGVersions: []pgxman.PGVersion{pgxman.PGVersion15, pgxman.PGVersion14},
+			Prune:      true,
+			Want: packSyncPlan{
+				Install: []pgxman.InstallExtension{hypopg, pgPartman},
+				Upgrade: []packSyncUpgrade{{From: installed[0], To: pgvector}},
+				Remove:  []pgxman.InstalledExtension{installed[4], installed[2]},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert.Equal(t, c.Want, newPackSyncPlan(installed, exts, c.PGVersions, c.Prune))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package pgxman
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+)
+
+// printInstallPlan prints the changes of installing or upgrading the extensions without applying them.
+func printInstallPlan(ctx context.Context, i pgxman.Installer, exts []pgxman.InstallExtension, upgrade bool, output string) error {
+	plan, err := i.Plan(ctx, exts)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		return printJSON(plan)
+	}
+
+	fmt.Println(formatInstallPlan(plan, upgrade))
+
+	return nil
+}
+
+// formatInstallPlan formats the packages and package sources of the plan for humans.
+func formatInstallPlan(plan *pgxman.InstallPlan, upgrade bool) string {
+	action := "installed"
+	if upgrade {
+		action = "upgraded"
+	}
+
+	out := []string{
+		fmt.Sprintf("The following packages would be %s:", action),
+	}
+	for _, pkg := range plan.Packages {
+		if pkg.Path != "" {
+			out = append(out, fmt.Sprintf("  %s (local package for PostgreSQL %s)", pkg.Package, pkg.PGVersion))
+		} else if pkg.RequiredBy != "" {
+			out = append(out, fmt.Sprintf("  %s (%s %s for PostgreSQL %s, required by %s)", pkg.Package, pkg.Name, pkg.Version, pkg.PGVersion, pkg.RequiredBy))
+		} else {
+			out = append(out, fmt.Sprintf("  %s (%s %s for PostgreSQL %s)", pkg.Package, pkg.Name, pkg.Version, pkg.PGVersion))
+		}
+	}
+
+	if len(plan.Sources) > 0 {
+		out = append(out, "The following package sources would be added or updated:")
+		for _, source := range plan.Sources {
+			out = append(out, fmt.Sprintf("  %s: %s", source.Name, source.SourcePath))
+			out = append(out, fmt.Sprintf("    keyring: %s (from %s)", source.KeyPath, source.KeyURL))
+			for _, line := range strings.Split(strings.TrimSpace(source.SourceContent), "\n") {
+				out = append(out, "    | "+line)
+			}
+		}
+	} else {
+		out = append(out, "No package sources would be added or updated.")
+	}
+
+	return strings.Join(out, "\n")
+}
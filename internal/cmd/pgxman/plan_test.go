@@ -0,0 +1,215 @@
+package pgxman
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/oapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_formatInstallPlan(t *testing.T) {
+	pgvector := pgxman.PlannedPackage{
+		Name:      "pgvector",
+		Version:   "0.5.1",
+		PGVersion: pgxman.PGVersion16,
+		Package:   "postgresql-16-pgxman-pgvector=0.5.1",
+	}
+	pgIVM := pgxman.PlannedPackage{
+		Name:       "pg_ivm",
+		Version:    "1.7.0",
+		PGVersion:  pgxman.PGVersion16,
+		Package:    "postgresql-16-pgxman-pg-ivm=1.7.0",
+		RequiredBy: "pgvector",
+	}
+	hypopg := pgxman.PlannedPackage{
+		Path:      "/PATH_TO/postgresql-16-pgxman-hypopg_1.4.0_amd64.deb",
+		PGVersion: pgxman.PGVersion16,
+		Package:   "/PATH_TO/postgresql-16-pgxman-hypopg_1.4.0_amd64.deb",
+	}
+	arrow := pgxman.PlannedSource{
+		Name:          "pgxman-apache-arrow",
+		SourcePath:    "/etc/apt/sources.list.d/pgxman-apache-arrow.sources",
+		SourceContent: "Types: deb\nURIs: https://apache.jfrog.io/artifactory/arrow/debian/\n",
+		KeyPath:       "/usr/share/keyrings/pgxman-apache-arrow.asc",
+		KeyURL:        "https://downloads.apache.org/arrow/KEYS",
+	}
+
+	cases := []struct {
+		Name    string
+		Plan    *pgxman.InstallPlan
+		Upgrade bool
+		Want    string
+	}{
+		{
+			Name: "registry extensions",
+			Plan: &pgxman.InstallPlan{
+				Packages: []pgxman.PlannedPackage{pgvector, pgIVM},
+			},
+			Want: `The following packages would be installed:
+  postgresql-16-pgxman-pgvector=0.5.1 (pgvector 0.5.1 for PostgreSQL 16)
+  postgresql-16-pgxman-pg-ivm=1.7.0 (pg_ivm 1.7.0 for PostgreSQL 16, required by pgvector)
+No package sources would be added or updated.`,
+		},
+		{
+			Name: "local package",
+			Plan: &pgxman.InstallPlan{
+				Packages: []pgxman.PlannedPackage{hypopg},
+			},
+			Upgrade: true,
+			Want: `The following packages would be upgraded:
+  /PATH_TO/postgresql-16-pgxman-hypopg_1.4.0_amd64.deb (local package for PostgreSQL 16)
+No package sources would be added or updated.`,
+		},
+		{
+			Name: "apt sources",
+			Plan: &pgxman.InstallPlan{
+				Packages: []pgxman.PlannedPackage{pgvector},
+				Sources:  []pgxman.PlannedSource{arrow},
+			},
+			Want: `The following packages would be installed:
+  postgresql-16-pgxman-pgvector=0.5.1 (pgvector 0.5.1 for PostgreSQL 16)
+The following package sources would be added or updated:
+  pgxman-apache-arrow: /etc/apt/sources.list.d/pgxman-apache-arrow.sources
+    keyring: /usr/share/keyrings/pgxman-apache-arrow.asc (from https://downloads.apache.org/arrow/KEYS)
+    | Types: deb
+    | URIs: https://apache.jfrog.io/artifactory/arrow/debian/`,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert.Equal(t, c.Want, formatInstallPlan(c.Plan, c.Upgrade))
+		})
+	}
+}
+
+func Test_dryRunPackInstall(t *testing.T) {
+	stubbedClient := StubbedRegistryClient{
+		ExtGetExtension: &oapi.Extension{
+			Name: "pgvector",
+			Packages: oapi.Packages{
+				string(pgxman.PGVersion16): {
+					Version: "0.5.1",
+					Platforms: []oapi.Platform{
+						{
+							Os: oapi.DebianBookworm,
+						},
+					},
+				},
+			},
+		},
+		ExtGetVersion: &oapi.Extension{
+			Name: "pgvector",
+		},
+	}
+	stubbedPlatformDetector := func() (pgxman.Platform, error) {
+		return pgxman.PlatformDebianBookworm, nil
+	}
+
+	pack := &pgxman.Pack{
+		APIVersion: pgxman.DefaultPackAPIVersion,
+		Postgres: pgxman.Postgres{
+			Version: pgxman.PGVersion16,
+		},
+		Extensions: []pgxman.PackExtension{
+			{
+				Name: "pgvector",
+			},
+		},
+	}
+
+	cases := []struct {
+		Name     string
+		Lock     *pgxman.PackLock
+		Update   bool
+		WantExts []pgxman.InstallExtension
+	}{
+		{
+			Name: "no lock file",
+			WantExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "0.5.1",
+					},
+					PGVersion: pgxman.PGVersion16,
+				},
+			},
+		},
+		{
+			Name: "update",
+			Lock: &pgxman.PackLock{
+				APIVersion: pgxman.DefaultPackLockAPIVersion,
+				Extensions: []pgxman.LockedExtension{
+					{
+						Name:       "pgvector",
+						Version:    "0.5.0",
+						Constraint: "latest",
+						PGVersion:  pgxman.PGVersion16,
+						Platform:   pgxman.PlatformDebianBookworm,
+					},
+				},
+			},
+			Update: true,
+			WantExts: []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{
+						Name:    "pgvector",
+						Version: "0.5.1",
+					},
+					PGVersion: pgxman.PGVersion16,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			lockFile := filepath.Join(t.TempDir(), pgxman.PackLockFileName)
+			var want []byte
+			if c.Lock != nil {
+				assert.NoError(pgxman.WritePackLockFile(lockFile, *c.Lock))
+
+				b, err := os.ReadFile(lockFile)
+				assert.NoError(err)
+				want = b
+			}
+
+			i := &stubbedPlanInstaller{}
+			locker := NewPackLocker(stubbedClient, stubbedPlatformDetector, c.Update, log.NewTextLogger())
+			assert.NoError(dryRunPackInstall(context.TODO(), i, locker, pack, lockFile, outputText))
+			assert.Equal(c.WantExts, i.Exts)
+
+			// the lock file is never written by a dry run
+			if c.Lock == nil {
+				assert.NoFileExists(lockFile)
+			} else {
+				got, err := os.ReadFile(lockFile)
+				assert.NoError(err)
+				assert.Equal(string(want), string(got))
+			}
+		})
+	}
+}
+
+// stubbedPlanInstaller records the extensions of the plan. Any other method panics so that a dry run never changes the system.
+type stubbedPlanInstaller struct {
+	pgxman.Installer
+
+	Exts []pgxman.InstallExtension
+}
+
+func (i *stubbedPlanInstaller) Plan(ctx context.Context, exts []pgxman.InstallExtension) (*pgxman.InstallPlan, error) {
+	i.Exts = exts
+
+	return &pgxman.InstallPlan{}, nil
+}
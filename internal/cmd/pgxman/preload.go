@@ -0,0 +1,71 @@
+package pgxman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/internal/pg"
+)
+
+// configurePreloadLibraries adds the shared preload libraries of the extensions to the configuration of
+// the main cluster of each PostgreSQL version and reports that a restart is needed.
+// The libraries are only reported if the cluster configuration is not found.
+func configurePreloadLibraries(exts []pgxman.InstallExtension) error {
+	var (
+		pgVers []pgxman.PGVersion
+		libs   = make(map[pgxman.PGVersion][]string)
+	)
+	for _, ext := range exts {
+		if len(ext.SharedPreloadLibraries) == 0 {
+			continue
+		}
+
+		if !slices.Contains(pgVers, ext.PGVersion) {
+			pgVers = append(pgVers, ext.PGVersion)
+		}
+		libs[ext.PGVersion] = append(libs[ext.PGVersion], ext.SharedPreloadLibraries...)
+	}
+
+	logger := log.NewTextLogger()
+
+	var failed bool
+	for _, pgVer := range pgVers {
+		dir := pg.ConfigDir(pgVer)
+		names := strings.Join(libs[pgVer], ", ")
+
+		if _, err := os.Stat(filepath.Join(dir, "postgresql.conf")); err != nil {
+			logger.Debug("cluster configuration not found", "error", err, "dir", dir)
+			fmt.Printf("[%s] Add %s to shared_preload_libraries of PostgreSQL %s and restart it for the extensions to work\n", infoMark, names, pgVer)
+			continue
+		}
+
+		change, err := pg.AddSharedPreloadLibraries(dir, libs[pgVer])
+		if err != nil {
+			logger.Debug("failed to configure shared_preload_libraries", "error", err, "dir", dir)
+			fmt.Printf("[%s] Failed to add %s to shared_preload_libraries of PostgreSQL %s: %s\n", errorMark, names, pgVer, err)
+			failed = true
+			continue
+		}
+
+		if change == nil {
+			continue
+		}
+
+		fmt.Printf("[%s] Added %s to shared_preload_libraries in %s\n", successMark, strings.Join(change.Added, ", "), change.File)
+		if change.Backup != "" {
+			fmt.Printf("    The previous configuration is backed up to %s\n", change.Backup)
+		}
+		fmt.Printf("Restart PostgreSQL %s for the change to take effect, e.g. sudo systemctl restart postgresql@%s-main\n", pgVer, pgVer)
+	}
+
+	if failed {
+		return fmt.Errorf("failed to configure shared_preload_libraries")
+	}
+
+	return nil
+}
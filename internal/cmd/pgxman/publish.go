@@ -30,7 +30,8 @@ func newPublishCmd() *cobra.Command {
 }
 
 func runPublish(c *cobra.Command, args []string) error {
-	client, err := newReigstryClient()
+	// extensions are published to the registry of --registry, never to a configured one by priority
+	client, err := newSingleRegistryClient(flagRegistryURL)
 	if err != nil {
 		return err
 	}
@@ -75,6 +76,8 @@ func convertPublishExtension(ext pgxman.Extension) oapi.PublishExtension {
 			Repository:  pkg.Repository,
 			Source:      pkg.Source,
 			Version:     pkg.Version,
+
+			SharedPreloadLibraries: pkg.SharedPreloadLibraries,
 		}
 	}
 
@@ -88,11 +91,9 @@ func convertPublishExtension(ext pgxman.Extension) oapi.PublishExtension {
 
 func convertPlatform(pkg pgxman.ExtensionPackage) []oapi.Platform {
 	var platforms []oapi.Platform
-	for k, v := range map[oapi.PlatformOs]*pgxman.AptExtensionBuilder{
-		oapi.DebianBookworm: pkg.Builders.DebianBookworm,
-		oapi.UbuntuJammy:    pkg.Builders.UbuntuJammy,
-		oapi.UbuntuNoble:    pkg.Builders.UbuntuNoble,
-	} {
+	for _, info := range pgxman.Platforms {
+		k := oapi.PlatformOs(info.Platform)
+		v := pkg.Builders.Builder(info.Platform)
 		if v == nil {
 			continue
 		}
@@ -121,7 +122,7 @@ func convertPlatform(pkg pgxman.ExtensionPackage) []oapi.Platform {
 		}
 
 		var aptRepos []oapi.AptRepository
-		for _, r := range v.AptRepositories {
+		for _, r := range pkg.Builders.AptRepositories(info.Platform) {
 			var types []oapi.AptRepositoryType
 			for _, t := range r.Types {
 				types = append(types, oapi.AptRepositoryType(strings.ReplaceAll(string(t), "-", "_")))
@@ -131,8 +132,9 @@ func convertPlatform(pkg pgxman.ExtensionPackage) []oapi.Platform {
 				Components: r.Components,
 				Id:         r.ID,
 				SignedKey: oapi.SignedKey{
-					Url:    r.SignedKey.URL,
-					Format: oapi.SignedKeyFormat(r.SignedKey.Format),
+					Url:         r.SignedKey.URL,
+					Format:      oapi.SignedKeyFormat(r.SignedKey.Format),
+					Fingerprint: r.SignedKey.NormalizedFingerprint(),
 				},
 				Suites: r.Suites,
 				Types:  types,
@@ -4,14 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 
 	"log/slog"
 
 	"github.com/pgxman/pgxman"
 	"github.com/pgxman/pgxman/internal/auth"
+	"github.com/pgxman/pgxman/internal/config"
 	"github.com/pgxman/pgxman/internal/log"
 	"github.com/pgxman/pgxman/internal/pg"
 	"github.com/pgxman/pgxman/internal/registry"
@@ -22,6 +27,7 @@ import (
 var (
 	flagDebug       bool
 	flagRegistryURL string
+	flagOffline     bool
 )
 
 func Command() *cobra.Command {
@@ -36,6 +42,10 @@ func Command() *cobra.Command {
 				log.SetLevel(slog.LevelDebug)
 			}
 
+			if flagOffline {
+				return nil
+			}
+
 			return checkUpgrade(cmd.Context())
 		},
 	}
@@ -45,14 +55,19 @@ func Command() *cobra.Command {
 	root.AddCommand(newBuildCmd())
 	root.AddCommand(newInstallCmd())
 	root.AddCommand(newUpgradeCmd())
+	root.AddCommand(newUninstallCmd())
+	root.AddCommand(newListCmd())
+	root.AddCommand(newOutdatedCmd())
 	root.AddCommand(newPackCmd())
+	root.AddCommand(newBundleCmd())
 	root.AddCommand(newPublishCmd())
 	root.AddCommand(newContainerCmd())
 	root.AddCommand(newDoctorCmd())
 	root.AddCommand(newAuthCmd())
 
 	root.PersistentFlags().BoolVar(&flagDebug, "debug", os.Getenv("DEBUG") != "", "enable debug logging")
-	root.PersistentFlags().StringVar(&flagRegistryURL, "registry", "https://registry.pgxman.com/v1", "registry URL")
+	root.PersistentFlags().StringVar(&flagRegistryURL, "registry", defaultRegistryURL, "registry URL. It overrides the registries in the config")
+	root.PersistentFlags().BoolVar(&flagOffline, "offline", false, "answer registry requests from the local cache without the network")
 
 	return root
 }
@@ -91,6 +106,108 @@ var (
 	errCanNotDetectPG = errors.New("could not detect a supported installation of PostgreSQL. For more info, run `pgxman doctor`")
 )
 
+// pgVersionAllInstalled selects all installed PostgreSQL versions for the --pg flag.
+const pgVersionAllInstalled = "all-installed"
+
+// parsePGVersions parses the --pg flag of comma-separated PostgreSQL versions or all-installed,
+// and checks that the PostgreSQL versions are installed.
+func parsePGVersions(ctx context.Context, s string) ([]pgxman.PGVersion, error) {
+	if s == pgVersionAllInstalled {
+		pgVers := pg.InstalledVersions(ctx)
+		if len(pgVers) == 0 {
+			return nil, errCanNotDetectPG
+		}
+
+		return pgVers, nil
+	}
+
+	pgVers, err := splitPGVersions(s)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pgVer := range pgVers {
+		if err := checkPGVerExists(ctx, pgVer); err != nil {
+			return nil, err
+		}
+	}
+
+	return pgVers, nil
+}
+
+func joinPGVersions(pgVers []pgxman.PGVersion) string {
+	var s []string
+	for _, pgVer := range pgVers {
+		s = append(s, string(pgVer))
+	}
+
+	return strings.Join(s, ", ")
+}
+
+// groupByPGVersion groups the extensions by PostgreSQL version in the order of the first appearance of each version.
+func groupByPGVersion(exts []pgxman.InstallExtension) ([]pgxman.PGVersion, map[pgxman.PGVersion][]pgxman.InstallExtension) {
+	var (
+		pgVers []pgxman.PGVersion
+		groups = make(map[pgxman.PGVersion][]pgxman.InstallExtension)
+	)
+	for _, ext := range exts {
+		if _, ok := groups[ext.PGVersion]; !ok {
+			pgVers = append(pgVers, ext.PGVersion)
+		}
+		groups[ext.PGVersion] = append(groups[ext.PGVersion], ext)
+	}
+
+	return pgVers, groups
+}
+
+// formatResults joins the result line of each extension. The lines are grouped under
+// the PostgreSQL versions if there are multiple versions.
+func formatResults(exts []pgxman.InstallExtension, format func(ext pgxman.InstallExtension) string) string {
+	pgVers, groups := groupByPGVersion(exts)
+
+	var out []string
+	for _, pgVer := range pgVers {
+		if len(pgVers) > 1 {
+			out = append(out, fmt.Sprintf("PostgreSQL %s:\n", pgVer))
+		}
+
+		for _, ext := range groups[pgVer] {
+			line := format(ext)
+			if len(pgVers) > 1 {
+				line = "  " + line
+			}
+			out = append(out, line)
+		}
+	}
+
+	return strings.Join(out, "")
+}
+
+func splitPGVersions(s string) ([]pgxman.PGVersion, error) {
+	var pgVers []pgxman.PGVersion
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		pgVer := pgxman.PGVersion(v)
+		if err := pgVer.Validate(); err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(pgVers, pgVer) {
+			pgVers = append(pgVers, pgVer)
+		}
+	}
+
+	if len(pgVers) == 0 {
+		return nil, errCanNotDetectPG
+	}
+
+	return pgVers, nil
+}
+
 func supportedPGVersions() []string {
 	var pgVers []string
 	for _, v := range pgxman.SupportedPGVersions {
@@ -100,6 +217,19 @@ func supportedPGVersions() []string {
 	return pgVers
 }
 
+func parsePGVersion(ver string) pgxman.PGVersion {
+	if ver == "" {
+		return pgxman.PGVersionUnknown
+	}
+
+	v := pgxman.PGVersion(ver)
+	if v.Validate() != nil {
+		return pgxman.PGVersionUnknown
+	}
+
+	return v
+}
+
 func checkPGVerExists(ctx context.Context, pgVer pgxman.PGVersion) error {
 	if pgVer == pgxman.PGVersionUnknown || !pg.VersionExists(ctx, pgVer) {
 		return errCanNotDetectPG
@@ -108,8 +238,63 @@ func checkPGVerExists(ctx context.Context, pgVer pgxman.PGVersion) error {
 	return nil
 }
 
-func newReigstryClient() (registry.Client, error) {
-	u, err := url.ParseRequestURI(flagRegistryURL)
+// defaultRegistryName is the name of the registry of --registry, and of the public registry if no registries are configured.
+const defaultRegistryName = "pgxman"
+
+const defaultRegistryURL = "https://registry.pgxman.com/v1"
+
+// newReigstryClient returns the client of the registries in the config in the order of priority.
+// The registry of --registry is used instead if it's set or no registries are configured.
+func newReigstryClient(cmd *cobra.Command) (registry.Client, error) {
+	regs := []config.Registry{
+		{
+			Name: defaultRegistryName,
+			URL:  flagRegistryURL,
+		},
+	}
+	if !cmd.Flags().Changed("registry") {
+		cfg, err := config.Read()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("read config: %w", err)
+		}
+
+		if cfg != nil && len(cfg.Registries) > 0 {
+			regs = cfg.Registries
+		}
+	}
+
+	var (
+		result registry.Registries
+		seen   = make(map[string]bool)
+	)
+	for _, reg := range regs {
+		if reg.Name == "" || strings.Contains(reg.Name, "/") {
+			return nil, fmt.Errorf("invalid registry name %q", reg.Name)
+		}
+		if seen[reg.Name] {
+			return nil, fmt.Errorf("duplicated registry name %q", reg.Name)
+		}
+		seen[reg.Name] = true
+
+		client, err := newSingleRegistryClient(reg.URL)
+		if err != nil {
+			return nil, fmt.Errorf("registry %s: %w", reg.Name, err)
+		}
+
+		result = append(result, registry.Registry{
+			Name:   reg.Name,
+			URL:    reg.URL,
+			Client: client,
+		})
+	}
+
+	return result, nil
+}
+
+// newSingleRegistryClient returns the client of the registry of the URL with the token stored for its host.
+// The extension metadata is cached under the user cache directory.
+func newSingleRegistryClient(registryURL string) (registry.Client, error) {
+	u, err := url.ParseRequestURI(registryURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid registry URL: %w", err)
 	}
@@ -117,8 +302,12 @@ func newReigstryClient() (registry.Client, error) {
 	t, err := auth.Token(u)
 	if err != nil {
 		// log error but continue
-		log.NewTextLogger().Debug("could not get token from keyring", "error", err)
+		log.NewTextLogger().Debug("could not get token from keyring", "error", err, "host", u.Host)
 	}
 
-	return registry.NewClient(flagRegistryURL, t)
+	cache := &registry.Cache{
+		Dir:     filepath.Join(config.CacheDir(), "registry"),
+		Offline: flagOffline,
+	}
+	return registry.NewClient(registryURL, t, registry.WithCache(cache))
 }
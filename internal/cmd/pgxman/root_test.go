@@ -0,0 +1,138 @@
+package pgxman
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/config"
+	"github.com/pgxman/pgxman/internal/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_splitPGVersions(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Value   string
+		Want    []pgxman.PGVersion
+		WantErr bool
+	}{
+		{
+			Name:  "single version",
+			Value: "16",
+			Want:  []pgxman.PGVersion{pgxman.PGVersion16},
+		},
+		{
+			Name:  "multiple versions",
+			Value: "15, 16,15",
+			Want:  []pgxman.PGVersion{pgxman.PGVersion15, pgxman.PGVersion16},
+		},
+		{
+			Name:    "unsupported version",
+			Value:   "15,12",
+			WantErr: true,
+		},
+		{
+			Name:    "empty",
+			Value:   "",
+			WantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := splitPGVersions(c.Value)
+			if c.WantErr {
+				assert.Error(err)
+				return
+			}
+
+			assert.NoError(err)
+			assert.Equal(c.Want, got)
+		})
+	}
+}
+
+func Test_formatResults(t *testing.T) {
+	assert := assert.New(t)
+
+	format := func(ext pgxman.InstallExtension) string {
+		return fmt.Sprintf("%s\n", ext)
+	}
+	ext := func(name string, pgVer pgxman.PGVersion) pgxman.InstallExtension {
+		return pgxman.InstallExtension{
+			PackExtension: pgxman.PackExtension{Name: name, Version: "1.0.0"},
+			PGVersion:     pgVer,
+		}
+	}
+
+	assert.Equal("a 1.0.0\nb 1.0.0\n", formatResults([]pgxman.InstallExtension{
+		ext("a", pgxman.PGVersion16),
+		ext("b", pgxman.PGVersion16),
+	}, format))
+
+	assert.Equal("PostgreSQL 16:\n  a 1.0.0\n  b 1.0.0\nPostgreSQL 15:\n  a 1.0.0\n", formatResults([]pgxman.InstallExtension{
+		ext("a", pgxman.PGVersion16),
+		ext("a", pgxman.PGVersion15),
+		ext("b", pgxman.PGVersion16),
+	}, format))
+}
+
+func Test_newReigstryClient(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	err := config.Write(config.Config{
+		Registries: []config.Registry{
+			{Name: "internal", URL: "https://registry.example.com/v1"},
+			{Name: "pgxman", URL: "https://registry.pgxman.com/v1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name string
+		Args []string
+		Want []string
+	}{
+		{
+			Name: "registries of config",
+			Want: []string{"internal https://registry.example.com/v1", "pgxman https://registry.pgxman.com/v1"},
+		},
+		{
+			Name: "registry flag",
+			Args: []string{"--registry", "https://registry.example.org/v1"},
+			Want: []string{"pgxman https://registry.example.org/v1"},
+		},
+		{
+			Name: "registry flag of default registry",
+			Args: []string{"--registry", "https://registry.pgxman.com/v1"},
+			Want: []string{"pgxman https://registry.pgxman.com/v1"},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cmd := Command()
+			assert.NoError(cmd.ParseFlags(c.Args))
+
+			client, err := newReigstryClient(cmd)
+			assert.NoError(err)
+
+			var got []string
+			for _, r := range client.(registry.Registries) {
+				got = append(got, r.Name+" "+r.URL)
+			}
+			assert.Equal(c.Want, got)
+		})
+	}
+}
@@ -33,7 +33,7 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no search terms provided")
 	}
 
-	client, err := newReigstryClient()
+	client, err := newReigstryClient(cmd)
 	if err != nil {
 		return err
 	}
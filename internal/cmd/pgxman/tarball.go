@@ -0,0 +1,50 @@
+package pgxman
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/pg"
+	"github.com/pgxman/pgxman/internal/plugin"
+)
+
+// newTarballInstaller returns the installer of relocatable tarballs for the PostgreSQL installation of pg_config
+// and the PostgreSQL version of the installation.
+func newTarballInstaller(ctx context.Context, pgConfig string) (pgxman.Installer, pgxman.PGVersion, error) {
+	pgVer, err := pg.ConfigVersion(ctx, pgConfig)
+	if err != nil {
+		return nil, pgxman.PGVersionUnknown, fmt.Errorf("detect PostgreSQL version of %s: %w", pgConfig, err)
+	}
+
+	return plugin.GetTarballInstaller(pgConfig), pgVer, nil
+}
+
+// parseTarballArgs returns the tarball installer of pg_config, its PostgreSQL version and the local tarballs of the arguments.
+// Extensions in the registry can't be installed because they aren't distributed as tarballs.
+func parseTarballArgs(ctx context.Context, pgConfig string, args []string, overwrite bool) (pgxman.Installer, []pgxman.PGVersion, []pgxman.InstallExtension, error) {
+	i, pgVer, err := newTarballInstaller(ctx, pgConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var exts []pgxman.InstallExtension
+	for _, arg := range args {
+		ext, err := parseInstallExtension(arg)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if ext.Path == "" {
+			return nil, nil, nil, fmt.Errorf("%s: only local tarballs can be installed with --pg-config", arg)
+		}
+		ext.Overwrite = overwrite
+
+		exts = append(exts, pgxman.InstallExtension{
+			PackExtension: *ext,
+			PGVersion:     pgVer,
+		})
+	}
+
+	return i, []pgxman.PGVersion{pgVer}, exts, nil
+}
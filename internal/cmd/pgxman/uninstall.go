@@ -0,0 +1,167 @@
+package pgxman
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/cmd/cmdutil"
+	"github.com/pgxman/pgxman/internal/errorsx"
+	"github.com/pgxman/pgxman/internal/iostreams"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/internal/pg"
+	"github.com/pgxman/pgxman/internal/plugin"
+	"github.com/pgxman/pgxman/internal/tui/spinner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagUninstallYes       bool
+	flagUninstallPGVersion string
+	flagUninstallPGConfig  string
+)
+
+func newUninstallCmd() *cobra.Command {
+	var defPGVer string
+	pgVer, err := pg.DetectVersion(context.Background())
+	if err == nil {
+		defPGVer = string(pgVer)
+	}
+
+	cmd := &cobra.Command{
+		Use:     "uninstall",
+		Aliases: []string{"remove", "rm"},
+		Short:   "Uninstall PostgreSQL extensions",
+		Long: `Uninstall PostgreSQL extensions that were installed by pgxman. The argument
+format is NAME. The PostgreSQL version is detected from pg_config
+if it exists, or can be specified with the --pg flag.`,
+		Example: fmt.Sprintf(`  # Uninstall pgvector for the installed PostgreSQL.
+  pgxman uninstall pgvector
+
+  # Uninstall pgvector for PostgreSQL %[1]s
+  pgxman uninstall pgvector --pg %[1]s
+
+  # Uninstall pgvector and postgis for PostgreSQL %[1]s
+  pgxman uninstall pgvector postgis --pg %[1]s
+
+  # Uninstall the pgvector tarball from the PostgreSQL installation of a pg_config
+  pgxman uninstall pgvector --pg-config /opt/pgsql/bin/pg_config`, pgxman.DefaultPGVersion),
+		RunE: runUninstall,
+		Args: cobra.MinimumNArgs(1),
+	}
+
+	cmd.PersistentFlags().BoolVarP(&flagUninstallYes, "yes", "y", false, `Automatic yes to prompts and run uninstall non-interactively.`)
+	cmd.PersistentFlags().StringVar(&flagUninstallPGVersion, "pg", defPGVer, fmt.Sprintf("Uninstall the extension for the PostgreSQL version. It detects the version by pg_config if it exists. Supported values are %s.", strings.Join(supportedPGVersions(), ", ")))
+	cmd.PersistentFlags().StringVar(&flagUninstallPGConfig, "pg-config", "", "Uninstall the extension tarball from the PostgreSQL installation of the pg_config.")
+	cmd.MarkFlagsMutuallyExclusive("pg", "pg-config")
+
+	return cmd
+}
+
+func runUninstall(cmd *cobra.Command, args []string) error {
+	var (
+		i     pgxman.Installer
+		pgVer pgxman.PGVersion
+		err   error
+	)
+	if flagUninstallPGConfig != "" {
+		i, pgVer, err = newTarballInstaller(cmd.Context(), flagUninstallPGConfig)
+		if err != nil {
+			return err
+		}
+	} else {
+		i, err = plugin.GetInstaller(cmd.Context())
+		if err != nil {
+			return errorsx.Pretty(err)
+		}
+
+		pgVer = parsePGVersion(flagUninstallPGVersion)
+		if err := checkPGVerExists(cmd.Context(), pgVer); err != nil {
+			return err
+		}
+	}
+
+	var exts []pgxman.InstallExtension
+	for _, arg := range args {
+		name, err := parseUninstallExtension(arg)
+		if err != nil {
+			return err
+		}
+
+		exts = append(exts, pgxman.InstallExtension{
+			PackExtension: pgxman.PackExtension{
+				Name: name,
+			},
+			PGVersion: pgVer,
+		})
+	}
+
+	warnExtensionsInUse(cmd.Context(), exts)
+
+	if !flagUninstallYes {
+		if err := i.PreUninstallCheck(cmd.Context(), exts, iostreams.NewIOStreams()); err != nil {
+			return err
+		}
+	}
+
+	logger := log.NewTextLogger()
+	fmt.Printf("Uninstalling extensions for PostgreSQL %s...\n", pgVer)
+	if err := uninstall(cmd.Context(), i, exts); err != nil {
+		logger.Debug("failed to uninstall extensions", "error", err, "extensions", exts)
+		return cmdutil.SilentError
+	}
+
+	return nil
+}
+
+// warnExtensionsInUse prints a warning for each extension that is still created in a database.
+// The check is best-effort because it needs access to the local PostgreSQL cluster.
+func warnExtensionsInUse(ctx context.Context, exts []pgxman.InstallExtension) {
+	logger := log.NewTextLogger()
+	for _, ext := range exts {
+		dbs, err := pg.ExtensionDatabases(ctx, ext.PGVersion, pg.ExtensionNames(ext.Name)...)
+		if err != nil {
+			logger.Debug("could not check databases for extension", "error", err, "extension", ext.Name)
+			continue
+		}
+
+		if len(dbs) > 0 {
+			fmt.Printf("[%s] %s is still created in databases: %s. Run `DROP EXTENSION` in each database before uninstalling.\n", infoMark, ext.Name, strings.Join(dbs, ", "))
+		}
+	}
+}
+
+// uninstall uninstalls the extensions in a single transaction and reports the result for each extension.
+func uninstall(ctx context.Context, i pgxman.Installer, exts []pgxman.InstallExtension) error {
+	if len(exts) == 0 {
+		return nil
+	}
+
+	var names []string
+	for _, ext := range exts {
+		names = append(names, ext.Name)
+	}
+
+	s := spinner.New(flagDebug)
+	s.WithIndicator(fmt.Sprintf("Uninstalling %s...\n", strings.Join(names, ", ")))
+	defer s.Stop()
+
+	s.Start()
+	if err := i.Uninstall(ctx, exts); err != nil {
+		err = handleInstallErr(err, "uninstall")
+
+		// the installer reports one error for the transaction, so it is printed once for all extensions
+		s.WithDone(fmt.Sprintf("[%s] %s: %s\n", errorMark, strings.Join(names, ", "), err))
+
+		return err
+	}
+
+	var done []string
+	for _, name := range names {
+		done = append(done, fmt.Sprintf("[%s] %s\n", successMark, name))
+	}
+	s.WithDone(strings.Join(done, ""))
+
+	return nil
+}
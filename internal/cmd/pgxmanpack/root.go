@@ -38,7 +38,7 @@ func Execute() error {
 			}
 
 			var err error
-			packager, err = plugin.GetPackager()
+			packager, err = plugin.GetPackager(cmd.Context())
 			if err != nil {
 				return errorsx.Pretty(err)
 			}
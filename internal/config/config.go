@@ -12,6 +12,15 @@ import (
 type Config struct {
 	OAuth                OAuth     `json:"oauth"`
 	LastUpgradeCheckTime time.Time `json:"lastUpgradeCheckTime"`
+	// Registries are the registries to look up extensions in, in the order of priority.
+	// The public registry is used if there is none.
+	Registries []Registry `json:"registries,omitempty"`
+}
+
+// Registry is a registry that extension names can be prefixed with by name, e.g. internal/pgvector.
+type Registry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
 }
 
 type OAuth struct {
@@ -61,6 +70,15 @@ func ConfigDir() string {
 	return filepath.Join(userConfigDir, "pgxman")
 }
 
+func CacheDir() string {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return filepath.Join(userCacheDir, "pgxman")
+}
+
 func newDefaultConfig() Config {
 	return Config{
 		OAuth: OAuth{
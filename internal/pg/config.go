@@ -0,0 +1,331 @@
+package pg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pgxman/pgxman"
+)
+
+const (
+	configFileName = "postgresql.conf"
+	// autoConfigFileName is the file in the data directory that ALTER SYSTEM writes, it's read after postgresql.conf
+	autoConfigFileName    = "postgresql.auto.conf"
+	preloadConfigFileName = "pgxman.conf"
+	configBackupPrefix    = ".pgxman-"
+	configBackupSuffix    = ".bak"
+)
+
+var (
+	regexpPreloadSetting = regexp.MustCompile(`(?i)^\s*shared_preload_libraries\s*(?:=\s*|\s)(.*)$`)
+	regexpIncludeDir     = regexp.MustCompile(`(?i)^\s*include_dir\s*(?:=\s*|\s)(.*)$`)
+	regexpDataDirectory  = regexp.MustCompile(`(?i)^\s*data_directory\s*(?:=\s*|\s)(.*)$`)
+)
+
+// ConfigDir returns the configuration directory of the main cluster of the PostgreSQL version
+// created by the PGDG packages.
+func ConfigDir(ver pgxman.PGVersion) string {
+	return filepath.Join("/etc/postgresql", string(ver), "main")
+}
+
+// PreloadChange describes a change of shared_preload_libraries in a configuration file.
+type PreloadChange struct {
+	// File is the configuration file that is changed
+	File string
+	// Backup is the copy of the file before the change, it's empty if the file is created
+	Backup string
+	// Libraries is the value of shared_preload_libraries after the change
+	Libraries []string
+	// Added are the libraries that are added
+	Added []string
+}
+
+// AddSharedPreloadLibraries adds the libraries to shared_preload_libraries of the cluster configured in dir.
+// Libraries that are already preloaded are kept. The file that has the effective setting is updated in place,
+// including postgresql.auto.conf in the data directory if the setting is set with ALTER SYSTEM, otherwise
+// the setting is written to conf.d/pgxman.conf if conf.d is included, or appended to postgresql.conf.
+// The file is backed up to a new timestamped file before it's changed. It returns nil if all libraries are already preloaded.
+func AddSharedPreloadLibraries(dir string, libs []string) (*PreloadChange, error) {
+	mainFile := filepath.Join(dir, configFileName)
+	setting, includeDirs, err := findPreloadSetting(mainFile)
+	if err != nil {
+		return nil, err
+	}
+
+	dataDir, err := findDataDirectory(mainFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// postgresql.auto.conf overrides the other files
+	autoSetting, _, err := findPreloadSetting(filepath.Join(dataDir, autoConfigFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("check shared_preload_libraries set by ALTER SYSTEM: %w", err)
+	}
+	if autoSetting != nil {
+		setting = autoSetting
+	}
+
+	var current []string
+	if setting != nil {
+		current = setting.Libraries
+	}
+
+	var added []string
+	for _, lib := range libs {
+		if !slices.Contains(current, lib) && !slices.Contains(added, lib) {
+			added = append(added, lib)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	change := &PreloadChange{
+		Libraries: append(slices.Clone(current), added...),
+		Added:     added,
+	}
+	line := formatPreloadSetting(change.Libraries)
+
+	if setting == nil {
+		change.File = mainFile
+		if slices.Contains(includeDirs, filepath.Join(dir, "conf.d")) {
+			change.File = filepath.Join(dir, "conf.d", preloadConfigFileName)
+		}
+	} else {
+		change.File = setting.File
+	}
+
+	content, err := os.ReadFile(change.File)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var lines []string
+	if len(content) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	}
+	if setting != nil {
+		lines[setting.Line] = line
+	} else {
+		lines = append(lines, line)
+	}
+
+	mode := os.FileMode(0644)
+	if content != nil {
+		fi, err := os.Stat(change.File)
+		if err != nil {
+			return nil, err
+		}
+		mode = fi.Mode()
+
+		change.Backup, err = backupConfigFile(change.File, content, mode)
+		if err != nil {
+			return nil, fmt.Errorf("back up %s: %w", change.File, err)
+		}
+	}
+
+	if err := writeFileAtomic(change.File, []byte(strings.Join(lines, "\n")+"\n"), mode); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// backupConfigFile writes the content of the file to a new backup file named after the current time,
+// e.g. postgresql.conf.pgxman-20240102T150405.bak, so that backups of earlier changes are kept.
+func backupConfigFile(file string, content []byte, mode os.FileMode) (string, error) {
+	base := file + configBackupPrefix + time.Now().Format("20060102T150405")
+	for i := 0; ; i++ {
+		backup := base + configBackupSuffix
+		if i > 0 {
+			backup = fmt.Sprintf("%s-%d%s", base, i, configBackupSuffix)
+		}
+
+		f, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+		if err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+
+			return "", err
+		}
+
+		if _, err := f.Write(content); err != nil {
+			f.Close()
+			return "", err
+		}
+
+		return backup, f.Close()
+	}
+}
+
+// findDataDirectory returns the data_directory of the configuration file, or the directory of the file if it's not set.
+func findDataDirectory(file string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(file)
+	for _, line := range strings.Split(string(content), "\n") {
+		if m := regexpDataDirectory.FindStringSubmatch(stripConfigComment(line)); m != nil {
+			dir = unquoteConfigValue(m[1])
+		}
+	}
+
+	return dir, nil
+}
+
+// preloadSetting is the line of a configuration file that sets shared_preload_libraries.
+type preloadSetting struct {
+	File      string
+	Line      int
+	Libraries []string
+}
+
+// findPreloadSetting returns the effective shared_preload_libraries setting, i.e. the last one,
+// in the configuration file and the directories included with include_dir, and the included directories.
+// include and include_if_exists are not followed.
+func findPreloadSetting(file string) (*preloadSetting, []string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		setting     *preloadSetting
+		includeDirs []string
+	)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = stripConfigComment(line)
+
+		if m := regexpPreloadSetting.FindStringSubmatch(line); m != nil {
+			setting = &preloadSetting{
+				File:      file,
+				Line:      i,
+				Libraries: parsePreloadLibraries(m[1]),
+			}
+			continue
+		}
+
+		if m := regexpIncludeDir.FindStringSubmatch(line); m != nil {
+			dir := unquoteConfigValue(m[1])
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(filepath.Dir(file), dir)
+			}
+			includeDirs = append(includeDirs, dir)
+
+			// files in the directory are included in the order of their names at the position of include_dir
+			files, err := filepath.Glob(filepath.Join(dir, "*.conf"))
+			if err != nil {
+				return nil, nil, err
+			}
+			sort.Strings(files)
+
+			for _, f := range files {
+				s, dirs, err := findPreloadSetting(f)
+				if err != nil {
+					return nil, nil, err
+				}
+				if s != nil {
+					setting = s
+				}
+				includeDirs = append(includeDirs, dirs...)
+			}
+		}
+	}
+
+	return setting, includeDirs, nil
+}
+
+// stripConfigComment removes the comment that starts with # outside of quotes.
+func stripConfigComment(line string) string {
+	var quoted bool
+	for i, r := range line {
+		switch r {
+		case '\'':
+			quoted = !quoted
+		case '#':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+
+	return line
+}
+
+func unquoteConfigValue(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) >= 2 && strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'") {
+		v = strings.ReplaceAll(v[1:len(v)-1], "''", "'")
+	}
+
+	return v
+}
+
+// parsePreloadLibraries parses the comma-separated libraries of shared_preload_libraries, e.g. 'pg_stat_statements, "my lib"'.
+func parsePreloadLibraries(v string) []string {
+	var libs []string
+	for _, lib := range strings.Split(unquoteConfigValue(v), ",") {
+		lib = strings.Trim(strings.TrimSpace(lib), `"`)
+		if lib != "" {
+			libs = append(libs, lib)
+		}
+	}
+
+	return libs
+}
+
+func formatPreloadSetting(libs []string) string {
+	var quoted []string
+	for _, lib := range libs {
+		if strings.ContainsAny(lib, ", ") {
+			lib = `"` + lib + `"`
+		}
+		quoted = append(quoted, strings.ReplaceAll(lib, "'", "''"))
+	}
+
+	return fmt.Sprintf("shared_preload_libraries = '%s'", strings.Join(quoted, ","))
+}
+
+// writeFileAtomic writes the file with a temporary file in the same directory and renames it
+// so that the configuration file is never partially written.
+func writeFileAtomic(file string, content []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return err
+	}
+
+	// keep the owner, e.g. postgres, of an existing file
+	if fi, err := os.Stat(file); err == nil {
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			if err := os.Chown(f.Name(), int(st.Uid), int(st.Gid)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return os.Rename(f.Name(), file)
+}
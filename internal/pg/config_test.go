@@ -0,0 +1,191 @@
+package pg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AddSharedPreloadLibraries(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Files       map[string]string
+		Libs        []string
+		WantFile    string
+		WantLibs    []string
+		WantContent string
+		WantBackup  bool
+	}{
+		{
+			Name: "update existing setting",
+			Files: map[string]string{
+				"postgresql.conf": "port = 5432\nshared_preload_libraries = 'pg_stat_statements'\t# (change requires restart)\n",
+			},
+			Libs:        []string{"pg_cron"},
+			WantFile:    "postgresql.conf",
+			WantLibs:    []string{"pg_stat_statements", "pg_cron"},
+			WantContent: "port = 5432\nshared_preload_libraries = 'pg_stat_statements,pg_cron'\n",
+			WantBackup:  true,
+		},
+		{
+			Name: "commented out setting",
+			Files: map[string]string{
+				"postgresql.conf": "#shared_preload_libraries = ''\n",
+			},
+			Libs:        []string{"pg_cron"},
+			WantFile:    "postgresql.conf",
+			WantLibs:    []string{"pg_cron"},
+			WantContent: "#shared_preload_libraries = ''\nshared_preload_libraries = 'pg_cron'\n",
+			WantBackup:  true,
+		},
+		{
+			Name: "write to conf.d",
+			Files: map[string]string{
+				"postgresql.conf":    "#shared_preload_libraries = ''\ninclude_dir = 'conf.d'\n",
+				"conf.d/other.conf":  "work_mem = '4MB'\n",
+				"conf.d/ignored.txt": "shared_preload_libraries = 'ignored'\n",
+			},
+			Libs:        []string{"timescaledb"},
+			WantFile:    "conf.d/pgxman.conf",
+			WantLibs:    []string{"timescaledb"},
+			WantContent: "shared_preload_libraries = 'timescaledb'\n",
+		},
+		{
+			Name: "setting in conf.d overrides postgresql.conf",
+			Files: map[string]string{
+				"postgresql.conf":   "shared_preload_libraries = 'a'\ninclude_dir 'conf.d'\n",
+				"conf.d/50-b.conf":  "shared_preload_libraries = 'b, \"c d\"'\n",
+				"conf.d/10-x.conf":  "shared_preload_libraries = 'x'\n",
+				"conf.d/99-y.conf":  "max_connections = 100\n",
+				"conf.d/empty.conf": "",
+			},
+			Libs:        []string{"pg_cron", "b"},
+			WantFile:    "conf.d/50-b.conf",
+			WantLibs:    []string{"b", "c d", "pg_cron"},
+			WantContent: "shared_preload_libraries = 'b,\"c d\",pg_cron'\n",
+			WantBackup:  true,
+		},
+		{
+			Name: "postgresql.conf after include_dir",
+			Files: map[string]string{
+				"postgresql.conf":  "include_dir = 'conf.d'\nshared_preload_libraries = 'a'\n",
+				"conf.d/50-b.conf": "shared_preload_libraries = 'b'\n",
+			},
+			Libs:        []string{"pg_cron"},
+			WantFile:    "postgresql.conf",
+			WantLibs:    []string{"a", "pg_cron"},
+			WantContent: "include_dir = 'conf.d'\nshared_preload_libraries = 'a,pg_cron'\n",
+			WantBackup:  true,
+		},
+		{
+			Name: "setting of ALTER SYSTEM overrides postgresql.conf",
+			Files: map[string]string{
+				"postgresql.conf":      "shared_preload_libraries = 'a'\n",
+				"postgresql.auto.conf": "# Do not edit this file manually!\nshared_preload_libraries = 'b'\n",
+			},
+			Libs:        []string{"pg_cron"},
+			WantFile:    "postgresql.auto.conf",
+			WantLibs:    []string{"b", "pg_cron"},
+			WantContent: "# Do not edit this file manually!\nshared_preload_libraries = 'b,pg_cron'\n",
+			WantBackup:  true,
+		},
+		{
+			Name: "postgresql.auto.conf in data_directory",
+			Files: map[string]string{
+				"postgresql.conf":           "data_directory = '$DIR/data'\t# use data in another directory\nshared_preload_libraries = 'a'\n",
+				"data/postgresql.auto.conf": "shared_preload_libraries = 'b'\n",
+			},
+			Libs:        []string{"pg_cron"},
+			WantFile:    "data/postgresql.auto.conf",
+			WantLibs:    []string{"b", "pg_cron"},
+			WantContent: "shared_preload_libraries = 'b,pg_cron'\n",
+			WantBackup:  true,
+		},
+		{
+			Name: "postgresql.auto.conf without setting",
+			Files: map[string]string{
+				"postgresql.conf":      "shared_preload_libraries = 'a'\n",
+				"postgresql.auto.conf": "work_mem = '4MB'\n",
+			},
+			Libs:        []string{"pg_cron"},
+			WantFile:    "postgresql.conf",
+			WantLibs:    []string{"a", "pg_cron"},
+			WantContent: "shared_preload_libraries = 'a,pg_cron'\n",
+			WantBackup:  true,
+		},
+		{
+			Name: "already preloaded",
+			Files: map[string]string{
+				"postgresql.conf": "shared_preload_libraries = 'pg_cron,pg_stat_statements'\n",
+			},
+			Libs: []string{"pg_cron"},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			dir := t.TempDir()
+			for name, content := range c.Files {
+				file := filepath.Join(dir, name)
+				assert.NoError(os.MkdirAll(filepath.Dir(file), 0755))
+				assert.NoError(os.WriteFile(file, []byte(strings.ReplaceAll(content, "$DIR", dir)), 0644))
+			}
+
+			change, err := AddSharedPreloadLibraries(dir, c.Libs)
+			assert.NoError(err)
+
+			if c.WantFile == "" {
+				assert.Nil(change)
+				return
+			}
+
+			file := filepath.Join(dir, c.WantFile)
+			assert.Equal(file, change.File)
+			assert.Equal(c.WantLibs, change.Libraries)
+
+			content, err := os.ReadFile(file)
+			assert.NoError(err)
+			assert.Equal(c.WantContent, string(content))
+
+			if c.WantBackup {
+				assert.True(strings.HasPrefix(change.Backup, file+configBackupPrefix), change.Backup)
+				assert.True(strings.HasSuffix(change.Backup, configBackupSuffix), change.Backup)
+
+				backup, err := os.ReadFile(change.Backup)
+				assert.NoError(err)
+				assert.Equal(c.Files[c.WantFile], string(backup))
+			} else {
+				assert.Empty(change.Backup)
+			}
+		})
+	}
+}
+
+func Test_AddSharedPreloadLibraries_KeepBackups(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, configFileName)
+	assert.NoError(os.WriteFile(file, []byte("shared_preload_libraries = 'a'\n"), 0644))
+
+	first, err := AddSharedPreloadLibraries(dir, []string{"b"})
+	assert.NoError(err)
+	second, err := AddSharedPreloadLibraries(dir, []string{"c"})
+	assert.NoError(err)
+	assert.NotEqual(first.Backup, second.Backup)
+
+	// the backup of the first change is the original configuration
+	backup, err := os.ReadFile(first.Backup)
+	assert.NoError(err)
+	assert.Equal("shared_preload_libraries = 'a'\n", string(backup))
+
+	backup, err = os.ReadFile(second.Backup)
+	assert.NoError(err)
+	assert.Equal("shared_preload_libraries = 'a,b'\n", string(backup))
+}
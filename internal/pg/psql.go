@@ -0,0 +1,168 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+)
+
+const (
+	ActivationCreated = "created"
+	ActivationUpdated = "updated"
+	ActivationSkipped = "skipped"
+)
+
+// Conn describes the PostgreSQL server that psql connects to.
+// The local cluster of the PostgreSQL version is used if ConnString is empty.
+type Conn struct {
+	PGVersion pgxman.PGVersion
+	// ConnString is a libpq connection string in the keyword/value or the URI format
+	ConnString string
+}
+
+// Databases returns the databases that accept connections, excluding templates.
+func Databases(ctx context.Context, conn Conn) ([]string, error) {
+	return runPsql(ctx, conn, "postgres", "SELECT datname FROM pg_database WHERE datallowconn AND NOT datistemplate ORDER BY datname")
+}
+
+// ActivateExtension updates the extension in the database with ALTER EXTENSION UPDATE if it has been created,
+// otherwise it creates the extension if create is set. It returns the action taken.
+func ActivateExtension(ctx context.Context, conn Conn, dbname, extName string, create bool) (string, error) {
+	rows, err := runPsql(ctx, conn, dbname, fmt.Sprintf("SELECT extname FROM pg_extension WHERE extname = %s", quoteLiteral(extName)))
+	if err != nil {
+		return "", err
+	}
+
+	if len(rows) > 0 {
+		if _, err := runPsql(ctx, conn, dbname, fmt.Sprintf("ALTER EXTENSION %s UPDATE", quoteIdent(extName))); err != nil {
+			return "", err
+		}
+
+		return ActivationUpdated, nil
+	}
+
+	if !create {
+		return ActivationSkipped, nil
+	}
+
+	if _, err := runPsql(ctx, conn, dbname, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s CASCADE", quoteIdent(extName))); err != nil {
+		return "", err
+	}
+
+	return ActivationCreated, nil
+}
+
+// ExtensionDatabases returns the databases of the local PostgreSQL cluster in which any of the extension names have been created.
+func ExtensionDatabases(ctx context.Context, ver pgxman.PGVersion, extNames ...string) ([]string, error) {
+	conn := Conn{PGVersion: ver}
+	dbs, err := runPsql(ctx, conn, "postgres", "SELECT datname FROM pg_database WHERE datallowconn ORDER BY datname")
+	if err != nil {
+		return nil, err
+	}
+
+	var quoted []string
+	for _, name := range extNames {
+		quoted = append(quoted, quoteLiteral(name))
+	}
+	query := fmt.Sprintf("SELECT extname FROM pg_extension WHERE extname IN (%s)", strings.Join(quoted, ", "))
+
+	var result []string
+	for _, db := range dbs {
+		exts, err := runPsql(ctx, conn, db, query)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(exts) > 0 {
+			result = append(result, db)
+		}
+	}
+
+	return result, nil
+}
+
+// ExtensionNames returns the possible names of the extension in pg_extension for a pgxman extension name.
+// Extension names in the registry may use dashes where the SQL extension uses underscores.
+func ExtensionNames(name string) []string {
+	names := []string{name}
+	if n := strings.ReplaceAll(name, "-", "_"); n != name {
+		names = append(names, n)
+	}
+
+	return names
+}
+
+// runPsql runs a query with psql and returns the rows of the first column.
+// For the local cluster, the cluster is selected with PGCLUSTER which is respected by pg_wrapper of the PGDG packages,
+// and the command is run as the postgres user when running as root so that peer authentication works.
+func runPsql(ctx context.Context, conn Conn, dbname, query string) ([]string, error) {
+	db := dbname
+	if conn.ConnString != "" {
+		db = connStringWithDBName(conn.ConnString, dbname)
+	}
+
+	args := []string{"psql", "-X", "-A", "-t", "-q", "-v", "ON_ERROR_STOP=1", "-d", db, "-c", query}
+	env := os.Environ()
+	if conn.ConnString == "" {
+		if os.Getuid() == 0 {
+			args = append([]string{"runuser", "-u", "postgres", "--"}, args...)
+		}
+
+		env = append(env, fmt.Sprintf("PGCLUSTER=%s/main", conn.PGVersion))
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = env
+
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("psql: %w: %s", err, strings.TrimSpace(string(b)))
+	}
+
+	return parsePsqlRows(string(b)), nil
+}
+
+func parsePsqlRows(out string) []string {
+	var rows []string
+	for _, row := range strings.Split(out, "\n") {
+		row = strings.TrimSpace(row)
+		if row == "" {
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// connStringWithDBName returns the connection string that connects to the database.
+func connStringWithDBName(connStr, dbname string) string {
+	if strings.HasPrefix(connStr, "postgres://") || strings.HasPrefix(connStr, "postgresql://") {
+		u, err := url.Parse(connStr)
+		if err == nil {
+			u.Path = "/" + dbname
+			u.RawPath = ""
+			return u.String()
+		}
+	}
+
+	// libpq uses the last value of a keyword that is specified multiple times
+	v := strings.ReplaceAll(dbname, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+
+	return fmt.Sprintf("%s dbname='%s'", connStr, v)
+}
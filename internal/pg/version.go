@@ -12,6 +12,8 @@ import (
 
 var (
 	regexpPGVersion = regexp.MustCompile(`^PostgreSQL (\d+).+\((.+)\s(.+)\)$`)
+	// regexpAnyPGVersion matches the version of any distribution, e.g. PostgreSQL 16.2 built from source
+	regexpAnyPGVersion = regexp.MustCompile(`^PostgreSQL (\d+)[.\w]*(\s.*)?$`)
 
 	ErrParsingPGVersion     = fmt.Errorf("failed to parse pg version")
 	ErrUnsupportedPGVersion = fmt.Errorf("unsupported pg version")
@@ -32,6 +34,30 @@ func VersionExists(ctx context.Context, ver pgxman.PGVersion) bool {
 	return pgVer == ver
 }
 
+// InstalledVersions returns the supported PostgreSQL versions that are installed.
+func InstalledVersions(ctx context.Context) []pgxman.PGVersion {
+	var result []pgxman.PGVersion
+	for _, ver := range pgxman.SupportedPGVersions {
+		if VersionExists(ctx, ver) {
+			result = append(result, ver)
+		}
+	}
+
+	return result
+}
+
+// ConfigVersion returns the PostgreSQL version of the pg_config at path.
+// Unlike DetectVersion, PostgreSQL of any distribution is accepted, e.g. one built from source under a custom prefix.
+func ConfigVersion(ctx context.Context, path string) (pgxman.PGVersion, error) {
+	cmd := exec.CommandContext(ctx, path, "--version")
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		return pgxman.PGVersionUnknown, err
+	}
+
+	return parseAnyPGVersion(strings.TrimSpace(string(b)))
+}
+
 func pgConfigVersion(ctx context.Context, path string) (pgxman.PGVersion, error) {
 	cmd := exec.CommandContext(ctx, path, "--version")
 	b, err := cmd.CombinedOutput()
@@ -59,3 +85,17 @@ func parsePGVersion(s string) (pgxman.PGVersion, error) {
 
 	return def, nil
 }
+
+func parseAnyPGVersion(s string) (pgxman.PGVersion, error) {
+	matches := regexpAnyPGVersion.FindStringSubmatch(s)
+	if len(matches) == 0 {
+		return pgxman.PGVersionUnknown, ErrParsingPGVersion
+	}
+
+	def := pgxman.PGVersion(matches[1])
+	if err := def.Validate(); err != nil {
+		return pgxman.PGVersionUnknown, ErrUnsupportedPGVersion
+	}
+
+	return def, nil
+}
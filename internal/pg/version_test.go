@@ -51,3 +51,56 @@ func Test_parsePGVersion(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseAnyPGVersion(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Str       string
+		WantPGVer pgxman.PGVersion
+		WantErr   error
+	}{
+		{
+			Name:      "pgdg",
+			Str:       "PostgreSQL 16.1 (Debian 16.1-1.pgdg120+1)",
+			WantPGVer: pgxman.PGVersion16,
+		},
+		{
+			Name:      "distro",
+			Str:       "PostgreSQL 14.10 (Ubuntu 14.10-0ubuntu0.22.04.1)",
+			WantPGVer: pgxman.PGVersion14,
+		},
+		{
+			Name:      "source",
+			Str:       "PostgreSQL 15.6",
+			WantPGVer: pgxman.PGVersion15,
+		},
+		{
+			Name:      "beta",
+			Str:       "PostgreSQL 16beta1",
+			WantPGVer: pgxman.PGVersion16,
+		},
+		{
+			Name:      "unsupported pg version",
+			Str:       "PostgreSQL 10.1",
+			WantPGVer: pgxman.PGVersionUnknown,
+			WantErr:   ErrUnsupportedPGVersion,
+		},
+		{
+			Name:      "malformed pg version",
+			Str:       "psql (PostgreSQL) 16.1",
+			WantPGVer: pgxman.PGVersionUnknown,
+			WantErr:   ErrParsingPGVersion,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ver, err := parseAnyPGVersion(c.Str)
+			assert.Equal(c.WantPGVer, ver)
+			assert.Equal(c.WantErr, err)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Package buildscript renders the build scripts of an extension that are shared by the packagers.
+package buildscript
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/pgxman/pgxman"
+	tmpl "github.com/pgxman/pgxman/internal/template"
+	"github.com/pgxman/pgxman/internal/template/script"
+)
+
+// Concat joins the build scripts into one script that prints the name of each script before running it.
+func Concat(scripts []pgxman.BuildScript) string {
+	var steps []string
+	for _, s := range scripts {
+		step := fmt.Sprintf("echo %q\n", s.Name)
+		step += s.Run
+
+		steps = append(steps, step)
+	}
+
+	return strings.Join(steps, "\n\n")
+}
+
+// ExportPrePost writes the pre and post build scripts of the extension into dir.
+func ExportPrePost(ext pgxman.Extension, dir string) error {
+	return tmpl.ExportFS(script.FS, templater{ext}, dir)
+}
+
+type scriptData struct {
+	pgxman.Extension
+}
+
+func (s scriptData) PreBuildScript() string {
+	return Concat(s.Build.Pre)
+}
+
+func (s scriptData) PostBuildScript() string {
+	return Concat(s.Build.Post)
+}
+
+type templater struct {
+	ext pgxman.Extension
+}
+
+func (s templater) Render(content []byte, out io.Writer) error {
+	t, err := template.New("").Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+
+	if err := t.Execute(out, scriptData{s.ext}); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	return nil
+}
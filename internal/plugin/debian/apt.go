@@ -14,6 +14,8 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -40,6 +42,12 @@ Signed-By: {{ .SignedBy }}
 	regexpSourceFileURIs = regexp.MustCompile(`(?m)^URIs:\s*(.+)$`)
 	regexpSourceListURIs = regexp.MustCompile(`\bdeb(?:-src)?\s+(?:\[.*\]\s+)?(http[^\s]+)`)
 	regexpConflictDebPkg = regexp.MustCompile(`trying to overwrite '(.+)', which is also in package`)
+	regexpErrorDebPkg    = regexp.MustCompile(`error processing archive (\S+)`)
+	regexpPurgedDebPkg   = regexp.MustCompile(`(?m)^Purg (\S+)`)
+)
+
+const (
+	dpkgQueryFormat = "${Package}\t${Version}\t${Architecture}\t${Status}\n"
 )
 
 type aptSourcesTmplData struct {
@@ -72,6 +80,7 @@ type AptSource struct {
 	SourcePath    string
 	SourceContent []byte
 	KeyPath       string
+	KeyURL        string
 	KeyContent    []byte
 }
 
@@ -79,12 +88,27 @@ func (a AptSource) String() string {
 	return fmt.Sprintf("%s (%s)", a.Name, a.SourcePath)
 }
 
+type DpkgPackage struct {
+	Pkg     string
+	Version string
+	Arch    string
+	Held    bool
+}
+
 type AptPackage struct {
 	Pkg       string
 	Opts      []string
 	Repos     []pgxman.AptRepository
 	IsLocal   bool
 	Overwrite bool
+	// RequiredBy is the extension that depends on the package if it's installed as a dependency
+	RequiredBy string
+}
+
+// Name returns the package name without the version.
+func (p AptPackage) Name() string {
+	name, _, _ := strings.Cut(p.Pkg, "=")
+	return name
 }
 
 func (a *Apt) ConvertSources(ctx context.Context, repos []pgxman.AptRepository) ([]AptSource, error) {
@@ -130,20 +154,11 @@ func (a *Apt) GetChangedSources(ctx context.Context, repos []pgxman.AptRepositor
 			return nil, err
 		}
 
-		diff, err := isFileDifferent(file.SourcePath, file.SourceContent)
+		changed, err := isSourceChanged(*file)
 		if err != nil {
 			return nil, err
 		}
-		if diff {
-			aptSourceMap[key] = *file
-			continue
-		}
-
-		diff, err = isFileDifferent(file.KeyPath, file.KeyContent)
-		if err != nil {
-			return nil, err
-		}
-		if diff {
+		if changed {
 			aptSourceMap[key] = *file
 		}
 	}
@@ -152,26 +167,311 @@ func (a *Apt) GetChangedSources(ctx context.Context, repos []pgxman.AptRepositor
 	for _, v := range aptSourceMap {
 		result = append(result, v)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
 
 	return result, nil
 }
 
 func (a *Apt) Install(ctx context.Context, pkgs []AptPackage, sources []AptSource) error {
-	return a.installOrUpgrade(ctx, pkgs, sources, false)
+	return a.installOrUpgrade(ctx, pkgs, sources, nil, false)
 }
 
 func (a *Apt) Upgrade(ctx context.Context, pkgs []AptPackage, sources []AptSource) error {
-	return a.installOrUpgrade(ctx, pkgs, sources, true)
+	return a.installOrUpgrade(ctx, pkgs, sources, nil, true)
+}
+
+// Sync upgrades the packages and purges the packages to remove with a single apt invocation
+// so that a failed sync is rolled back as a whole.
+// Installed packages that depend on the packages to remove are not removed, the sync is refused instead.
+func (a *Apt) Sync(ctx context.Context, pkgs []AptPackage, sources []AptSource, remove []AptPackage) error {
+	for _, pkg := range remove {
+		installed, err := a.isInstalled(ctx, pkg)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			return fmt.Errorf("%s: %w", pkg.Pkg, pgxman.ErrExtensionNotInstalled)
+		}
+	}
+
+	dependents, err := a.ReverseDependencies(ctx, remove)
+	if err != nil {
+		return err
+	}
+	if len(dependents) > 0 {
+		return fmt.Errorf("%s depend on the packages to remove", strings.Join(aptPkgNames(dependents), ", "))
+	}
+
+	return a.installOrUpgrade(ctx, pkgs, sources, remove, true)
+}
+
+// InstallOffline installs local packages without updating the package index so that no network access is needed.
+// The held packages are unheld before installing if they are installed, and are held after installing.
+// They are rolled back to the previous versions if the installation fails.
+func (a *Apt) InstallOffline(ctx context.Context, pkgs []AptPackage, sources []AptSource, held []AptPackage) (err error) {
+	a.Logger.Debug("Installing debian packages offline", "packages", pkgs, "sources", sources, "held", held)
+
+	snap, err := a.snapshot(ctx, held, sources)
+	if err != nil {
+		return err
+	}
+	defer a.rollbackOnError(ctx, snap, &err)
+
+	if err := a.writeSources(sources); err != nil {
+		return err
+	}
+
+	var installed []AptPackage
+	for _, pkg := range held {
+		ok, err := a.isInstalled(ctx, pkg)
+		if err != nil {
+			return err
+		}
+		if ok {
+			installed = append(installed, pkg)
+		}
+	}
+	if len(installed) > 0 {
+		if err := a.aptMarkUnhold(ctx, installed...); err != nil {
+			return err
+		}
+	}
+
+	if err := a.aptInstallOrUpgrade(ctx, pkgs, nil, false); err != nil {
+		return err
+	}
+
+	if len(held) > 0 {
+		return a.aptMarkHold(ctx, held...)
+	}
+
+	return nil
+}
+
+// Download downloads the packages and all of their dependencies into dir, including the dependencies
+// that are installed on this host.
+// It uses a temporary apt configuration with the repositories of the packages
+// so that the apt configuration of the system is unchanged.
+func (a *Apt) Download(ctx context.Context, pkgs []AptPackage, dir string) error {
+	a.Logger.Debug("Downloading debian packages", "packages", pkgs, "dir", dir)
+
+	workDir, err := os.MkdirTemp("", "pgxman-apt-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	var (
+		sourceDir   = filepath.Join(workDir, "sources.list.d")
+		keyRingsDir = filepath.Join(workDir, "keyrings")
+		listsDir    = filepath.Join(workDir, "lists")
+	)
+	for _, d := range []string{sourceDir, filepath.Join(listsDir, "partial"), filepath.Join(dir, "partial")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return err
+		}
+	}
+
+	written := make(map[string]struct{})
+	for _, pkg := range pkgs {
+		for _, repo := range pkg.Repos {
+			name := repo.Name() + ".sources"
+			if _, ok := written[name]; ok {
+				continue
+			}
+
+			uris := a.removeDuplicatedSourceHosts(repo.URIs)
+			if len(uris) == 0 {
+				a.Logger.Debug("Skipping apt source that already exists", "name", repo.Name())
+				continue
+			}
+			repo.URIs = uris
+
+			keyContent, err := downloadKey(repo)
+			if err != nil {
+				return err
+			}
+
+			file, err := newAptSource(repo, keyContent, sourceDir, keyRingsDir)
+			if err != nil {
+				return err
+			}
+
+			if err := a.writeSources([]AptSource{*file}); err != nil {
+				return err
+			}
+
+			written[name] = struct{}{}
+		}
+	}
+
+	// keep the sources of the system that are not replaced by the sources of the packages
+	files, err := os.ReadDir(aptSourceDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if _, ok := written[file.Name()]; ok || file.IsDir() {
+			continue
+		}
+
+		b, err := os.ReadFile(filepath.Join(aptSourceDir, file.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := writeFile(filepath.Join(sourceDir, file.Name()), b); err != nil {
+			return err
+		}
+	}
+
+	// an empty dpkg status makes apt resolve the dependencies as on a host without any package installed
+	// so that the dependencies that are installed on this host are downloaded too
+	statusFile := filepath.Join(workDir, "status")
+	if err := writeFile(statusFile, nil); err != nil {
+		return err
+	}
+
+	opts := []string{
+		"-o", "Dir::Etc::SourceParts=" + sourceDir,
+		"-o", "Dir::State::Lists=" + listsDir,
+		"-o", "Dir::State::status=" + statusFile,
+		"-o", "Dir::Cache::archives=" + dir,
+		"-o", "Dir::Cache::pkgcache=",
+		"-o", "Dir::Cache::srcpkgcache=",
+	}
+
+	if _, err := a.runAptCmd(ctx, "apt-get", append([]string{"update"}, opts...)...); err != nil {
+		return fmt.Errorf("apt-get update: %w", err)
+	}
+
+	args := append([]string{"install", "--download-only", "--yes", "--no-install-recommends"}, opts...)
+	args = append(args, aptPkgNames(pkgs)...)
+	if _, err := a.runAptCmd(ctx, "apt-get", args...); err != nil {
+		return fmt.Errorf("apt-get install --download-only: %w", err)
+	}
+
+	return errors.Join(
+		os.RemoveAll(filepath.Join(dir, "partial")),
+		os.RemoveAll(filepath.Join(dir, "lock")),
+	)
+}
+
+// Uninstall purges the packages with a single apt invocation so that apt removes them together.
+// The installed packages that depend on them are purged too, see ReverseDependencies.
+// They are rolled back to the previous state if the purge fails.
+func (a *Apt) Uninstall(ctx context.Context, pkgs []AptPackage) (err error) {
+	a.Logger.Debug("Uninstalling debian packages", "packages", pkgs)
+
+	for _, pkg := range pkgs {
+		installed, err := a.isInstalled(ctx, pkg)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			return fmt.Errorf("%s: %w", pkg.Pkg, pgxman.ErrExtensionNotInstalled)
+		}
+	}
+
+	dependents, err := a.ReverseDependencies(ctx, pkgs)
+	if err != nil {
+		return err
+	}
+	pkgs = append(slices.Clone(pkgs), dependents...)
+
+	snap, err := a.snapshot(ctx, pkgs, nil)
+	if err != nil {
+		return err
+	}
+	defer a.rollbackOnError(ctx, snap, &err)
+
+	return a.aptPurge(ctx, pkgs)
+}
+
+// ReverseDependencies returns the installed packages that apt purges together with the packages
+// because they depend on them.
+func (a *Apt) ReverseDependencies(ctx context.Context, pkgs []AptPackage) ([]AptPackage, error) {
+	if len(pkgs) == 0 {
+		return nil, nil
+	}
+
+	opts := []string{"purge", "--simulate", "--allow-change-held-packages"}
+	opts = append(opts, aptPkgNames(pkgs)...)
+
+	out, err := a.runAptCmd(ctx, "apt-get", opts...)
+	if err != nil {
+		return nil, fmt.Errorf("apt-get purge --simulate: %w", err)
+	}
+
+	var result []AptPackage
+	for _, name := range parsePurgedDebPkgs(out) {
+		requested := slices.ContainsFunc(pkgs, func(pkg AptPackage) bool {
+			return pkg.Name() == name
+		})
+		if !requested {
+			result = append(result, AptPackage{Pkg: name})
+		}
+	}
+
+	return result, nil
 }
 
-func (a *Apt) installOrUpgrade(ctx context.Context, pkgs []AptPackage, sources []AptSource, upgrade bool) error {
-	a.Logger.Debug("Installing or upgrading debian packages", "packages", pkgs, "sources", sources, "upgrade", upgrade)
+// ListInstalled returns the installed Debian packages whose names match the pattern.
+func (a *Apt) ListInstalled(ctx context.Context, pattern string) ([]DpkgPackage, error) {
+	out, err := a.runDpkgQuery(ctx, "--show", "--showformat="+dpkgQueryFormat, pattern)
+	if err != nil {
+		// dpkg-query exits with 1 when no package matches the pattern
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("dpkg-query: %w", err)
+	}
+
+	return parseDpkgQuery(out), nil
+}
+
+// ListFiles returns the files installed by the Debian package.
+func (a *Apt) ListFiles(ctx context.Context, pkg string) ([]string, error) {
+	out, err := a.runDpkgQuery(ctx, "--listfiles", pkg)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, pgxman.ErrExtensionNotInstalled
+		}
+
+		return nil, fmt.Errorf("dpkg-query: %w", err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+
+	return files, nil
+}
+
+// installOrUpgrade installs or upgrades the packages and purges the packages to remove.
+// They are rolled back to the previous state if it fails.
+func (a *Apt) installOrUpgrade(ctx context.Context, pkgs []AptPackage, sources []AptSource, remove []AptPackage, upgrade bool) (err error) {
+	a.Logger.Debug("Installing or upgrading debian packages", "packages", pkgs, "sources", sources, "remove", remove, "upgrade", upgrade)
+
+	snap, err := a.snapshot(ctx, append(slices.Clone(pkgs), remove...), sources)
+	if err != nil {
+		return err
+	}
+	defer a.rollbackOnError(ctx, snap, &err)
 
 	if err := a.addSources(ctx, sources); err != nil {
 		return err
 	}
 
-	if err := a.aptInstallOrUpgrade(ctx, pkgs, upgrade); err != nil {
+	if err := a.aptInstallOrUpgrade(ctx, pkgs, remove, upgrade); err != nil {
 		return err
 	}
 
@@ -197,42 +497,56 @@ func (a *Apt) removeDuplicatedSourceHosts(uris []string) []string {
 }
 
 func (a *Apt) addSources(ctx context.Context, files []AptSource) error {
+	if err := a.writeSources(files); err != nil {
+		return err
+	}
+
+	return a.aptUpdate(ctx)
+}
+
+func (a *Apt) writeSources(files []AptSource) error {
 	for _, file := range files {
 		a.Logger.Debug("Writing source", "source_path", file.SourcePath, "key_path", file.KeyPath)
 		if err := writeFile(file.SourcePath, file.SourceContent); err != nil {
 			return err
 		}
+		// the key is trusted on first use, a changed key may be a compromised key URL
+		if from, to, ok := keyChange(file); ok {
+			a.Logger.Warn("Replacing the signing key of the apt repository", "name", file.Name, "from", formatFingerprints(from), "to", formatFingerprints(to))
+		}
 		if err := writeFile(file.KeyPath, []byte(file.KeyContent)); err != nil {
 			return err
 		}
 	}
 
-	return a.aptUpdate(ctx)
+	return nil
 }
 
-func (a *Apt) aptInstallOrUpgrade(ctx context.Context, pkgs []AptPackage, upgrade bool) error {
-	for _, pkg := range pkgs {
-		if err := a.aptInstallOrUpgradeOne(ctx, pkg, upgrade); err != nil {
-			return err
-		}
+// aptInstallOrUpgrade installs or upgrades all packages and purges the packages to remove with a single apt invocation
+// so that apt resolves them together and a failed resolution leaves the system unchanged.
+func (a *Apt) aptInstallOrUpgrade(ctx context.Context, pkgs []AptPackage, remove []AptPackage, upgrade bool) (err error) {
+	if len(pkgs) == 0 && len(remove) == 0 {
+		return nil
 	}
 
-	return nil
-}
-
-func (a *Apt) aptInstallOrUpgradeOne(ctx context.Context, pkg AptPackage, upgrade bool) (err error) {
-	logger := a.Logger.With("package", pkg, "upgrade", upgrade)
+	logger := a.Logger.With("packages", pkgs, "remove", remove, "upgrade", upgrade)
 	logger.Debug("Running apt install or upgrade")
 
 	// apt-mark hold and unhold don't work for a local package
-	if !pkg.IsLocal {
-		err = errors.Join(err, a.aptMarkUnhold(ctx, pkg))
+	var remotePkgs []AptPackage
+	for _, pkg := range pkgs {
+		if !pkg.IsLocal {
+			remotePkgs = append(remotePkgs, pkg)
+		}
+	}
+	if len(remotePkgs) > 0 {
+		err = errors.Join(err, a.aptMarkUnhold(ctx, remotePkgs...))
 		if err != nil {
 			return err
 		}
 
 		defer func() {
-			err = errors.Join(err, a.aptMarkHold(ctx, pkg))
+			err = errors.Join(err, a.aptMarkHold(ctx, remotePkgs...))
 		}()
 	}
 
@@ -243,15 +557,30 @@ func (a *Apt) aptInstallOrUpgradeOne(ctx context.Context, pkg AptPackage, upgrad
 		opts = []string{"install"}
 	}
 
+	// the packages to remove are held after they are installed or upgraded by pgxman
+	if len(remove) > 0 {
+		if err := a.aptMarkUnhold(ctx, remove...); err != nil {
+			return err
+		}
+
+		opts = append(opts, "--purge")
+	}
+
 	opts = append(opts, "--yes", "--no-install-recommends")
-	opts = append(opts, pkg.Opts...)
-	opts = append(opts, pkg.Pkg)
+	opts = append(opts, mergePackageOpts(pkgs)...)
+	for _, pkg := range pkgs {
+		opts = append(opts, pkg.Pkg)
+	}
+	// a package name with the suffix - is removed by apt install and apt upgrade
+	for _, pkg := range remove {
+		opts = append(opts, pkg.Name()+"-")
+	}
 
 	out, oerr := a.runAptCmd(ctx, "apt", opts...)
 	if oerr != nil {
 		if conflictDebPkg(out) {
-			if pkg.Overwrite {
-				logger.Debug("Force overwriting package")
+			if canOverwriteConflict(out, pkgs) {
+				logger.Debug("Force overwriting packages")
 				_, oerr = a.runAptCmd(ctx, "apt", append(opts, "-o", "Dpkg::Options::=--force-overwrite")...)
 			} else {
 				return pgxman.ErrConflictExtension
@@ -266,6 +595,62 @@ func (a *Apt) aptInstallOrUpgradeOne(ctx context.Context, pkg AptPackage, upgrad
 	return err
 }
 
+// mergePackageOpts merges the options of the packages that are installed by one apt command.
+// The options of a package are kept together, e.g. -o A=1, since an option can take the next argument as its value.
+// Options that are the same as the options of an earlier package are dropped.
+func mergePackageOpts(pkgs []AptPackage) []string {
+	var (
+		opts []string
+		seen [][]string
+	)
+	for _, pkg := range pkgs {
+		if len(pkg.Opts) == 0 || slices.ContainsFunc(seen, func(o []string) bool { return slices.Equal(o, pkg.Opts) }) {
+			continue
+		}
+
+		seen = append(seen, pkg.Opts)
+		opts = append(opts, pkg.Opts...)
+	}
+
+	return opts
+}
+
+// aptPurge purges all packages with a single apt invocation.
+func (a *Apt) aptPurge(ctx context.Context, pkgs []AptPackage) error {
+	logger := a.Logger.With("packages", pkgs)
+	logger.Debug("Running apt purge")
+
+	// the packages are held after they are installed or upgraded by pgxman
+	if err := a.aptMarkUnhold(ctx, pkgs...); err != nil {
+		return err
+	}
+
+	opts := []string{"purge", "--yes"}
+	opts = append(opts, mergePackageOpts(pkgs)...)
+	opts = append(opts, aptPkgNames(pkgs)...)
+
+	if _, err := a.runAptCmd(ctx, "apt", opts...); err != nil {
+		return fmt.Errorf("apt purge: %w", err)
+	}
+
+	return nil
+}
+
+func (a *Apt) isInstalled(ctx context.Context, pkg AptPackage) (bool, error) {
+	out, err := a.runDpkgQuery(ctx, "--show", "--showformat=${Status}", pkg.Pkg)
+	if err != nil {
+		// dpkg-query exits with 1 when the package is unknown
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("dpkg-query: %w", err)
+	}
+
+	return strings.HasSuffix(strings.TrimSpace(out), " installed"), nil
+}
+
 func (a *Apt) aptUpdate(ctx context.Context) error {
 	_, err := a.runAptCmd(ctx, "apt", "update")
 	if err != nil {
@@ -275,8 +660,8 @@ func (a *Apt) aptUpdate(ctx context.Context) error {
 	return nil
 }
 
-func (a *Apt) aptMarkHold(ctx context.Context, pkg AptPackage) error {
-	_, err := a.runAptCmd(ctx, "apt-mark", "hold", pkg.Pkg)
+func (a *Apt) aptMarkHold(ctx context.Context, pkgs ...AptPackage) error {
+	_, err := a.runAptCmd(ctx, "apt-mark", append([]string{"hold"}, aptPkgNames(pkgs)...)...)
 	if err != nil {
 		return fmt.Errorf("apt-mark hold: %w", err)
 	}
@@ -284,8 +669,8 @@ func (a *Apt) aptMarkHold(ctx context.Context, pkg AptPackage) error {
 	return nil
 }
 
-func (a *Apt) aptMarkUnhold(ctx context.Context, pkg AptPackage) error {
-	_, err := a.runAptCmd(ctx, "apt-mark", "unhold", pkg.Pkg)
+func (a *Apt) aptMarkUnhold(ctx context.Context, pkgs ...AptPackage) error {
+	_, err := a.runAptCmd(ctx, "apt-mark", append([]string{"unhold"}, aptPkgNames(pkgs)...)...)
 	if err != nil {
 		return fmt.Errorf("apt-mark unhold: %w", err)
 	}
@@ -293,6 +678,22 @@ func (a *Apt) aptMarkUnhold(ctx context.Context, pkg AptPackage) error {
 	return nil
 }
 
+// runDpkgQuery runs dpkg-query with only stdout captured
+// because dpkg-query writes warnings for unmatched patterns to stderr.
+func (a *Apt) runDpkgQuery(ctx context.Context, args ...string) (string, error) {
+	bw := bytes.NewBuffer(nil)
+	lw := a.Logger.Writer(slog.LevelDebug)
+
+	cmd := exec.CommandContext(ctx, "dpkg-query", args...)
+	cmd.Stdout = io.MultiWriter(lw, bw)
+	cmd.Stderr = lw
+
+	a.Logger.Debug("Running dpkg-query command", "command", cmd.String())
+	err := cmd.Run()
+
+	return bw.String(), err
+}
+
 func (a *Apt) runAptCmd(ctx context.Context, command string, args ...string) (string, error) {
 	bw := bytes.NewBuffer(nil)
 	lw := a.Logger.Writer(slog.LevelDebug)
@@ -311,13 +712,20 @@ func (a *Apt) runAptCmd(ctx context.Context, command string, args ...string) (st
 func (a *Apt) newSourceFile(repo pgxman.AptRepository) (*AptSource, error) {
 	logger := a.Logger.WithGroup(repo.Name())
 
-	keyPath := filepath.Join(aptKeyRingsDir, repo.Name()+"."+string(repo.SignedKey.Format))
-	logger.Debug("Downloading gpg key", "url", repo.SignedKey, "path", keyPath)
-	keyContent, err := downloadURL(repo.SignedKey.URL)
+	logger.Debug("Downloading gpg key", "url", repo.SignedKey)
+	keyContent, err := downloadKey(repo)
 	if err != nil {
 		return nil, err
 	}
 
+	return newAptSource(repo, keyContent, aptSourceDir, aptKeyRingsDir)
+}
+
+// newAptSource returns the apt source of a repository whose source file is in sourceDir
+// and whose key is in keyRingsDir.
+func newAptSource(repo pgxman.AptRepository, keyContent []byte, sourceDir, keyRingsDir string) (*AptSource, error) {
+	keyPath := filepath.Join(keyRingsDir, aptKeyFileName(repo))
+
 	sourceContent := bytes.NewBuffer(nil)
 	if err := aptSourcesTmpl.Execute(sourceContent, aptSourcesTmplData{
 		Types:      repo.TypesString(),
@@ -331,13 +739,18 @@ func (a *Apt) newSourceFile(repo pgxman.AptRepository) (*AptSource, error) {
 
 	return &AptSource{
 		Name:          repo.Name(),
-		SourcePath:    filepath.Join(aptSourceDir, repo.Name()+".sources"),
+		SourcePath:    filepath.Join(sourceDir, repo.Name()+".sources"),
 		SourceContent: sourceContent.Bytes(),
 		KeyPath:       keyPath,
+		KeyURL:        repo.SignedKey.URL,
 		KeyContent:    keyContent,
 	}, nil
 }
 
+func aptKeyFileName(repo pgxman.AptRepository) string {
+	return repo.Name() + "." + string(repo.SignedKey.Format)
+}
+
 func downloadURL(url string) ([]byte, error) {
 	resp, err := http.Get(url)
 	if err != nil {
@@ -345,9 +758,23 @@ func downloadURL(url string) ([]byte, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+
 	return io.ReadAll(resp.Body)
 }
 
+// isSourceChanged reports whether the source file or the key of an apt source differs from the one on disk.
+func isSourceChanged(file AptSource) (bool, error) {
+	diff, err := isFileDifferent(file.SourcePath, file.SourceContent)
+	if err != nil || diff {
+		return diff, err
+	}
+
+	return isFileDifferent(file.KeyPath, file.KeyContent)
+}
+
 func isFileDifferent(path string, content []byte) (bool, error) {
 	c, err := os.ReadFile(path)
 	if err != nil {
@@ -366,10 +793,79 @@ func isFileDifferent(path string, content []byte) (bool, error) {
 
 }
 
+func parseDpkgQuery(out string) []DpkgPackage {
+	var result []DpkgPackage
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+
+		// status is in the format of "WANT ERROR STATUS", e.g. "hold ok installed"
+		status := strings.Fields(fields[3])
+		if len(status) != 3 || status[2] != "installed" {
+			continue
+		}
+
+		result = append(result, DpkgPackage{
+			Pkg:     fields[0],
+			Version: fields[1],
+			Arch:    fields[2],
+			Held:    status[0] == "hold",
+		})
+	}
+
+	return result
+}
+
+// parsePurgedDebPkgs returns the names of the packages that a simulated apt-get purge removes.
+func parsePurgedDebPkgs(out string) []string {
+	var names []string
+	for _, m := range regexpPurgedDebPkg.FindAllStringSubmatch(out, -1) {
+		names = append(names, m[1])
+	}
+
+	return names
+}
+
 func conflictDebPkg(out string) bool {
 	return regexpConflictDebPkg.MatchString(out)
 }
 
+// canOverwriteConflict reports whether the packages that failed to unpack because of a conflict are allowed to overwrite files.
+// All packages must allow overwriting if the failed packages can't be determined from the output.
+func canOverwriteConflict(out string, pkgs []AptPackage) bool {
+	var failed []AptPackage
+	for _, m := range regexpErrorDebPkg.FindAllStringSubmatch(out, -1) {
+		name := debFilePkgName(m[1])
+		for _, pkg := range pkgs {
+			if pkg.Name() == name || pkg.Pkg == m[1] {
+				failed = append(failed, pkg)
+			}
+		}
+	}
+	if len(failed) == 0 {
+		failed = pkgs
+	}
+
+	for _, pkg := range failed {
+		if !pkg.Overwrite {
+			return false
+		}
+	}
+
+	return true
+}
+
+func aptPkgNames(pkgs []AptPackage) []string {
+	var names []string
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Pkg)
+	}
+
+	return names
+}
+
 func writeFile(path string, content []byte) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
@@ -384,22 +880,8 @@ func coreAptRepos() ([]pgxman.AptRepository, error) {
 		return nil, fmt.Errorf("detect platform: %s", err)
 	}
 
-	var (
-		prefix   string
-		codename string
-	)
-
-	switch p {
-	case pgxman.PlatformDebianBookworm:
-		prefix = "debian"
-		codename = "bookworm"
-	case pgxman.PlatformUbuntuJammy:
-		prefix = "ubuntu"
-		codename = "jammy"
-	case pgxman.PlatformUbuntuNoble:
-		prefix = "ubuntu"
-		codename = "noble"
-	default:
+	info, ok := p.Info()
+	if !ok || info.AptPrefix == "" {
 		return nil, fmt.Errorf("unsupported platform")
 	}
 
@@ -407,8 +889,8 @@ func coreAptRepos() ([]pgxman.AptRepository, error) {
 		{
 			ID:         "core",
 			Types:      []pgxman.AptRepositoryType{pgxman.AptRepositoryTypeDeb},
-			URIs:       []string{fmt.Sprintf("%s/%s", coreAptSourceURL, prefix)},
-			Suites:     []string{codename},
+			URIs:       []string{fmt.Sprintf("%s/%s", coreAptSourceURL, info.AptPrefix)},
+			Suites:     []string{info.Codename},
 			Components: []string{"main"},
 			SignedKey: pgxman.AptRepositorySignedKey{
 				URL:    coreAptSourceGPGKeyURL,
@@ -419,7 +901,7 @@ func coreAptRepos() ([]pgxman.AptRepository, error) {
 			ID:         "pgdg",
 			Types:      []pgxman.AptRepositoryType{pgxman.AptRepositoryTypeDeb},
 			URIs:       []string{"https://apt.postgresql.org/pub/repos/apt"},
-			Suites:     []string{fmt.Sprintf("%s-pgdg", codename)},
+			Suites:     []string{fmt.Sprintf("%s-pgdg", info.Codename)},
 			Components: []string{"main"},
 			SignedKey: pgxman.AptRepositorySignedKey{
 				URL:    "https://www.postgresql.org/media/keys/ACCC4CF8.asc",
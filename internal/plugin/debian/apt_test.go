@@ -1,10 +1,12 @@
 package debian
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/pgxman/pgxman/internal/log"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -92,3 +94,197 @@ E: Sub-process /usr/bin/dpkg returned an error code (1)
 
 	assert.True(t, conflictDebPkg(out))
 }
+
+func Test_parseDpkgQuery(t *testing.T) {
+	out := "postgresql-15-pgxman-pgvector\t0.5.1\tamd64\thold ok installed\n" +
+		"postgresql-16-pgxman-pg-ivm\t1.7.0\tamd64\tinstall ok installed\n" +
+		"postgresql-14-pgxman-postgis\t3.4.0\tamd64\tdeinstall ok config-files\n"
+
+	assert.Equal(
+		t,
+		[]DpkgPackage{
+			{Pkg: "postgresql-15-pgxman-pgvector", Version: "0.5.1", Arch: "amd64", Held: true},
+			{Pkg: "postgresql-16-pgxman-pg-ivm", Version: "1.7.0", Arch: "amd64", Held: false},
+		},
+		parseDpkgQuery(out),
+	)
+}
+
+func Test_parsePurgedDebPkgs(t *testing.T) {
+	out := `Reading package lists...
+Building dependency tree...
+The following packages will be REMOVED:
+  postgresql-16-pgxman-pgvector* postgresql-16-pgxman-pgvectorscale*
+0 upgraded, 0 newly installed, 2 to remove and 0 not upgraded.
+Purg postgresql-16-pgxman-pgvectorscale [0.2.0]
+Purg postgresql-16-pgxman-pgvector [0.5.1]
+`
+
+	assert.Equal(
+		t,
+		[]string{"postgresql-16-pgxman-pgvectorscale", "postgresql-16-pgxman-pgvector"},
+		parsePurgedDebPkgs(out),
+	)
+}
+
+func Test_canOverwriteConflict(t *testing.T) {
+	out := `dpkg: error processing archive /var/cache/apt/archives/postgresql-15-pgxman-pg-stat-statements_15.5.0_arm64.deb (--unpack):
+ trying to overwrite '/usr/lib/postgresql/15/lib/bitcode/pg_stat_statements/pg_stat_statements.bc', which is also in package postgresql-15 15.5-1.pgdg120+1`
+
+	cases := []struct {
+		Name string
+		Out  string
+		Pkgs []AptPackage
+		Want bool
+	}{
+		{
+			Name: "failed package allows overwrite",
+			Out:  out,
+			Pkgs: []AptPackage{
+				{Pkg: "postgresql-15-pgxman-pgvector=0.5.1"},
+				{Pkg: "postgresql-15-pgxman-pg-stat-statements=15.5.0", Overwrite: true},
+			},
+			Want: true,
+		},
+		{
+			Name: "failed package doesn't allow overwrite",
+			Out:  out,
+			Pkgs: []AptPackage{
+				{Pkg: "postgresql-15-pgxman-pgvector=0.5.1", Overwrite: true},
+				{Pkg: "postgresql-15-pgxman-pg-stat-statements=15.5.0"},
+			},
+			Want: false,
+		},
+		{
+			Name: "unknown failed package",
+			Out:  "trying to overwrite '/usr/lib/postgresql/15/lib/foo.so', which is also in package postgresql-15",
+			Pkgs: []AptPackage{
+				{Pkg: "postgresql-15-pgxman-pgvector=0.5.1", Overwrite: true},
+				{Pkg: "postgresql-15-pgxman-pg-stat-statements=15.5.0"},
+			},
+			Want: false,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert.Equal(t, c.Want, canOverwriteConflict(c.Out, c.Pkgs))
+		})
+	}
+}
+
+func Test_mergePackageOpts(t *testing.T) {
+	cases := []struct {
+		Name string
+		Pkgs []AptPackage
+		Want []string
+	}{
+		{
+			Name: "options with values",
+			Pkgs: []AptPackage{
+				{Pkg: "postgresql-16-pgxman-pgvector", Opts: []string{"-o", "A=1"}},
+				{Pkg: "postgresql-16-pgxman-pg-ivm", Opts: []string{"-o", "B=2"}},
+			},
+			Want: []string{"-o", "A=1", "-o", "B=2"},
+		},
+		{
+			Name: "same options",
+			Pkgs: []AptPackage{
+				{Pkg: "postgresql-16-pgxman-pgvector", Opts: []string{"-o", "A=1"}},
+				{Pkg: "postgresql-16-pgxman-pg-ivm", Opts: []string{"-o", "A=1"}},
+				{Pkg: "postgresql-16-pgxman-pg-hint-plan"},
+			},
+			Want: []string{"-o", "A=1"},
+		},
+		{
+			Name: "no options",
+			Pkgs: []AptPackage{
+				{Pkg: "postgresql-16-pgxman-pgvector"},
+			},
+			Want: nil,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert.Equal(t, c.Want, mergePackageOpts(c.Pkgs))
+		})
+	}
+}
+
+func Test_rollbackActions(t *testing.T) {
+	assert := assert.New(t)
+
+	snap := &aptSnapshot{
+		Pkgs: map[string]DpkgPackage{
+			"postgresql-15-pgxman-pgvector": {Pkg: "postgresql-15-pgxman-pgvector", Version: "0.5.0", Held: true},
+			"postgresql-15-pgxman-postgis":  {Pkg: "postgresql-15-pgxman-postgis", Version: "3.4.0"},
+			"postgresql-15-pgxman-pg-ivm":   {Pkg: "postgresql-15-pgxman-pg-ivm", Version: "1.7.0"},
+			"postgresql-15-pgxman-hll":      {Pkg: "postgresql-15-pgxman-hll", Version: "2.18", Held: true},
+		},
+	}
+	current := map[string]DpkgPackage{
+		"postgresql-15-pgxman-pgvector": {Pkg: "postgresql-15-pgxman-pgvector", Version: "0.5.1"},
+		"postgresql-15-pgxman-pg-ivm":   {Pkg: "postgresql-15-pgxman-pg-ivm", Version: "1.7.0"},
+		"postgresql-15-pgxman-hypopg":   {Pkg: "postgresql-15-pgxman-hypopg", Version: "1.4.0"},
+		"postgresql-15-pgxman-hll":      {Pkg: "postgresql-15-pgxman-hll", Version: "2.18"},
+	}
+
+	restore, remove, held := rollbackActions(snap, current)
+	assert.Equal([]string{"postgresql-15-pgxman-pgvector=0.5.0", "postgresql-15-pgxman-postgis=3.4.0"}, restore)
+	assert.Equal([]string{"postgresql-15-pgxman-hypopg"}, remove)
+	assert.Equal([]string{"postgresql-15-pgxman-hll", "postgresql-15-pgxman-pgvector"}, held)
+}
+
+// fakeAptGet is an apt-get that downloads libc6 as a dependency of the requested packages
+// if the dpkg status of apt is empty, i.e. if libc6 isn't considered installed.
+const fakeAptGet = `#!/bin/sh
+[ "$1" = "update" ] && exit 0
+
+status=""
+archives=""
+pkgs=""
+for arg in "$@"; do
+  case "$arg" in
+    Dir::State::status=*) status="${arg#*=}" ;;
+    Dir::Cache::archives=*) archives="${arg#*=}" ;;
+    -*|install|Dir::*) ;;
+    *) pkgs="$pkgs $arg" ;;
+  esac
+done
+
+for pkg in $pkgs; do
+  touch "$archives/${pkg%%=*}_1.0_amd64.deb"
+done
+if [ -n "$status" ] && [ ! -s "$status" ]; then
+  touch "$archives/libc6_2.36_amd64.deb"
+fi
+`
+
+func TestApt_Download(t *testing.T) {
+	if _, err := os.Stat(aptSourceDir); err != nil {
+		t.Skip("apt is not available")
+	}
+
+	assert := assert.New(t)
+
+	bin := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(bin, "apt-get"), []byte(fakeAptGet), 0755))
+	t.Setenv("PATH", bin+string(filepath.ListSeparator)+os.Getenv("PATH"))
+
+	a := &Apt{Logger: log.NewTextLogger()}
+	dir := t.TempDir()
+	assert.NoError(a.Download(context.Background(), []AptPackage{{Pkg: "postgresql-16-pgxman-pgvector=0.5.1"}}, dir))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(err)
+
+	var files []string
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	// dependencies that are installed on the host are downloaded
+	assert.Equal([]string{"libc6_2.36_amd64.deb", "postgresql-16-pgxman-pgvector_1.0_amd64.deb"}, files)
+}
@@ -0,0 +1,398 @@
+package debian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mholt/archiver/v3"
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/iostreams"
+)
+
+const (
+	bundleAPIVersion   = "v1"
+	bundleManifestFile = "manifest.json"
+	bundleDebsDir      = "debs"
+	bundleKeyRingsDir  = "keyrings"
+)
+
+// bundleManifest describes the content of a bundle.
+// A bundle is a tar archive in the following structure:
+//
+//   - manifest.json
+//   - debs
+//     -- postgresql-15-pgxman-pgvector_0.5.0_amd64.deb
+//     -- ...
+//   - keyrings
+//     -- pgxman-core.gpg
+//     -- ...
+type bundleManifest struct {
+	APIVersion   string                 `json:"apiVersion"`
+	Platform     pgxman.Platform        `json:"platform"`
+	Extensions   []bundleExtension      `json:"extensions"`
+	Packages     []string               `json:"packages"`
+	Repositories []pgxman.AptRepository `json:"repositories"`
+}
+
+type bundleExtension struct {
+	pgxman.PackExtension
+	PGVersion pgxman.PGVersion `json:"pgVersion"`
+	// Package is the file name of the extension package in the debs directory
+	Package                string   `json:"package"`
+	SharedPreloadLibraries []string `json:"sharedPreloadLibraries,omitempty"`
+}
+
+func (m bundleManifest) Validate(p pgxman.Platform) error {
+	if m.APIVersion != bundleAPIVersion {
+		return fmt.Errorf("invalid bundle api version: %s", m.APIVersion)
+	}
+
+	if m.Platform != p {
+		return fmt.Errorf("bundle is created for %s but the platform is %s", m.Platform, p)
+	}
+
+	return nil
+}
+
+func (i *DebianInstaller) Bundle(ctx context.Context, exts []pgxman.InstallExtension, dst string) error {
+	i.Logger.Debug("Bundling extensions", "extensions", exts, "dst", dst)
+
+	if err := checkRootAccess(); err != nil {
+		return err
+	}
+
+	tar := archiver.NewTar()
+	tar.OverwriteExisting = true
+	if err := tar.CheckExt(dst); err != nil {
+		return err
+	}
+
+	p, err := pgxman.DetectPlatform()
+	if err != nil {
+		return fmt.Errorf("detect platform: %s", err)
+	}
+
+	dir, err := os.MkdirTemp("", "pgxman-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		debsDir     = filepath.Join(dir, bundleDebsDir)
+		keyRingsDir = filepath.Join(dir, bundleKeyRingsDir)
+	)
+	for _, d := range []string{debsDir, keyRingsDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return err
+		}
+	}
+
+	var (
+		aptPkgs  []AptPackage
+		manifest = bundleManifest{
+			APIVersion: bundleAPIVersion,
+			Platform:   p,
+		}
+	)
+	for _, ext := range exts {
+		if err := ext.Validate(); err != nil {
+			return err
+		}
+
+		aptPkg, err := newAptPackage(ext)
+		if err != nil {
+			return err
+		}
+
+		aptPkgs = append(aptPkgs, aptPkg)
+	}
+
+	apt, err := NewApt(i.Logger.WithGroup("apt"))
+	if err != nil {
+		return err
+	}
+
+	if err := apt.Download(ctx, aptPkgs, debsDir); err != nil {
+		return err
+	}
+
+	for idx, aptPkg := range aptPkgs {
+		ext := exts[idx]
+
+		var pkgFile string
+		if aptPkg.IsLocal {
+			pkgFile = filepath.Base(aptPkg.Pkg)
+			if err := copyFile(aptPkg.Pkg, filepath.Join(debsDir, pkgFile)); err != nil {
+				return err
+			}
+
+			ext.Path = pkgFile
+		} else {
+			pkgFile, err = findDebFile(debsDir, aptPkg.Name())
+			if err != nil {
+				return err
+			}
+		}
+
+		manifest.Extensions = append(manifest.Extensions, bundleExtension{
+			PackExtension:          ext.PackExtension,
+			PGVersion:              ext.PGVersion,
+			Package:                pkgFile,
+			SharedPreloadLibraries: ext.SharedPreloadLibraries,
+		})
+	}
+
+	repos := make(map[string]pgxman.AptRepository)
+	for _, aptPkg := range aptPkgs {
+		for _, repo := range aptPkg.Repos {
+			repos[repo.Name()] = repo
+		}
+	}
+	for _, repo := range repos {
+		i.Logger.Debug("Downloading gpg key", "url", repo.SignedKey)
+		keyContent, err := downloadKey(repo)
+		if err != nil {
+			return err
+		}
+
+		if err := writeFile(filepath.Join(keyRingsDir, aptKeyFileName(repo)), keyContent); err != nil {
+			return err
+		}
+
+		manifest.Repositories = append(manifest.Repositories, repo)
+	}
+	sort.Slice(manifest.Repositories, func(i, j int) bool {
+		return manifest.Repositories[i].Name() < manifest.Repositories[j].Name()
+	})
+
+	debs, err := os.ReadDir(debsDir)
+	if err != nil {
+		return err
+	}
+	for _, deb := range debs {
+		manifest.Packages = append(manifest.Packages, deb.Name())
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, bundleManifestFile), b); err != nil {
+		return err
+	}
+
+	return tar.Archive(
+		[]string{
+			filepath.Join(dir, bundleManifestFile),
+			debsDir,
+			keyRingsDir,
+		},
+		dst,
+	)
+}
+
+func (i *DebianInstaller) PreInstallBundleCheck(ctx context.Context, src string, io *iostreams.IOStreams) error {
+	if err := checkRootAccess(); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "pgxman-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	aptPkgs, aptSources, _, _, err := i.bundlePlan(src, dir)
+	if err != nil {
+		return err
+	}
+
+	// show the file names instead of the extracted paths
+	var debPkgs []AptPackage
+	for _, aptPkg := range aptPkgs {
+		debPkgs = append(debPkgs, AptPackage{Pkg: filepath.Base(aptPkg.Pkg)})
+	}
+
+	return promptInstallOrUpgrade(io, debPkgs, aptSources, false)
+}
+
+func (i *DebianInstaller) InstallBundle(ctx context.Context, src string) ([]pgxman.InstallExtension, error) {
+	i.Logger.Debug("Installing bundle", "src", src)
+
+	if err := checkRootAccess(); err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "pgxman-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	aptPkgs, aptSources, heldPkgs, exts, err := i.bundlePlan(src, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	apt, err := NewApt(i.Logger.WithGroup("apt"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := apt.InstallOffline(ctx, aptPkgs, aptSources, heldPkgs); err != nil {
+		return nil, err
+	}
+
+	return exts, nil
+}
+
+// bundlePlan extracts the bundle src into dir and returns the local apt packages to install,
+// the apt sources that will be added or updated, the packages to hold, and the extensions in the bundle.
+func (i *DebianInstaller) bundlePlan(src, dir string) ([]AptPackage, []AptSource, []AptPackage, []pgxman.InstallExtension, error) {
+	if err := archiver.NewTar().Unarchive(src, dir); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("extract bundle: %w", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, bundleManifestFile))
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("read bundle manifest: %w", err)
+	}
+
+	var manifest bundleManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+
+	p, err := pgxman.DetectPlatform()
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("detect platform: %s", err)
+	}
+	if err := manifest.Validate(p); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	var (
+		extPkgs = make(map[string]bundleExtension)
+		exts    []pgxman.InstallExtension
+	)
+	for _, ext := range manifest.Extensions {
+		extPkgs[ext.Package] = ext
+		exts = append(exts, pgxman.InstallExtension{
+			PackExtension:          ext.PackExtension,
+			PGVersion:              ext.PGVersion,
+			SharedPreloadLibraries: ext.SharedPreloadLibraries,
+		})
+	}
+
+	var (
+		aptPkgs  []AptPackage
+		heldPkgs []AptPackage
+	)
+	for _, pkg := range manifest.Packages {
+		ext := pgxman.InstallExtension{
+			PackExtension: pgxman.PackExtension{
+				Path: filepath.Join(dir, bundleDebsDir, pkg),
+			},
+		}
+		if bext, ok := extPkgs[pkg]; ok {
+			ext.Options = bext.Options
+			ext.Overwrite = bext.Overwrite
+
+			// extensions from the registry are held as if they were installed from apt repositories
+			if bext.Name != "" {
+				heldPkgs = append(heldPkgs, AptPackage{Pkg: debFilePkgName(pkg)})
+			}
+		}
+
+		aptPkg, err := newAptPackage(ext)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		aptPkgs = append(aptPkgs, aptPkg)
+	}
+
+	apt, err := NewApt(i.Logger.WithGroup("apt"))
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	var aptSources []AptSource
+	for _, repo := range manifest.Repositories {
+		uris := apt.removeDuplicatedSourceHosts(repo.URIs)
+		if len(uris) == 0 {
+			i.Logger.Debug("Skipping apt source that already exists", "name", repo.Name())
+			continue
+		}
+		repo.URIs = uris
+
+		keyContent, err := os.ReadFile(filepath.Join(dir, bundleKeyRingsDir, aptKeyFileName(repo)))
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("read bundle keyring: %w", err)
+		}
+
+		file, err := newAptSource(repo, keyContent, aptSourceDir, aptKeyRingsDir)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		changed, err := isSourceChanged(*file)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if changed {
+			aptSources = append(aptSources, *file)
+		}
+	}
+
+	return aptPkgs, aptSources, heldPkgs, exts, nil
+}
+
+// findDebFile returns the file name of the package in dir.
+func findDebFile(dir, name string) (string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		if debFilePkgName(file.Name()) == name {
+			return file.Name(), nil
+		}
+	}
+
+	return "", fmt.Errorf("package %s is not downloaded", name)
+}
+
+// debFilePkgName returns the package name of a deb file in the format of NAME_VERSION_ARCH.deb.
+func debFilePkgName(file string) string {
+	name, _, _ := strings.Cut(filepath.Base(file), "_")
+	return name
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,44 @@
+package debian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_bundleManifest_Validate(t *testing.T) {
+	assert := assert.New(t)
+
+	m := bundleManifest{
+		APIVersion: bundleAPIVersion,
+		Platform:   pgxman.PlatformDebianBookworm,
+	}
+	assert.NoError(m.Validate(pgxman.PlatformDebianBookworm))
+	assert.EqualError(m.Validate(pgxman.PlatformUbuntuJammy), "bundle is created for debian_bookworm but the platform is ubuntu_jammy")
+
+	m.APIVersion = "v2"
+	assert.EqualError(m.Validate(pgxman.PlatformDebianBookworm), "invalid bundle api version: v2")
+}
+
+func Test_findDebFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	for _, f := range []string{
+		"postgresql-15-pgxman-pgvector_0.5.0_amd64.deb",
+		"postgresql-15-pgxman-pgvector-extra_1.0.0_amd64.deb",
+		"libgeos-c1v5_3.11.1-1_amd64.deb",
+	} {
+		assert.NoError(os.WriteFile(filepath.Join(dir, f), nil, 0644))
+	}
+
+	got, err := findDebFile(dir, "postgresql-15-pgxman-pgvector")
+	assert.NoError(err)
+	assert.Equal("postgresql-15-pgxman-pgvector_0.5.0_amd64.deb", got)
+
+	_, err = findDebFile(dir, "postgresql-15-pgxman-postgis")
+	assert.EqualError(err, "package postgresql-15-pgxman-postgis is not downloaded")
+}
@@ -5,6 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/eiannone/keyboard"
@@ -17,12 +21,12 @@ type DebianInstaller struct {
 	Logger *log.Logger
 }
 
-func (i *DebianInstaller) Install(ctx context.Context, ext pgxman.InstallExtension) error {
-	return i.installOrUpgrade(ctx, ext, false)
+func (i *DebianInstaller) Install(ctx context.Context, exts []pgxman.InstallExtension) error {
+	return i.installOrUpgrade(ctx, exts, false)
 }
 
-func (i *DebianInstaller) Upgrade(ctx context.Context, ext pgxman.InstallExtension) error {
-	return i.installOrUpgrade(ctx, ext, true)
+func (i *DebianInstaller) Upgrade(ctx context.Context, exts []pgxman.InstallExtension) error {
+	return i.installOrUpgrade(ctx, exts, true)
 }
 
 func (i *DebianInstaller) PreInstallCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams) error {
@@ -33,27 +37,74 @@ func (i *DebianInstaller) PreUpgradeCheck(ctx context.Context, exts []pgxman.Ins
 	return i.installOrUpgradeCheck(ctx, exts, io, true)
 }
 
-func (i DebianInstaller) installOrUpgradeCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams, upgrade bool) error {
+func (i *DebianInstaller) Uninstall(ctx context.Context, exts []pgxman.InstallExtension) error {
+	i.Logger.Debug("Uninstalling extensions", "extensions", exts)
+
 	if err := checkRootAccess(); err != nil {
 		return err
 	}
 
-	var (
-		aptPkgs  []AptPackage
-		aptRepos []pgxman.AptRepository
-	)
-	for _, extToInstall := range exts {
-		if err := extToInstall.Validate(); err != nil {
+	var aptPkgs []AptPackage
+	for _, ext := range exts {
+		if err := ext.Validate(); err != nil {
 			return err
 		}
 
-		aptPkg, err := newAptPackage(extToInstall)
-		if err != nil {
+		aptPkgs = append(aptPkgs, newUninstallAptPackage(ext))
+	}
+
+	apt, err := NewApt(i.Logger.WithGroup("apt"))
+	if err != nil {
+		return err
+	}
+
+	return apt.Uninstall(ctx, aptPkgs)
+}
+
+// Sync upgrades the extensions and purges the extensions to remove with a single apt invocation.
+func (i *DebianInstaller) Sync(ctx context.Context, exts []pgxman.InstallExtension, remove []pgxman.InstallExtension) error {
+	i.Logger.Debug("Syncing extensions", "extensions", exts, "remove", remove)
+
+	if err := checkRootAccess(); err != nil {
+		return err
+	}
+
+	aptPkgs, aptSources, err := i.plan(ctx, exts)
+	if err != nil {
+		return err
+	}
+
+	var removePkgs []AptPackage
+	for _, ext := range remove {
+		if err := ext.Validate(); err != nil {
 			return err
 		}
 
-		aptPkgs = append(aptPkgs, aptPkg)
-		aptRepos = append(aptRepos, aptPkg.Repos...)
+		removePkgs = append(removePkgs, newUninstallAptPackage(ext))
+	}
+
+	apt, err := NewApt(i.Logger.WithGroup("apt"))
+	if err != nil {
+		return err
+	}
+
+	return apt.Sync(ctx, aptPkgs, aptSources, removePkgs)
+}
+
+// PreUninstallCheck asks to confirm the packages to remove, including the installed packages that depend on them
+// and are removed too. Without a terminal, removing packages that are not requested is refused.
+func (i *DebianInstaller) PreUninstallCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams) error {
+	if err := checkRootAccess(); err != nil {
+		return err
+	}
+
+	var aptPkgs []AptPackage
+	for _, extToUninstall := range exts {
+		if err := extToUninstall.Validate(); err != nil {
+			return err
+		}
+
+		aptPkgs = append(aptPkgs, newUninstallAptPackage(extToUninstall))
 	}
 
 	if len(aptPkgs) == 0 {
@@ -65,51 +116,247 @@ func (i DebianInstaller) installOrUpgradeCheck(ctx context.Context, exts []pgxma
 		return err
 	}
 
-	aptSources, err := apt.GetChangedSources(ctx, aptRepos)
+	// packages that are not installed are reported by Uninstall
+	var installed []AptPackage
+	for _, pkg := range aptPkgs {
+		ok, err := apt.isInstalled(ctx, pkg)
+		if err != nil {
+			return err
+		}
+		if ok {
+			installed = append(installed, pkg)
+		}
+	}
+
+	dependents, err := apt.ReverseDependencies(ctx, installed)
 	if err != nil {
 		return err
 	}
 
-	return promptInstallOrUpgrade(io, aptPkgs, aptSources, upgrade)
+	return promptUninstall(io, aptPkgs, dependents)
+}
+
+func (i *DebianInstaller) List(ctx context.Context) ([]pgxman.InstalledExtension, error) {
+	apt, err := NewApt(i.Logger.WithGroup("apt"))
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs, err := apt.ListInstalled(ctx, extensionDebPkg("*", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []pgxman.InstalledExtension
+	for _, pkg := range pkgs {
+		ext, ok := parseExtensionDebPkg(pkg)
+		if !ok {
+			i.Logger.Debug("Skipping unrecognized package", "package", pkg.Pkg)
+			continue
+		}
+
+		result = append(result, ext)
+	}
+
+	return result, nil
+}
+
+// SQLExtensions returns the SQL extensions of the control files in the installed package of the extension.
+func (i *DebianInstaller) SQLExtensions(ctx context.Context, ext pgxman.InstallExtension) ([]string, error) {
+	apt, err := NewApt(i.Logger.WithGroup("apt"))
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := AptPackage{
+		Pkg: extensionDebPkg(string(ext.PGVersion), ext.Name),
+	}
+	if ext.Path != "" {
+		pkg = AptPackage{
+			Pkg:     ext.Path,
+			IsLocal: true,
+		}
+	}
+
+	name, err := apt.debPkgName(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := apt.ListFiles(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlExtensionNames(files), nil
 }
 
-func (i DebianInstaller) installOrUpgrade(ctx context.Context, ext pgxman.InstallExtension, upgrade bool) error {
-	i.Logger.Debug("Installing extension", "extension", ext)
+func (i *DebianInstaller) Plan(ctx context.Context, exts []pgxman.InstallExtension) (*pgxman.InstallPlan, error) {
+	aptPkgs, aptSources, err := i.plan(ctx, exts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newInstallPlan(exts, aptPkgs, aptSources), nil
+}
+
+// newInstallPlan returns the plan of the apt packages of the extensions in the same order and the apt sources.
+func newInstallPlan(exts []pgxman.InstallExtension, aptPkgs []AptPackage, aptSources []AptSource) *pgxman.InstallPlan {
+	plan := &pgxman.InstallPlan{}
+	for idx, aptPkg := range aptPkgs {
+		ext := exts[idx]
+		plan.Packages = append(plan.Packages, pgxman.PlannedPackage{
+			Name:       ext.Name,
+			Version:    ext.Version,
+			Path:       ext.Path,
+			PGVersion:  ext.PGVersion,
+			Package:    aptPkg.Pkg,
+			RequiredBy: ext.RequiredBy,
+		})
+	}
+	for _, source := range aptSources {
+		plan.Sources = append(plan.Sources, pgxman.PlannedSource{
+			Name:          source.Name,
+			SourcePath:    source.SourcePath,
+			SourceContent: string(source.SourceContent),
+			KeyPath:       source.KeyPath,
+			KeyURL:        source.KeyURL,
+		})
+	}
+
+	return plan
+}
 
+func (i DebianInstaller) installOrUpgradeCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams, upgrade bool) error {
 	if err := checkRootAccess(); err != nil {
 		return err
 	}
 
-	if err := ext.Validate(); err != nil {
+	aptPkgs, aptSources, err := i.plan(ctx, exts)
+	if err != nil {
 		return err
 	}
 
-	aptPkg, err := newAptPackage(ext)
+	if len(aptPkgs) == 0 {
+		return nil
+	}
+
+	return promptInstallOrUpgrade(io, aptPkgs, aptSources, upgrade)
+}
+
+// plan returns the apt packages in the order of the extensions and the apt sources that will be added or updated.
+// It doesn't change the system.
+func (i DebianInstaller) plan(ctx context.Context, exts []pgxman.InstallExtension) ([]AptPackage, []AptSource, error) {
+	var (
+		aptPkgs  []AptPackage
+		aptRepos []pgxman.AptRepository
+	)
+	for _, ext := range exts {
+		if err := ext.Validate(); err != nil {
+			return nil, nil, err
+		}
+
+		aptPkg, err := newAptPackage(ext)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		aptPkgs = append(aptPkgs, aptPkg)
+		aptRepos = append(aptRepos, aptPkg.Repos...)
+	}
+
+	if len(aptPkgs) == 0 {
+		return nil, nil, nil
+	}
+
+	apt, err := NewApt(i.Logger.WithGroup("apt"))
 	if err != nil {
+		return nil, nil, err
+	}
+
+	aptSources, err := apt.GetChangedSources(ctx, aptRepos)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aptPkgs, aptSources, nil
+}
+
+func (i DebianInstaller) installOrUpgrade(ctx context.Context, exts []pgxman.InstallExtension, upgrade bool) error {
+	i.Logger.Debug("Installing extensions", "extensions", exts)
+
+	if err := checkRootAccess(); err != nil {
 		return err
 	}
 
-	apt, err := NewApt(i.Logger.WithGroup("apt"))
+	aptPkgs, aptSources, err := i.plan(ctx, exts)
 	if err != nil {
 		return err
 	}
 
-	aptSources, err := apt.GetChangedSources(ctx, aptPkg.Repos)
+	apt, err := NewApt(i.Logger.WithGroup("apt"))
 	if err != nil {
 		return err
 	}
 
 	if upgrade {
-		return apt.Upgrade(ctx, []AptPackage{aptPkg}, aptSources)
+		return apt.Upgrade(ctx, aptPkgs, aptSources)
 	}
 
-	return apt.Install(ctx, []AptPackage{aptPkg}, aptSources)
+	return apt.Install(ctx, aptPkgs, aptSources)
 }
 
 func extDebPkgName(ext pgxman.InstallExtension) string {
 	return fmt.Sprintf("postgresql-%s-pgxman-%s=%s", ext.PGVersion, debNormalizedName(ext.Name), ext.Version)
 }
 
+func newUninstallAptPackage(ext pgxman.InstallExtension) AptPackage {
+	return AptPackage{
+		Pkg:  extensionDebPkg(string(ext.PGVersion), ext.Name),
+		Opts: ext.Options,
+	}
+}
+
+var (
+	regexpExtensionDebPkg = regexp.MustCompile(`^postgresql-(\d+)-pgxman-(.+)$`)
+)
+
+// parseExtensionDebPkg converts an installed Debian package into an extension.
+// The name is the normalized Debian name of the extension, see debNormalizedName.
+func parseExtensionDebPkg(pkg DpkgPackage) (pgxman.InstalledExtension, bool) {
+	match := regexpExtensionDebPkg.FindStringSubmatch(pkg.Pkg)
+	if len(match) == 0 {
+		return pgxman.InstalledExtension{}, false
+	}
+
+	return pgxman.InstalledExtension{
+		Name:      match[2],
+		Version:   pkg.Version,
+		PGVersion: pgxman.PGVersion(match[1]),
+		Arch:      pkg.Arch,
+		Package:   pkg.Pkg,
+		Held:      pkg.Held,
+	}, true
+}
+
+// sqlExtensionNames returns the names of the extension control files, e.g. /usr/share/postgresql/15/extension/vector.control.
+func sqlExtensionNames(files []string) []string {
+	var names []string
+	for _, file := range files {
+		if filepath.Ext(file) != ".control" || filepath.Base(filepath.Dir(file)) != "extension" {
+			continue
+		}
+
+		name := strings.TrimSuffix(filepath.Base(file), ".control")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
 func checkRootAccess() error {
 	if os.Getuid() != 0 {
 		return pgxman.ErrRootAccessRequired
@@ -135,10 +382,11 @@ func newAptPackage(ext pgxman.InstallExtension) (AptPackage, error) {
 		}
 	} else {
 		aptPkg = AptPackage{
-			Pkg:       extDebPkgName(ext),
-			Opts:      ext.Options,
-			Repos:     coreAptRepos,
-			Overwrite: ext.Overwrite,
+			Pkg:        extDebPkgName(ext),
+			Opts:       ext.Options,
+			Repos:      coreAptRepos,
+			Overwrite:  ext.Overwrite,
+			RequiredBy: ext.RequiredBy,
 		}
 
 		aptPkg.Repos = append(aptPkg.Repos, ext.AptRepositories...)
@@ -165,13 +413,20 @@ func promptInstallOrUpgrade(io *iostreams.IOStreams, debPkgs []AptPackage, sourc
 		fmt.Sprintf("The following Debian packages will be %s:", action),
 	}
 	for _, debPkg := range debPkgs {
-		out = append(out, "  "+debPkg.Pkg)
+		if debPkg.RequiredBy != "" {
+			out = append(out, fmt.Sprintf("  %s (required by %s)", debPkg.Pkg, debPkg.RequiredBy))
+		} else {
+			out = append(out, "  "+debPkg.Pkg)
+		}
 	}
 
 	if len(sources) > 0 {
 		out = append(out, "The following Apt repositories will be added or updated:")
 		for _, source := range sources {
 			out = append(out, "  "+source.Name)
+			if from, to, ok := keyChange(source); ok {
+				out = append(out, fmt.Sprintf("    WARNING: the signing key changes from %s to %s", formatFingerprints(from), formatFingerprints(to)))
+			}
 		}
 	}
 
@@ -188,3 +443,39 @@ func promptInstallOrUpgrade(io *iostreams.IOStreams, debPkgs []AptPackage, sourc
 
 	return nil
 }
+
+func promptUninstall(io *iostreams.IOStreams, debPkgs []AptPackage, dependents []AptPackage) error {
+	if !io.IsTerminal() {
+		if len(dependents) > 0 {
+			return fmt.Errorf("%s depend on the packages to uninstall and would be removed too, run with `--yes` to uninstall them", strings.Join(aptPkgNames(dependents), ", "))
+		}
+
+		return nil
+	}
+
+	out := []string{
+		"The following Debian packages will be removed:",
+	}
+	for _, debPkg := range debPkgs {
+		out = append(out, "  "+debPkg.Pkg)
+	}
+	if len(dependents) > 0 {
+		out = append(out, "The following Debian packages depend on them and will be removed too:")
+		for _, debPkg := range dependents {
+			out = append(out, "  "+debPkg.Pkg)
+		}
+	}
+
+	out = append(out, "Do you want to continue? [Y/n]")
+
+	err := io.Prompt(strings.Join(out, "\n"), []rune{'y', 'Y'}, []keyboard.Key{keyboard.KeyEnter})
+	if err != nil {
+		if errors.Is(err, iostreams.ErrAbortPrompt) {
+			return fmt.Errorf("uninstallation aborted")
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package debian
+
+import (
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseExtensionDebPkg(t *testing.T) {
+	assert := assert.New(t)
+
+	ext, ok := parseExtensionDebPkg(DpkgPackage{Pkg: "postgresql-15-pgxman-pg-ivm", Version: "1.7.0", Arch: "arm64", Held: true})
+	assert.True(ok)
+	assert.Equal(
+		pgxman.InstalledExtension{
+			Name:      "pg-ivm",
+			Version:   "1.7.0",
+			PGVersion: pgxman.PGVersion15,
+			Arch:      "arm64",
+			Package:   "postgresql-15-pgxman-pg-ivm",
+			Held:      true,
+		},
+		ext,
+	)
+
+	_, ok = parseExtensionDebPkg(DpkgPackage{Pkg: "postgresql-15"})
+	assert.False(ok)
+}
+
+func Test_sqlExtensionNames(t *testing.T) {
+	files := []string{
+		"/usr/share/postgresql/15/extension",
+		"/usr/share/postgresql/15/extension/postgis.control",
+		"/usr/share/postgresql/15/extension/postgis--3.4.0.sql",
+		"/usr/share/postgresql/15/extension/postgis_raster.control",
+		"/usr/share/doc/postgresql-15-pgxman-postgis/README.control",
+		"/usr/share/postgresql/15/extension/postgis.control",
+	}
+
+	assert.Equal(t, []string{"postgis", "postgis_raster"}, sqlExtensionNames(files))
+}
+
+func Test_newInstallPlan(t *testing.T) {
+	pgvector := pgxman.InstallExtension{
+		PackExtension: pgxman.PackExtension{
+			Name:    "pgvector",
+			Version: "0.5.1",
+		},
+		PGVersion: pgxman.PGVersion16,
+	}
+	pgIVM := pgxman.InstallExtension{
+		PackExtension: pgxman.PackExtension{
+			Name:    "pg_ivm",
+			Version: "1.7.0",
+		},
+		PGVersion:  pgxman.PGVersion16,
+		RequiredBy: "pgvector",
+	}
+	hypopg := pgxman.InstallExtension{
+		PackExtension: pgxman.PackExtension{
+			Path: "/PATH_TO/postgresql-16-pgxman-hypopg_1.4.0_amd64.deb",
+		},
+		PGVersion: pgxman.PGVersion16,
+	}
+
+	repo := pgxman.AptRepository{
+		ID:         "apache-arrow-debian-bookworm",
+		Types:      []pgxman.AptRepositoryType{pgxman.AptRepositoryTypeDeb},
+		URIs:       []string{"https://apache.jfrog.io/artifactory/arrow/debian/"},
+		Suites:     []string{"bookworm"},
+		Components: []string{"main"},
+		SignedKey: pgxman.AptRepositorySignedKey{
+			URL:    "https://downloads.apache.org/arrow/KEYS",
+			Format: pgxman.AptRepositorySignedKeyFormatAsc,
+		},
+	}
+	source, err := newAptSource(repo, []byte(testKeyAsc), "/etc/apt/sources.list.d", "/usr/share/keyrings")
+	assert.NoError(t, err)
+
+	cases := []struct {
+		Name       string
+		Exts       []pgxman.InstallExtension
+		AptSources []AptSource
+		Want       *pgxman.InstallPlan
+	}{
+		{
+			Name: "registry extension",
+			Exts: []pgxman.InstallExtension{pgvector},
+			Want: &pgxman.InstallPlan{
+				Packages: []pgxman.PlannedPackage{
+					{
+						Name:      "pgvector",
+						Version:   "0.5.1",
+						PGVersion: pgxman.PGVersion16,
+						Package:   "postgresql-16-pgxman-pgvector=0.5.1",
+					},
+				},
+			},
+		},
+		{
+			Name: "extension dependency",
+			Exts: []pgxman.InstallExtension{pgvector, pgIVM},
+			Want: &pgxman.InstallPlan{
+				Packages: []pgxman.PlannedPackage{
+					{
+						Name:      "pgvector",
+						Version:   "0.5.1",
+						PGVersion: pgxman.PGVersion16,
+						Package:   "postgresql-16-pgxman-pgvector=0.5.1",
+					},
+					{
+						Name:       "pg_ivm",
+						Version:    "1.7.0",
+						PGVersion:  pgxman.PGVersion16,
+						Package:    "postgresql-16-pgxman-pg-ivm=1.7.0",
+						RequiredBy: "pgvector",
+					},
+				},
+			},
+		},
+		{
+			Name: "local package",
+			Exts: []pgxman.InstallExtension{hypopg},
+			Want: &pgxman.InstallPlan{
+				Packages: []pgxman.PlannedPackage{
+					{
+						Path:      "/PATH_TO/postgresql-16-pgxman-hypopg_1.4.0_amd64.deb",
+						PGVersion: pgxman.PGVersion16,
+						Package:   "/PATH_TO/postgresql-16-pgxman-hypopg_1.4.0_amd64.deb",
+					},
+				},
+			},
+		},
+		{
+			Name:       "apt sources",
+			Exts:       []pgxman.InstallExtension{pgvector},
+			AptSources: []AptSource{*source},
+			Want: &pgxman.InstallPlan{
+				Packages: []pgxman.PlannedPackage{
+					{
+						Name:      "pgvector",
+						Version:   "0.5.1",
+						PGVersion: pgxman.PGVersion16,
+						Package:   "postgresql-16-pgxman-pgvector=0.5.1",
+					},
+				},
+				Sources: []pgxman.PlannedSource{
+					{
+						Name:       "pgxman-apache-arrow-debian-bookworm",
+						SourcePath: "/etc/apt/sources.list.d/pgxman-apache-arrow-debian-bookworm.sources",
+						SourceContent: `Types: deb
+URIs: https://apache.jfrog.io/artifactory/arrow/debian/
+Suites: bookworm
+Components: main
+Signed-By: /usr/share/keyrings/pgxman-apache-arrow-debian-bookworm.asc
+`,
+						KeyPath: "/usr/share/keyrings/pgxman-apache-arrow-debian-bookworm.asc",
+						KeyURL:  "https://downloads.apache.org/arrow/KEYS",
+					},
+				},
+			},
+		},
+		{
+			Name: "no extensions",
+			Want: &pgxman.InstallPlan{},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var aptPkgs []AptPackage
+			for _, ext := range c.Exts {
+				pkg := AptPackage{Pkg: extDebPkgName(ext)}
+				if ext.Path != "" {
+					pkg = AptPackage{Pkg: ext.Path, IsLocal: true}
+				}
+
+				aptPkgs = append(aptPkgs, pkg)
+			}
+
+			assert.Equal(c.Want, newInstallPlan(c.Exts, aptPkgs, c.AptSources))
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package debian
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+)
+
+const (
+	pgpArmorBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	pgpArmorEnd   = "-----END PGP PUBLIC KEY BLOCK-----"
+
+	pgpTagPublicKey = 6
+)
+
+// downloadKey downloads the signing key of an apt repository and verifies it against the pinned fingerprint if there is one.
+func downloadKey(repo pgxman.AptRepository) ([]byte, error) {
+	key, err := downloadURL(repo.SignedKey.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyKey(key, repo.SignedKey.NormalizedFingerprint()); err != nil {
+		return nil, fmt.Errorf("signing key of apt repository %s from %s: %w", repo.ID, repo.SignedKey.URL, err)
+	}
+
+	return key, nil
+}
+
+// verifyKey checks that every primary key in the key file has the fingerprint.
+// A key file with additional keys is rejected because apt trusts all keys in a keyring.
+func verifyKey(key []byte, fingerprint string) error {
+	if fingerprint == "" {
+		return nil
+	}
+
+	fps, err := keyFingerprints(key)
+	if err != nil {
+		return err
+	}
+
+	for _, fp := range fps {
+		if fp != fingerprint {
+			return fmt.Errorf("fingerprint mismatch: want %s, got %s", fingerprint, strings.Join(fps, ", "))
+		}
+	}
+
+	return nil
+}
+
+// keyChange returns the fingerprints of the key on disk and of the new key of an apt source
+// if the new key replaces a key with different fingerprints.
+func keyChange(source AptSource) ([]string, []string, bool) {
+	current, err := os.ReadFile(source.KeyPath)
+	if err != nil || bytes.Equal(current, source.KeyContent) {
+		return nil, nil, false
+	}
+
+	from, _ := keyFingerprints(current)
+	to, _ := keyFingerprints(source.KeyContent)
+	if len(from) > 0 && slices.Equal(from, to) {
+		return nil, nil, false
+	}
+
+	return from, to, true
+}
+
+func formatFingerprints(fps []string) string {
+	if len(fps) == 0 {
+		return "unknown"
+	}
+
+	return strings.Join(fps, ", ")
+}
+
+// keyFingerprints returns the fingerprints of the primary keys in an OpenPGP key file
+// in either the ASCII armored (asc) or binary (gpg) format.
+// Ref: https://www.rfc-editor.org/rfc/rfc9580#section-5.5.4
+func keyFingerprints(key []byte) ([]string, error) {
+	var data []byte
+	if bytes.Contains(key, []byte(pgpArmorBegin)) {
+		var err error
+		data, err = decodeArmor(key)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		data = key
+	}
+
+	var fps []string
+	for len(data) > 0 {
+		tag, body, rest, err := readPGPPacket(data)
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+
+		if tag != pgpTagPublicKey {
+			continue
+		}
+
+		fp, err := pgpFingerprint(body)
+		if err != nil {
+			return nil, err
+		}
+		fps = append(fps, fp)
+	}
+
+	if len(fps) == 0 {
+		return nil, errors.New("no OpenPGP public key is found")
+	}
+
+	return fps, nil
+}
+
+// decodeArmor decodes the ASCII armored public key blocks and concatenates them.
+// The armor headers and the checksum are skipped.
+func decodeArmor(key []byte) ([]byte, error) {
+	var (
+		result  []byte
+		body    strings.Builder
+		inBlock bool
+		inBody  bool
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(key))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == pgpArmorBegin:
+			inBlock, inBody = true, false
+			body.Reset()
+		case !inBlock:
+		case line == pgpArmorEnd:
+			b, err := base64.StdEncoding.DecodeString(body.String())
+			if err != nil {
+				return nil, fmt.Errorf("invalid armored key: %w", err)
+			}
+			result = append(result, b...)
+			inBlock = false
+		case !inBody && (line == "" || strings.Contains(line, ": ")):
+			// armor headers end with an empty line
+			inBody = line == ""
+		case strings.HasPrefix(line, "="):
+			// checksum
+		default:
+			inBody = true
+			body.WriteString(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if inBlock {
+		return nil, errors.New("invalid armored key: missing end of block")
+	}
+
+	return result, nil
+}
+
+// readPGPPacket returns the tag and the body of the first packet and the remaining data.
+// Ref: https://www.rfc-editor.org/rfc/rfc9580#section-4.2
+func readPGPPacket(data []byte) (byte, []byte, []byte, error) {
+	errTruncated := errors.New("invalid OpenPGP packet: truncated")
+
+	if data[0]&0x80 == 0 {
+		return 0, nil, nil, errors.New("invalid OpenPGP packet header")
+	}
+
+	var (
+		tag    byte
+		length int
+		n      int
+	)
+	if data[0]&0x40 != 0 {
+		tag = data[0] & 0x3f
+		if len(data) < 2 {
+			return 0, nil, nil, errTruncated
+		}
+
+		switch l := data[1]; {
+		case l < 192:
+			length, n = int(l), 2
+		case l < 224:
+			if len(data) < 3 {
+				return 0, nil, nil, errTruncated
+			}
+			length, n = (int(l)-192)<<8+int(data[2])+192, 3
+		case l == 255:
+			if len(data) < 6 {
+				return 0, nil, nil, errTruncated
+			}
+			length, n = int(binary.BigEndian.Uint32(data[2:6])), 6
+		default:
+			return 0, nil, nil, errors.New("invalid OpenPGP packet: partial body length is not supported for keys")
+		}
+	} else {
+		tag = (data[0] >> 2) & 0x0f
+
+		switch data[0] & 0x03 {
+		case 0:
+			if len(data) < 2 {
+				return 0, nil, nil, errTruncated
+			}
+			length, n = int(data[1]), 2
+		case 1:
+			if len(data) < 3 {
+				return 0, nil, nil, errTruncated
+			}
+			length, n = int(binary.BigEndian.Uint16(data[1:3])), 3
+		case 2:
+			if len(data) < 5 {
+				return 0, nil, nil, errTruncated
+			}
+			length, n = int(binary.BigEndian.Uint32(data[1:5])), 5
+		default:
+			// indeterminate length
+			length, n = len(data)-1, 1
+		}
+	}
+
+	if length < 0 || len(data)-n < length {
+		return 0, nil, nil, errTruncated
+	}
+
+	return tag, data[n : n+length], data[n+length:], nil
+}
+
+// pgpFingerprint returns the fingerprint of a public key packet in upper case hex.
+// Ref: https://www.rfc-editor.org/rfc/rfc9580#section-5.5.4
+func pgpFingerprint(body []byte) (string, error) {
+	if len(body) == 0 {
+		return "", errors.New("invalid OpenPGP public key: empty packet")
+	}
+
+	var h hash.Hash
+	switch body[0] {
+	case 4:
+		h = sha1.New()
+		h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+	case 5, 6:
+		prefix := byte(0x9a)
+		if body[0] == 6 {
+			prefix = 0x9b
+		}
+
+		h = sha256.New()
+		h.Write([]byte{prefix})
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(body))))
+	default:
+		return "", fmt.Errorf("unsupported OpenPGP public key version: %d", body[0])
+	}
+	h.Write(body)
+
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil))), nil
+}
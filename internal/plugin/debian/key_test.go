@@ -0,0 +1,121 @@
+package debian
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testKeyFingerprint = "2968B35A977730638D6385B0EA5924310F972706"
+	testKeyAsc         = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+mDMEatKuVBYJKwYBBAHaRw8BAQdAWp5UkVDQuLe7LsgKUnwUxiG19fylaTk3Nbst
+MqIqaPu0HXBneG1hbiB0ZXN0IDx0ZXN0QHBneG1hbi5jb20+iJAEExYIADgWIQQp
+aLNal3cwY41jhbDqWSQxD5cnBgUCatKuVAIbAwULCQgHAgYVCgkICwIEFgIDAQIe
+AQIXgAAKCRDqWSQxD5cnBlKLAP94hFjfCKHx78nwuO2VUyhZC/SylX2uM4HXwo7/
+/C5tNAD+NoVqdkFKSdZ7ffp4j/6qkuRPo9HKfqMrZdl+kAWXSg4=
+=/aRU
+-----END PGP PUBLIC KEY BLOCK-----`
+
+	otherKeyFingerprint = "E1BA72DC08EE87527970745E6EE855EA1A69B700"
+	otherKeyAsc         = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+mDMEatKuVBYJKwYBBAHaRw8BAQdAJi4HRtdIDL9xrmFcwwyAZQdtEXylFDe5K5I1
+Kkjvaei0H3BneG1hbiBvdGhlciA8b3RoZXJAcGd4bWFuLmNvbT6IkAQTFggAOBYh
+BOG6ctwI7odSeXB0Xm7oVeoaabcABQJq0q5UAhsDBQsJCAcCBhUKCQgLAgQWAgMB
+Ah4BAheAAAoJEG7oVeoaabcATa4A/ifOvhpyViImeSFy02Y7pJ5z5vcp0jV3PqLl
+XIqr5xqwAQDXBAbmNJh2rRLxaUDPp025idiQ1zaBQ7leStn/IDKMBQ==
+=WWnG
+-----END PGP PUBLIC KEY BLOCK-----`
+)
+
+func Test_keyFingerprints(t *testing.T) {
+	testKeyGpg, err := base64.StdEncoding.DecodeString("mDMEatKuVBYJKwYBBAHaRw8BAQdAWp5UkVDQuLe7LsgKUnwUxiG19fylaTk3NbstMqIqaPu0HXBneG1hbiB0ZXN0IDx0ZXN0QHBneG1hbi5jb20+iJAEExYIADgWIQQpaLNal3cwY41jhbDqWSQxD5cnBgUCatKuVAIbAwULCQgHAgYVCgkICwIEFgIDAQIeAQIXgAAKCRDqWSQxD5cnBlKLAP94hFjfCKHx78nwuO2VUyhZC/SylX2uM4HXwo7//C5tNAD+NoVqdkFKSdZ7ffp4j/6qkuRPo9HKfqMrZdl+kAWXSg4=")
+	assert.NoError(t, err)
+
+	cases := []struct {
+		Name    string
+		Key     []byte
+		WantFps []string
+		WantErr bool
+	}{
+		{
+			Name:    "asc",
+			Key:     []byte(testKeyAsc),
+			WantFps: []string{testKeyFingerprint},
+		},
+		{
+			Name:    "gpg",
+			Key:     testKeyGpg,
+			WantFps: []string{testKeyFingerprint},
+		},
+		{
+			Name:    "keyring",
+			Key:     []byte(testKeyAsc + "\n" + otherKeyAsc),
+			WantFps: []string{testKeyFingerprint, otherKeyFingerprint},
+		},
+		{
+			Name:    "truncated",
+			Key:     testKeyGpg[:len(testKeyGpg)-1],
+			WantErr: true,
+		},
+		{
+			Name:    "not a key",
+			Key:     []byte("<html>Not Found</html>"),
+			WantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			fps, err := keyFingerprints(c.Key)
+			if c.WantErr {
+				assert.Error(err)
+				return
+			}
+
+			assert.NoError(err)
+			assert.Equal(c.WantFps, fps)
+		})
+	}
+}
+
+func Test_verifyKey(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(verifyKey([]byte(testKeyAsc), testKeyFingerprint))
+	assert.NoError(verifyKey([]byte("not a key"), ""))
+	assert.ErrorContains(verifyKey([]byte(otherKeyAsc), testKeyFingerprint), "fingerprint mismatch")
+	// an extra key in the keyring would be trusted by apt
+	assert.ErrorContains(verifyKey([]byte(testKeyAsc+"\n"+otherKeyAsc), testKeyFingerprint), "fingerprint mismatch")
+}
+
+func Test_keyChange(t *testing.T) {
+	assert := assert.New(t)
+
+	keyPath := filepath.Join(t.TempDir(), "pgxman-test.asc")
+	source := AptSource{
+		KeyPath:    keyPath,
+		KeyContent: []byte(testKeyAsc),
+	}
+
+	_, _, changed := keyChange(source)
+	assert.False(changed, "new key")
+
+	assert.NoError(os.WriteFile(keyPath, []byte(testKeyAsc+"\n"), 0644))
+	_, _, changed = keyChange(source)
+	assert.False(changed, "same key in different bytes")
+
+	assert.NoError(os.WriteFile(keyPath, []byte(otherKeyAsc), 0644))
+	from, to, changed := keyChange(source)
+	assert.True(changed)
+	assert.Equal([]string{otherKeyFingerprint}, from)
+	assert.Equal([]string{testKeyFingerprint}, to)
+}
@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
@@ -17,9 +18,10 @@ import (
 	"github.com/mholt/archiver/v3"
 	"github.com/pgxman/pgxman"
 	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/internal/plugin/buildscript"
+	"github.com/pgxman/pgxman/internal/plugin/tarball"
 	tmpl "github.com/pgxman/pgxman/internal/template"
 	"github.com/pgxman/pgxman/internal/template/debian"
-	"github.com/pgxman/pgxman/internal/template/script"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -168,7 +170,7 @@ func (p *DebianPackager) generatePrePostScripts(ext pgxman.Extension, scriptDir
 	logger := p.Logger.With("name", ext.Name, "script-dir", scriptDir)
 	logger.Info("Generating pre/post scripts")
 
-	return tmpl.ExportFS(script.FS, scriptTemplater{ext}, scriptDir)
+	return buildscript.ExportPrePost(ext, scriptDir)
 }
 
 func (p *DebianPackager) targetDir(opts pgxman.PackagerOptions) string {
@@ -193,18 +195,13 @@ func (p *DebianPackager) downloadSource(ext pgxman.ExtensionPackage, targetDir s
 
 	targetFile := filepath.Join(targetDir, fmt.Sprintf("%s_%s.orig.tar.gz", ext.Name, ext.Version))
 
-	// file is already downloaded
-	if _, err := os.Stat(targetFile); err == nil {
-		return targetFile, nil
-	}
-
-	source, err := ext.ParseSource()
+	source, err := ext.ArchiveSource(targetFile)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
-	if err := source.Archive(targetFile); err != nil {
-		return "", err
+	if s, ok := source.(pgxman.RevisionSource); ok {
+		logger.Info("Resolved source revision", "revision", s.Revision())
 	}
 
 	return targetFile, nil
@@ -234,7 +231,7 @@ func (p *DebianPackager) unarchiveSource(sourceFile, buildDir string) error {
 		return fmt.Errorf("source is not an archive format: %s", sourceFile)
 	}
 
-	// TODO: support more archive types
+	// sources are repackaged into a tar.gz with a single top-level directory regardless of their archive format
 	targz, ok := ar.(*archiver.TarGz)
 	if ok {
 		targz.StripComponents = 1
@@ -293,7 +290,7 @@ func (p *DebianPackager) installBuildDependencies(ctx context.Context, ext pgxma
 		return err
 	}
 
-	sources, err := apt.ConvertSources(ctx, append(repos, builder.AptRepositories...))
+	sources, err := apt.ConvertSources(ctx, append(repos, ext.Builders.AptRepositories(builder.Type)...))
 	if err != nil {
 		return err
 	}
@@ -355,6 +352,34 @@ func (p *DebianPackager) buildDebian(ctx context.Context, pkg pgxman.ExtensionPa
 		return fmt.Errorf("debuild: %w", err)
 	}
 
+	if slices.Contains(pkg.Formats, pgxman.FormatTar) {
+		if err := p.packTarball(ctx, pkg, buildDir); err != nil {
+			return fmt.Errorf("pack tarball: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// packTarball packs the files that debuild installs for the package into a tarball next to the Debian package.
+func (p *DebianPackager) packTarball(ctx context.Context, pkg pgxman.ExtensionPackage, buildDir string) error {
+	platform, err := pgxman.DetectPlatform()
+	if err != nil {
+		return err
+	}
+
+	file, err := tarball.Pack(ctx, pkg, tarball.PackOptions{
+		DestDir:  filepath.Join(buildDir, "debian", extensionDebPkg(string(pkg.PGVersion), pkg.Name)),
+		PGConfig: fmt.Sprintf("/usr/lib/postgresql/%s/bin/pg_config", pkg.PGVersion),
+		Platform: platform,
+		OutDir:   filepath.Dir(buildDir),
+	})
+	if err != nil {
+		return err
+	}
+
+	p.Logger.Info("Packed tarball", "file", file)
+
 	return nil
 }
 
@@ -406,7 +431,7 @@ func (e extensionData) Deps() string {
 }
 
 func (e extensionData) MainBuildScript() string {
-	return concatBuildScript(e.Build.Main)
+	return buildscript.Concat(e.Build.Main)
 }
 
 func (e extensionData) TimeNow() string {
@@ -427,18 +452,6 @@ func (e extensionData) expandDeps(deps []string) []string {
 	return expandedDeps
 }
 
-func concatBuildScript(scripts []pgxman.BuildScript) string {
-	var steps []string
-	for _, s := range scripts {
-		step := fmt.Sprintf("echo %q\n", s.Name)
-		step += s.Run
-
-		steps = append(steps, step)
-	}
-
-	return strings.Join(steps, "\n\n")
-}
-
 type debianPackageTemplater struct {
 	ext pgxman.ExtensionPackage
 }
@@ -457,35 +470,6 @@ func (d debianPackageTemplater) Render(content []byte, out io.Writer) error {
 	return nil
 }
 
-type scriptData struct {
-	pgxman.Extension
-}
-
-func (s scriptData) PreBuildScript() string {
-	return concatBuildScript(s.Build.Pre)
-}
-
-func (s scriptData) PostBuildScript() string {
-	return concatBuildScript(s.Build.Post)
-}
-
-type scriptTemplater struct {
-	ext pgxman.Extension
-}
-
-func (s scriptTemplater) Render(content []byte, out io.Writer) error {
-	t, err := template.New("").Parse(string(content))
-	if err != nil {
-		return fmt.Errorf("parse template: %w", err)
-	}
-
-	if err := t.Execute(out, scriptData{s.ext}); err != nil {
-		return fmt.Errorf("execute template: %w", err)
-	}
-
-	return nil
-}
-
 func extensionDebPkg(pgversion, extName string) string {
 	return fmt.Sprintf("postgresql-%s-pgxman-%s", pgversion, debNormalizedName(extName))
 }
@@ -0,0 +1,197 @@
+package debian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+)
+
+// aptSnapshot records the installed extension packages and the apt source files
+// before they are changed so that a failed install, upgrade or uninstall can be rolled back.
+type aptSnapshot struct {
+	// Names are the names of the packages that are installed, upgraded or uninstalled
+	Names []string
+	// Pkgs are the packages that were installed before, by name
+	Pkgs map[string]DpkgPackage
+	// Files are the contents of the source and key files before they are written,
+	// nil if the file didn't exist
+	Files map[string][]byte
+}
+
+func (a *Apt) snapshot(ctx context.Context, pkgs []AptPackage, sources []AptSource) (*aptSnapshot, error) {
+	snap := &aptSnapshot{
+		Pkgs:  make(map[string]DpkgPackage),
+		Files: make(map[string][]byte),
+	}
+
+	for _, pkg := range pkgs {
+		name, err := a.debPkgName(ctx, pkg)
+		if err != nil {
+			return nil, err
+		}
+		snap.Names = append(snap.Names, name)
+
+		installed, err := a.ListInstalled(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range installed {
+			snap.Pkgs[p.Pkg] = p
+		}
+	}
+
+	for _, source := range sources {
+		for _, path := range []string{source.SourcePath, source.KeyPath} {
+			b, err := os.ReadFile(path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+
+			snap.Files[path] = b
+		}
+	}
+
+	a.Logger.Debug("Recorded snapshot", "packages", snap.Pkgs, "files", len(snap.Files))
+
+	return snap, nil
+}
+
+// rollbackOnError rolls back to the snapshot if *errp is not nil.
+// ErrRolledBack is added to *errp if the rollback succeeds.
+func (a *Apt) rollbackOnError(ctx context.Context, snap *aptSnapshot, errp *error) {
+	if *errp == nil {
+		return
+	}
+
+	if err := a.rollback(ctx, snap); err != nil {
+		*errp = errors.Join(*errp, fmt.Errorf("rollback: %w", err))
+		return
+	}
+
+	*errp = errors.Join(*errp, pgxman.ErrRolledBack)
+}
+
+// rollback restores the packages and the source files recorded in the snapshot.
+// Upgraded packages are downgraded to the recorded versions, newly installed packages are removed,
+// and new source files are removed.
+func (a *Apt) rollback(ctx context.Context, snap *aptSnapshot) error {
+	a.Logger.Debug("Rolling back", "packages", snap.Pkgs)
+
+	current := make(map[string]DpkgPackage)
+	for _, name := range snap.Names {
+		installed, err := a.ListInstalled(ctx, name)
+		if err != nil {
+			return err
+		}
+		for _, p := range installed {
+			current[p.Pkg] = p
+		}
+	}
+
+	var errs []error
+
+	restore, remove, held := rollbackActions(snap, current)
+	if len(restore) > 0 {
+		opts := []string{"install", "--yes", "--no-install-recommends", "--allow-downgrades", "--allow-change-held-packages"}
+		if _, err := a.runAptCmd(ctx, "apt", append(opts, restore...)...); err != nil {
+			errs = append(errs, fmt.Errorf("apt install %s: %w", strings.Join(restore, " "), err))
+		}
+	}
+	if len(remove) > 0 {
+		opts := []string{"purge", "--yes", "--allow-change-held-packages"}
+		if _, err := a.runAptCmd(ctx, "apt", append(opts, remove...)...); err != nil {
+			errs = append(errs, fmt.Errorf("apt purge %s: %w", strings.Join(remove, " "), err))
+		}
+	}
+	if len(held) > 0 {
+		var pkgs []AptPackage
+		for _, name := range held {
+			pkgs = append(pkgs, AptPackage{Pkg: name})
+		}
+
+		if err := a.aptMarkHold(ctx, pkgs...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for path, content := range snap.Files {
+		a.Logger.Debug("Restoring file", "path", path, "removed", content == nil)
+
+		var err error
+		if content == nil {
+			err = os.Remove(path)
+			if errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = writeFile(path, content)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(snap.Files) > 0 {
+		// the package index is refreshed on a best-effort basis because the restored sources may be unreachable
+		if err := a.aptUpdate(ctx); err != nil {
+			a.Logger.Debug("Failed to update package index after rollback", "error", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// rollbackActions returns the packages to restore in the format of NAME=VERSION,
+// the packages to remove, and the packages to hold again after restoring.
+func rollbackActions(snap *aptSnapshot, current map[string]DpkgPackage) (restore []string, remove []string, held []string) {
+	var names []string
+	for name := range current {
+		names = append(names, name)
+	}
+	for name := range snap.Pkgs {
+		if _, ok := current[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prev, wasInstalled := snap.Pkgs[name]
+		cur, isInstalled := current[name]
+
+		switch {
+		case wasInstalled && (!isInstalled || cur.Version != prev.Version):
+			restore = append(restore, fmt.Sprintf("%s=%s", name, prev.Version))
+			if prev.Held {
+				held = append(held, name)
+			}
+		case !wasInstalled && isInstalled:
+			remove = append(remove, name)
+		case wasInstalled && prev.Held && !cur.Held:
+			// the package is unchanged but was unheld to be changed
+			held = append(held, name)
+		}
+	}
+
+	return restore, remove, held
+}
+
+// debPkgName returns the name of the Debian package without the version.
+// The name of a local package is read from the package file.
+func (a *Apt) debPkgName(ctx context.Context, pkg AptPackage) (string, error) {
+	if !pkg.IsLocal {
+		return pkg.Name(), nil
+	}
+
+	out, err := a.runAptCmd(ctx, "dpkg-deb", "--field", pkg.Pkg, "Package")
+	if err != nil {
+		return "", fmt.Errorf("dpkg-deb: %w", err)
+	}
+
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,178 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+)
+
+// describeTimeout is how long a plugin has to describe itself so that a broken plugin
+// on PATH doesn't block looking up the others.
+var describeTimeout = 5 * time.Second
+
+// Plugin is an external plugin executable.
+type Plugin struct {
+	// Name is the name of the plugin without ExecutablePrefix
+	Name string
+	Path string
+}
+
+// Discover returns the external plugins on PATH. A plugin in an earlier PATH directory
+// shadows the plugins of the same name in later ones.
+func Discover() []Plugin {
+	var (
+		result []Plugin
+		seen   = make(map[string]bool)
+	)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		var plugins []Plugin
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), ExecutablePrefix)
+			if !ok || name == "" || seen[name] {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+
+			seen[name] = true
+			plugins = append(plugins, Plugin{Name: name, Path: path})
+		}
+
+		sort.Slice(plugins, func(i, j int) bool {
+			return plugins[i].Name < plugins[j].Name
+		})
+		result = append(result, plugins...)
+	}
+
+	return result
+}
+
+func isExecutable(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode().IsRegular() && fi.Mode().Perm()&0111 != 0
+}
+
+// Find returns the first external plugin on PATH that supports the capability for the platform.
+// It returns false if there is none.
+func Find(ctx context.Context, p pgxman.Platform, c Capability, logger *log.Logger) (Plugin, bool) {
+	for _, plugin := range Discover() {
+		desc, err := plugin.Describe(ctx, logger)
+		if err != nil {
+			logger.Debug("Skipping external plugin", "plugin", plugin.Path, "error", err)
+			continue
+		}
+
+		if desc.Supports(p, c) {
+			return plugin, true
+		}
+	}
+
+	return Plugin{}, false
+}
+
+// Supports returns whether the plugin supports the capability for the platform.
+func (d DescribeResult) Supports(p pgxman.Platform, c Capability) bool {
+	if !slices.Contains(d.Capabilities, c) {
+		return false
+	}
+
+	return slices.Contains(d.Platforms, AnyPlatform) || slices.Contains(d.Platforms, string(p))
+}
+
+// Describe returns what the plugin supports. It fails if the plugin doesn't respond within describeTimeout.
+func (p Plugin) Describe(ctx context.Context, logger *log.Logger) (DescribeResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, describeTimeout)
+	defer cancel()
+
+	var result DescribeResult
+	if err := p.call(ctx, MethodDescribe, struct{}{}, &result, logger.Writer(slog.LevelDebug)); err != nil {
+		return result, err
+	}
+
+	if result.APIVersion != DefaultProtocolAPIVersion {
+		return result, fmt.Errorf("unsupported protocol api version: %q", result.APIVersion)
+	}
+
+	return result, nil
+}
+
+// call runs the plugin with the request of the method on stdin and decodes the result from stdout into result.
+// The stderr of the plugin is written to stderr.
+func (p Plugin) call(ctx context.Context, method string, params any, result any, stderr io.Writer) error {
+	pb, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshal params: %w", err)
+	}
+
+	req, err := json.Marshal(Request{
+		APIVersion: DefaultProtocolAPIVersion,
+		Method:     method,
+		Params:     pb,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+	runErr := cmd.Run()
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("plugin %s %s: %w", p.Name, method, runErr)
+		}
+
+		return fmt.Errorf("plugin %s %s: invalid response: %w", p.Name, method, err)
+	}
+
+	if resp.Error != nil {
+		return fmt.Errorf("plugin %s %s: %w", p.Name, method, resp.Error)
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("plugin %s %s: %w", p.Name, method, runErr)
+	}
+
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("plugin %s %s: invalid result: %w", p.Name, method, err)
+	}
+
+	return nil
+}
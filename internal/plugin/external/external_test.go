@@ -0,0 +1,196 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHelperPlugin isn't a real test. It's the external plugin that the other tests run.
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("PGXMAN_TEST_PLUGIN") != "1" {
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var (
+		result any
+		rerr   *Error
+	)
+	switch req.Method {
+	case MethodDescribe:
+		result = DescribeResult{
+			APIVersion:   DefaultProtocolAPIVersion,
+			Platforms:    []string{string(pgxman.PlatformRockyLinux9)},
+			Capabilities: []Capability{CapabilityInstaller},
+		}
+	case MethodInstallerList:
+		result = []pgxman.InstalledExtension{
+			{
+				Name:      "pgvector",
+				Version:   "0.5.0",
+				PGVersion: pgxman.PGVersion16,
+			},
+		}
+	case MethodInstallerPreInstallCheck:
+		result = CheckResult{}
+	case MethodInstallerInstall:
+		var params InstallerParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if params.Extensions[0].Name == "conflict" {
+			rerr = &Error{Code: ErrorCodeConflictExtension, Message: "conflict is installed outside of the plugin"}
+		}
+	default:
+		rerr = &Error{Message: "unsupported method " + req.Method}
+	}
+
+	b, _ := json.Marshal(result)
+	_ = json.NewEncoder(os.Stdout).Encode(Response{
+		APIVersion: DefaultProtocolAPIVersion,
+		Result:     b,
+		Error:      rerr,
+	})
+	os.Exit(0)
+}
+
+// installPlugin installs an executable that runs TestHelperPlugin as the plugin of name in dir.
+func installPlugin(t *testing.T, dir, name string) {
+	t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := fmt.Sprintf("#!/bin/sh\nPGXMAN_TEST_PLUGIN=1 exec %q -test.run=TestHelperPlugin\n", exe)
+	if err := os.WriteFile(filepath.Join(dir, ExecutablePrefix+name), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		dir1 = t.TempDir()
+		dir2 = t.TempDir()
+	)
+	installPlugin(t, dir1, "golden")
+	installPlugin(t, dir2, "golden")
+	installPlugin(t, dir2, "another")
+	// not executable
+	assert.NoError(os.WriteFile(filepath.Join(dir2, ExecutablePrefix+"readme"), nil, 0644))
+	t.Setenv("PATH", dir1+string(filepath.ListSeparator)+dir2)
+
+	assert.Equal(
+		[]Plugin{
+			{Name: "golden", Path: filepath.Join(dir1, ExecutablePrefix+"golden")},
+			{Name: "another", Path: filepath.Join(dir2, ExecutablePrefix+"another")},
+		},
+		Discover(),
+	)
+}
+
+func TestFind_DescribeTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	timeout := describeTimeout
+	describeTimeout = 100 * time.Millisecond
+	t.Cleanup(func() {
+		describeTimeout = timeout
+	})
+
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not found")
+	}
+
+	dir := t.TempDir()
+	// a plugin that never responds is sorted before the one that does
+	script := fmt.Sprintf("#!/bin/sh\nexec %q 10\n", sleep)
+	assert.NoError(os.WriteFile(filepath.Join(dir, ExecutablePrefix+"broken"), []byte(script), 0755))
+	installPlugin(t, dir, "golden")
+	t.Setenv("PATH", dir)
+
+	start := time.Now()
+	p, ok := Find(context.Background(), pgxman.PlatformRockyLinux9, CapabilityInstaller, log.NewTextLogger())
+	assert.True(ok)
+	assert.Equal("golden", p.Name)
+	assert.Less(time.Since(start), 5*time.Second)
+
+	// the plugins aren't described once the command is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok = Find(ctx, pgxman.PlatformRockyLinux9, CapabilityInstaller, log.NewTextLogger())
+	assert.False(ok)
+}
+
+func TestInstaller(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	logger := log.NewTextLogger()
+
+	dir := t.TempDir()
+	installPlugin(t, dir, "golden")
+	t.Setenv("PATH", dir)
+
+	_, ok := Find(ctx, pgxman.PlatformRockyLinux9, CapabilityPackager, logger)
+	assert.False(ok)
+	_, ok = Find(ctx, pgxman.PlatformDebianBookworm, CapabilityInstaller, logger)
+	assert.False(ok)
+
+	p, ok := Find(ctx, pgxman.PlatformRockyLinux9, CapabilityInstaller, logger)
+	assert.True(ok)
+	assert.Equal("golden", p.Name)
+
+	i := &Installer{Plugin: p, Logger: logger}
+
+	installed, err := i.List(ctx)
+	assert.NoError(err)
+	assert.Equal(
+		[]pgxman.InstalledExtension{
+			{
+				Name:      "pgvector",
+				Version:   "0.5.0",
+				PGVersion: pgxman.PGVersion16,
+			},
+		},
+		installed,
+	)
+
+	exts := []pgxman.InstallExtension{
+		{
+			PackExtension: pgxman.PackExtension{Name: "pgvector", Version: "0.5.0"},
+			PGVersion:     pgxman.PGVersion16,
+		},
+	}
+	assert.NoError(i.PreInstallCheck(ctx, exts, nil))
+	assert.NoError(i.Install(ctx, exts))
+
+	exts[0].Name = "conflict"
+	err = i.Install(ctx, exts)
+	assert.True(errors.Is(err, pgxman.ErrConflictExtension))
+	assert.ErrorContains(err, "conflict is installed outside of the plugin")
+
+	err = i.Uninstall(ctx, exts[:1])
+	assert.ErrorContains(err, "unsupported method installer.uninstall")
+}
@@ -0,0 +1,89 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/eiannone/keyboard"
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/iostreams"
+	"github.com/pgxman/pgxman/internal/log"
+)
+
+// Installer is an installer that delegates to an external plugin.
+type Installer struct {
+	Plugin Plugin
+	Logger *log.Logger
+}
+
+func (i *Installer) Install(ctx context.Context, exts []pgxman.InstallExtension) error {
+	return i.call(ctx, MethodInstallerInstall, InstallerParams{Extensions: exts}, nil)
+}
+
+func (i *Installer) Upgrade(ctx context.Context, exts []pgxman.InstallExtension) error {
+	return i.call(ctx, MethodInstallerUpgrade, InstallerParams{Extensions: exts}, nil)
+}
+
+func (i *Installer) PreInstallCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams) error {
+	return i.check(ctx, MethodInstallerPreInstallCheck, exts, io, "installation aborted")
+}
+
+func (i *Installer) PreUpgradeCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams) error {
+	return i.check(ctx, MethodInstallerPreUpgradeCheck, exts, io, "upgrade aborted")
+}
+
+func (i *Installer) Uninstall(ctx context.Context, exts []pgxman.InstallExtension) error {
+	return i.call(ctx, MethodInstallerUninstall, InstallerParams{Extensions: exts}, nil)
+}
+
+func (i *Installer) PreUninstallCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams) error {
+	return i.check(ctx, MethodInstallerPreUninstallCheck, exts, io, "uninstallation aborted")
+}
+
+func (i *Installer) List(ctx context.Context) ([]pgxman.InstalledExtension, error) {
+	var result []pgxman.InstalledExtension
+	if err := i.call(ctx, MethodInstallerList, struct{}{}, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (i *Installer) Plan(ctx context.Context, exts []pgxman.InstallExtension) (*pgxman.InstallPlan, error) {
+	var result pgxman.InstallPlan
+	if err := i.call(ctx, MethodInstallerPlan, InstallerParams{Extensions: exts}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// check calls the pre-check method and asks the user to confirm the prompt that the plugin returns.
+func (i *Installer) check(ctx context.Context, method string, exts []pgxman.InstallExtension, io *iostreams.IOStreams, abortMsg string) error {
+	var result CheckResult
+	if err := i.call(ctx, method, InstallerParams{Extensions: exts}, &result); err != nil {
+		return err
+	}
+
+	if result.Prompt == "" || !io.IsTerminal() {
+		return nil
+	}
+
+	err := io.Prompt(result.Prompt+"\nDo you want to continue? [Y/n]", []rune{'y', 'Y'}, []keyboard.Key{keyboard.KeyEnter})
+	if err != nil {
+		if errors.Is(err, iostreams.ErrAbortPrompt) {
+			return fmt.Errorf(abortMsg)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *Installer) call(ctx context.Context, method string, params any, result any) error {
+	i.Logger.Debug("Calling external plugin", "plugin", i.Plugin.Path, "method", method)
+	return i.Plugin.call(ctx, method, params, result, i.Logger.Writer(slog.LevelDebug))
+}
@@ -0,0 +1,44 @@
+package external
+
+import (
+	"context"
+	"os"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+)
+
+// Packager is a packager that delegates to an external plugin.
+type Packager struct {
+	Plugin Plugin
+	Logger *log.Logger
+}
+
+func (p *Packager) Init(ctx context.Context, ext pgxman.Extension, opts pgxman.PackagerOptions) error {
+	return p.call(ctx, MethodPackagerInit, ext, opts)
+}
+
+func (p *Packager) Pre(ctx context.Context, ext pgxman.Extension, opts pgxman.PackagerOptions) error {
+	return p.call(ctx, MethodPackagerPre, ext, opts)
+}
+
+func (p *Packager) Main(ctx context.Context, ext pgxman.Extension, opts pgxman.PackagerOptions) error {
+	return p.call(ctx, MethodPackagerMain, ext, opts)
+}
+
+func (p *Packager) Post(ctx context.Context, ext pgxman.Extension, opts pgxman.PackagerOptions) error {
+	return p.call(ctx, MethodPackagerPost, ext, opts)
+}
+
+// call calls the packager method of the plugin. The stderr of the plugin is shown as is
+// like the output of the build tools of the built-in packagers.
+func (p *Packager) call(ctx context.Context, method string, ext pgxman.Extension, opts pgxman.PackagerOptions) error {
+	p.Logger.Debug("Calling external plugin", "plugin", p.Plugin.Path, "method", method)
+
+	params := PackagerParams{
+		Extension: ext,
+		Path:      ext.Path,
+		Options:   opts,
+	}
+	return p.Plugin.call(ctx, method, params, nil, os.Stderr)
+}
@@ -0,0 +1,115 @@
+package external
+
+import (
+	"encoding/json"
+
+	"github.com/pgxman/pgxman"
+)
+
+// The protocol between pgxman and external plugins. A plugin is run once per method call:
+// pgxman writes a Request to its stdin and reads a Response from its stdout.
+// Anything written to stderr is treated as logs.
+
+const (
+	DefaultProtocolAPIVersion = "v1"
+
+	// ExecutablePrefix is the prefix of the names of external plugins on PATH, e.g. pgxman-plugin-golden
+	ExecutablePrefix = "pgxman-plugin-"
+
+	// AnyPlatform is declared by plugins that support every platform, including the ones that pgxman doesn't support
+	AnyPlatform = "*"
+)
+
+type Capability string
+
+const (
+	CapabilityPackager  Capability = "packager"
+	CapabilityInstaller Capability = "installer"
+)
+
+const (
+	MethodDescribe = "describe"
+
+	MethodPackagerInit = "packager.init"
+	MethodPackagerPre  = "packager.pre"
+	MethodPackagerMain = "packager.main"
+	MethodPackagerPost = "packager.post"
+
+	MethodInstallerInstall           = "installer.install"
+	MethodInstallerUpgrade           = "installer.upgrade"
+	MethodInstallerPreInstallCheck   = "installer.preInstallCheck"
+	MethodInstallerPreUpgradeCheck   = "installer.preUpgradeCheck"
+	MethodInstallerUninstall         = "installer.uninstall"
+	MethodInstallerPreUninstallCheck = "installer.preUninstallCheck"
+	MethodInstallerList              = "installer.list"
+	MethodInstallerPlan              = "installer.plan"
+)
+
+// Error codes that are mapped to the errors of pgxman so that they are reported like the ones of the built-in plugins.
+const (
+	ErrorCodeRootAccessRequired    = "root_access_required"
+	ErrorCodeExtensionNotInstalled = "extension_not_installed"
+	ErrorCodeConflictExtension     = "conflict_extension"
+)
+
+type Request struct {
+	APIVersion string          `json:"apiVersion"`
+	Method     string          `json:"method"`
+	Params     json.RawMessage `json:"params,omitempty"`
+}
+
+type Response struct {
+	APIVersion string          `json:"apiVersion"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      *Error          `json:"error,omitempty"`
+}
+
+// Error is the error of a method call.
+type Error struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	switch e.Code {
+	case ErrorCodeRootAccessRequired:
+		return pgxman.ErrRootAccessRequired
+	case ErrorCodeExtensionNotInstalled:
+		return pgxman.ErrExtensionNotInstalled
+	case ErrorCodeConflictExtension:
+		return pgxman.ErrConflictExtension
+	}
+
+	return nil
+}
+
+// DescribeResult is the result of the describe method.
+type DescribeResult struct {
+	APIVersion string `json:"apiVersion"`
+	// Platforms are the platforms that the plugin supports, e.g. debian_bookworm, or AnyPlatform
+	Platforms    []string     `json:"platforms"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+// PackagerParams are the params of the packager methods.
+type PackagerParams struct {
+	Extension pgxman.Extension `json:"extension"`
+	// Path is the path of the buildkit of the extension
+	Path    string                 `json:"path"`
+	Options pgxman.PackagerOptions `json:"options"`
+}
+
+// InstallerParams are the params of the installer methods that operate on multiple extensions.
+type InstallerParams struct {
+	Extensions []pgxman.InstallExtension `json:"extensions"`
+}
+
+// CheckResult is the result of the pre-check methods.
+type CheckResult struct {
+	// Prompt is the summary of the changes that pgxman asks the user to confirm. No confirmation is asked if it's empty.
+	Prompt string `json:"prompt,omitempty"`
+}
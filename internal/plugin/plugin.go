@@ -1,28 +1,40 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/pgxman/pgxman"
 	"github.com/pgxman/pgxman/internal/log"
 
 	"github.com/pgxman/pgxman/internal/plugin/debian"
+	"github.com/pgxman/pgxman/internal/plugin/external"
+	"github.com/pgxman/pgxman/internal/plugin/rpm"
+	"github.com/pgxman/pgxman/internal/plugin/tarball"
 )
 
 func init() {
-	debPkg := &debian.DebianPackager{
-		Logger: log.NewTextLogger(),
+	var (
+		debPkg = &debian.DebianPackager{
+			Logger: log.NewTextLogger(),
+		}
+		rpmPkg = &rpm.RpmPackager{
+			Logger: log.NewTextLogger(),
+		}
+		debInstaller = &debian.DebianInstaller{
+			Logger: log.NewTextLogger(),
+		}
+	)
+
+	for _, info := range pgxman.Platforms {
+		switch info.Format {
+		case pgxman.FormatDeb:
+			RegisterPackager(info.Platform, debPkg)
+			RegisterInstaller(info.Platform, debInstaller)
+		case pgxman.FormatRpm:
+			RegisterPackager(info.Platform, rpmPkg)
+		}
 	}
-	RegisterPackager(pgxman.PlatformDebianBookworm, debPkg)
-	RegisterPackager(pgxman.PlatformUbuntuJammy, debPkg)
-	RegisterPackager(pgxman.PlatformUbuntuNoble, debPkg)
-
-	debInstaller := &debian.DebianInstaller{
-		Logger: log.NewTextLogger(),
-	}
-	RegisterInstaller(pgxman.PlatformDebianBookworm, debInstaller)
-	RegisterInstaller(pgxman.PlatformUbuntuJammy, debInstaller)
-	RegisterInstaller(pgxman.PlatformUbuntuNoble, debInstaller)
 }
 
 var (
@@ -34,36 +46,61 @@ func RegisterPackager(p pgxman.Platform, packager pgxman.Packager) {
 	packagers[p] = packager
 }
 
-func GetPackager() (pgxman.Packager, error) {
+// GetPackager returns the packager of the current platform. It falls back to an external plugin
+// if there is no built-in packager for the platform.
+func GetPackager(ctx context.Context) (pgxman.Packager, error) {
 	bt, err := pgxman.DetectPlatform()
-	if err != nil {
-		return nil, err
+	if err == nil {
+		if pkg := packagers[bt]; pkg != nil {
+			return pkg, nil
+		}
 	}
 
-	pkg := packagers[bt]
-	if pkg == nil {
-		return nil, &ErrUnsupportedPlugin{p: bt}
+	logger := log.NewTextLogger()
+	if p, ok := external.Find(ctx, bt, external.CapabilityPackager, logger); ok {
+		return &external.Packager{Plugin: p, Logger: logger}, nil
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	return pkg, nil
+	return nil, &ErrUnsupportedPlugin{p: bt}
 }
 
 func RegisterInstaller(p pgxman.Platform, installer pgxman.Installer) {
 	installers[p] = installer
 }
 
-func GetInstaller() (pgxman.Installer, error) {
+// GetInstaller returns the installer of the current platform. It falls back to an external plugin
+// if there is no built-in installer for the platform.
+func GetInstaller(ctx context.Context) (pgxman.Installer, error) {
 	bt, err := pgxman.DetectPlatform()
+	if err == nil {
+		if i := installers[bt]; i != nil {
+			return i, nil
+		}
+	}
+
+	logger := log.NewTextLogger()
+	if p, ok := external.Find(ctx, bt, external.CapabilityInstaller, logger); ok {
+		return &external.Installer{Plugin: p, Logger: logger}, nil
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
-	i := installers[bt]
-	if i == nil {
-		return nil, &ErrUnsupportedPlugin{p: bt}
-	}
+	return nil, &ErrUnsupportedPlugin{p: bt}
+}
 
-	return i, nil
+// GetTarballInstaller returns the installer of relocatable tarballs for the PostgreSQL installation of pg_config.
+// Unlike the other installers, it doesn't depend on the platform.
+func GetTarballInstaller(pgConfig string) pgxman.Installer {
+	return &tarball.TarballInstaller{
+		PGConfig: pgConfig,
+		Logger:   log.NewTextLogger(),
+	}
 }
 
 type ErrUnsupportedPlugin struct {
@@ -0,0 +1,444 @@
+package rpm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"log/slog"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/pgxman/pgxman/internal/plugin/buildscript"
+	"github.com/pgxman/pgxman/internal/plugin/tarball"
+	tmpl "github.com/pgxman/pgxman/internal/template"
+	"github.com/pgxman/pgxman/internal/template/rpm"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	extensionDepPrefix = "pgxman/"
+)
+
+type RpmPackager struct {
+	Logger *log.Logger
+}
+
+// Init generates the following folder structure:
+//
+//   - workspace
+//     -- extension.yaml
+//     -- target
+//     --- script
+//     ---- pre
+//     ---- post
+//     --- 15
+//     ---- rpmbuild
+//     ----- SOURCES
+//     ------ pgvector-0.5.0.tar.gz
+//     ------ main
+//     ----- SPECS
+//     ------ extension.spec
+//     --- 14
+//     ---- rpmbuild
+//     ----- SOURCES
+//     ------ pgvector-0.5.0.tar.gz
+//     ------ main
+//     ----- SPECS
+//     ------ extension.spec
+func (p *RpmPackager) Init(ctx context.Context, ext pgxman.Extension, opts pgxman.PackagerOptions) error {
+	p.Logger.Debug("Init step", "opts", opts, "name", ext.Name)
+
+	if err := checkRootAccess(); err != nil {
+		return err
+	}
+
+	if err := p.installBuildDependencies(ctx, ext); err != nil {
+		return fmt.Errorf("install build dependencies: %w", err)
+	}
+
+	if err := p.generatePrePostScripts(ext, p.targetScriptDir(opts)); err != nil {
+		return fmt.Errorf("write pre/post scripts: %w", err)
+	}
+
+	for _, pkg := range ext.Packages() {
+		if err := p.prepareBuildDir(opts, pkg); err != nil {
+			return fmt.Errorf("prepare build dir: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (p *RpmPackager) Pre(ctx context.Context, ext pgxman.Extension, opts pgxman.PackagerOptions) error {
+	p.Logger.Debug("Pre step", "opts", opts, "name", ext.Name)
+
+	if err := checkRootAccess(); err != nil {
+		return err
+	}
+
+	return p.runScript(ctx, filepath.Join(p.targetScriptDir(opts), "pre"))
+}
+
+func (p *RpmPackager) Post(ctx context.Context, ext pgxman.Extension, opts pgxman.PackagerOptions) error {
+	p.Logger.Debug("Post step", "opts", opts, "name", ext.Name)
+
+	if err := checkRootAccess(); err != nil {
+		return err
+	}
+
+	return p.runScript(ctx, filepath.Join(p.targetScriptDir(opts), "post"))
+}
+
+func (p *RpmPackager) Main(ctx context.Context, ext pgxman.Extension, opts pgxman.PackagerOptions) error {
+	p.Logger.Debug("Main step", "opts", opts, "name", ext.Name)
+
+	if err := checkRootAccess(); err != nil {
+		return err
+	}
+
+	token := opts.Parallel
+	g, gctx := errgroup.WithContext(ctx)
+	for _, pkg := range ext.Packages() {
+		pkg := pkg
+
+		g.Go(func() error {
+			if err := p.buildRpm(gctx, pkg, p.targetPgVerDir(opts, pkg.PGVersion)); err != nil {
+				return fmt.Errorf("rpm build: %w", err)
+			}
+
+			return nil
+		})
+		token--
+
+		// if token is used up, kick off builds & wait for them to finish
+		if token == 0 {
+			if err := g.Wait(); err != nil {
+				return err
+			}
+
+			// reset
+			g, gctx = errgroup.WithContext(ctx)
+			token = opts.Parallel
+		}
+	}
+
+	return g.Wait()
+}
+
+func (p *RpmPackager) prepareBuildDir(opts pgxman.PackagerOptions, pkg pgxman.ExtensionPackage) error {
+	rpmBuildDir := p.targetRpmBuildDir(opts, pkg.PGVersion)
+	for _, dir := range []string{"BUILD", "RPMS", "SOURCES", "SPECS", "SRPMS"} {
+		if err := os.MkdirAll(filepath.Join(rpmBuildDir, dir), 0755); err != nil {
+			return fmt.Errorf("mkdir: %w", err)
+		}
+	}
+
+	p.Logger.Debug("Preparing build dir", "target", rpmBuildDir, "name", pkg.Name, "pgVer", pkg.PGVersion)
+
+	if err := p.downloadSource(pkg, filepath.Join(rpmBuildDir, "SOURCES")); err != nil {
+		return fmt.Errorf("download source %s: %w", pkg.Source, err)
+	}
+
+	if err := p.generateRpmTemplate(pkg, rpmBuildDir); err != nil {
+		return fmt.Errorf("generate rpm template: %w", err)
+	}
+
+	return nil
+}
+
+func (p *RpmPackager) generatePrePostScripts(ext pgxman.Extension, scriptDir string) error {
+	logger := p.Logger.With("name", ext.Name, "script-dir", scriptDir)
+	logger.Info("Generating pre/post scripts")
+
+	return buildscript.ExportPrePost(ext, scriptDir)
+}
+
+func (p *RpmPackager) targetDir(opts pgxman.PackagerOptions) string {
+	return filepath.Join(opts.WorkDir, "target")
+}
+
+func (p *RpmPackager) targetScriptDir(opts pgxman.PackagerOptions) string {
+	return filepath.Join(p.targetDir(opts), "script")
+}
+
+func (p *RpmPackager) targetPgVerDir(opts pgxman.PackagerOptions, pgVer pgxman.PGVersion) string {
+	return filepath.Join(p.targetDir(opts), string(pgVer))
+}
+
+func (p *RpmPackager) targetRpmBuildDir(opts pgxman.PackagerOptions, pgVer pgxman.PGVersion) string {
+	return filepath.Join(p.targetPgVerDir(opts, pgVer), "rpmbuild")
+}
+
+func (p *RpmPackager) downloadSource(ext pgxman.ExtensionPackage, sourcesDir string) error {
+	logger := p.Logger.With(slog.String("source", ext.Source))
+	logger.Info("Downloading source")
+
+	targetFile := filepath.Join(sourcesDir, rpmSourceFile(ext))
+
+	source, err := ext.ArchiveSource(targetFile)
+	if err != nil {
+		return err
+	}
+
+	if s, ok := source.(pgxman.RevisionSource); ok {
+		logger.Info("Resolved source revision", "revision", s.Revision())
+	}
+
+	return nil
+}
+
+func (p *RpmPackager) generateRpmTemplate(ext pgxman.ExtensionPackage, rpmBuildDir string) error {
+	logger := p.Logger.With("name", ext.Name, "rpm-build-dir", rpmBuildDir)
+	logger.Info("Generating rpm template")
+
+	return tmpl.ExportFS(rpm.FS, rpmPackageTemplater{ext}, rpmBuildDir)
+}
+
+func (p *RpmPackager) installBuildDependencies(ctx context.Context, ext pgxman.Extension) error {
+	builder := ext.Builders.Current()
+
+	deps := ext.BuildDependencies
+	if len(builder.BuildDependencies) > 0 {
+		deps = builder.BuildDependencies
+	}
+
+	var depsToInstall []string
+	for _, dep := range deps {
+		if strings.HasPrefix(dep, extensionDepPrefix) {
+			dep = strings.TrimPrefix(dep, extensionDepPrefix)
+			for _, ver := range ext.PGVersions {
+				depsToInstall = append(depsToInstall, extensionRpmPkg(string(ver), dep))
+			}
+		} else {
+			depsToInstall = append(depsToInstall, dep)
+		}
+	}
+
+	if len(depsToInstall) == 0 {
+		return nil
+	}
+
+	logger := p.Logger.With(slog.String("name", ext.Name), slog.String("version", ext.Version), slog.Any("deps", depsToInstall))
+	logger.Info("Installing build deps")
+
+	lw := logger.Writer(slog.LevelDebug)
+
+	dnf := exec.CommandContext(ctx, "dnf", append([]string{"install", "-y"}, depsToInstall...)...)
+	dnf.Stdout = lw
+	dnf.Stderr = lw
+
+	if err := dnf.Run(); err != nil {
+		return fmt.Errorf("dnf install: %w", err)
+	}
+
+	return nil
+}
+
+func (p *RpmPackager) runScript(ctx context.Context, file string) error {
+	logger := p.Logger.With(slog.String("script", file))
+	logger.Info("Running script")
+
+	lw := logger.Writer(slog.LevelDebug)
+
+	runScript := exec.CommandContext(ctx, "bash", file)
+	runScript.Dir = filepath.Dir(file)
+	runScript.Stdout = lw
+	runScript.Stderr = lw
+
+	if err := runScript.Run(); err != nil {
+		return fmt.Errorf("running script: %w", err)
+	}
+
+	return nil
+}
+
+// buildRpm builds the package with rpmbuild and moves it to the PostgreSQL version directory
+// where the packages of all formats are exported from.
+func (p *RpmPackager) buildRpm(ctx context.Context, pkg pgxman.ExtensionPackage, pgVerDir string) error {
+	rpmBuildDir := filepath.Join(pgVerDir, "rpmbuild")
+
+	logger := p.Logger.WithGroup(string(pkg.PGVersion))
+	logger = logger.With("name", pkg.Name, "version", pkg.Version, "build-dir", rpmBuildDir)
+	logger.Info("Building rpm package")
+
+	var (
+		buildRoot = filepath.Join(rpmBuildDir, "BUILDROOT", extensionRpmPkg(string(pkg.PGVersion), pkg.Name))
+		packTar   = slices.Contains(pkg.Formats, pgxman.FormatTar)
+	)
+
+	args := []string{
+		"-bb",
+		"--define", fmt.Sprintf("_topdir %s", rpmBuildDir),
+		"--buildroot", buildRoot,
+	}
+	// the build root is removed after the build unless it's packed into a tarball
+	if packTar {
+		args = append(args, "--noclean")
+	}
+
+	rpmbuild := exec.CommandContext(
+		ctx,
+		"rpmbuild",
+		append(args, filepath.Join(rpmBuildDir, "SPECS", "extension.spec"))...,
+	)
+	rpmbuild.Env = append(
+		os.Environ(),
+		fmt.Sprintf("PGRX_HOME=%s", rpmBuildDir),
+	)
+	rpmbuild.Dir = rpmBuildDir
+	rpmbuild.Stdout = os.Stdout
+	rpmbuild.Stderr = os.Stderr
+
+	logger.Info("Running rpmbuild", "cmd", rpmbuild.String())
+	if err := rpmbuild.Run(); err != nil {
+		return fmt.Errorf("rpmbuild: %w", err)
+	}
+
+	rpms, err := filepath.Glob(filepath.Join(rpmBuildDir, "RPMS", "*", "*.rpm"))
+	if err != nil {
+		return fmt.Errorf("glob built packages: %w", err)
+	}
+
+	for _, file := range rpms {
+		if err := os.Rename(file, filepath.Join(pgVerDir, filepath.Base(file))); err != nil {
+			return fmt.Errorf("move built package: %w", err)
+		}
+	}
+
+	if packTar {
+		platform, err := pgxman.DetectPlatform()
+		if err != nil {
+			return err
+		}
+
+		file, err := tarball.Pack(ctx, pkg, tarball.PackOptions{
+			DestDir:  buildRoot,
+			PGConfig: fmt.Sprintf("/usr/pgsql-%s/bin/pg_config", pkg.PGVersion),
+			Platform: platform,
+			OutDir:   pgVerDir,
+		})
+		if err != nil {
+			return fmt.Errorf("pack tarball: %w", err)
+		}
+
+		logger.Info("Packed tarball", "file", file)
+	}
+
+	return nil
+}
+
+type extensionData struct {
+	pgxman.ExtensionPackage
+}
+
+func (e extensionData) PackageName() string {
+	return extensionRpmPkg(string(e.PGVersion), e.Name)
+}
+
+// PackageVersion returns the version without dashes that aren't allowed in rpm versions.
+// A tilde sorts a pre-release before its release, e.g. 1.0.0~beta1 < 1.0.0.
+func (e extensionData) PackageVersion() string {
+	return strings.ReplaceAll(e.Version, "-", "~")
+}
+
+// PackageLicense returns the license of the extension that is required in rpm packages.
+func (e extensionData) PackageLicense() string {
+	if e.License == "" {
+		return "NOASSERTION"
+	}
+
+	return e.License
+}
+
+// Summary returns the first line of the description because rpm summaries are single lines.
+func (e extensionData) Summary() string {
+	summary, _, _ := strings.Cut(strings.TrimSpace(e.Description), "\n")
+	return summary
+}
+
+func (e extensionData) SourceFile() string {
+	return rpmSourceFile(e.ExtensionPackage)
+}
+
+func (e extensionData) Maintainers() string {
+	var maintainers []string
+	for _, m := range e.ExtensionPackage.Maintainers {
+		maintainers = append(maintainers, fmt.Sprintf("%s <%s>", m.Name, m.Email))
+	}
+
+	return strings.Join(maintainers, ", ")
+}
+
+func (e extensionData) BuildDeps() string {
+	required := []string{
+		fmt.Sprintf("postgresql%s-devel", e.PGVersion),
+	}
+
+	deps := e.BuildDependencies
+	if builders := e.Builders; builders != nil {
+		builder := builders.Current()
+		if len(builder.BuildDependencies) != 0 {
+			deps = builder.BuildDependencies
+		}
+	}
+
+	return strings.Join(append(required, e.expandDeps(deps)...), ", ")
+}
+
+func (e extensionData) Deps() string {
+	required := []string{
+		fmt.Sprintf("postgresql%s-server", e.PGVersion),
+	}
+
+	deps := e.RunDependencies
+	if builders := e.Builders; builders != nil {
+		builder := builders.Current()
+		if len(builder.RunDependencies) != 0 {
+			deps = builder.RunDependencies
+		}
+	}
+
+	return strings.Join(append(required, e.expandDeps(deps)...), ", ")
+}
+
+func (e extensionData) MainBuildScript() string {
+	return buildscript.Concat(e.Build.Main)
+}
+
+func (e extensionData) expandDeps(deps []string) []string {
+	var expandedDeps []string
+	for _, dep := range deps {
+		if strings.HasPrefix(dep, extensionDepPrefix) {
+			dep = strings.TrimPrefix(dep, extensionDepPrefix)
+			dep = extensionRpmPkg(string(e.PGVersion), dep)
+		}
+
+		expandedDeps = append(expandedDeps, dep)
+	}
+
+	return expandedDeps
+}
+
+type rpmPackageTemplater struct {
+	ext pgxman.ExtensionPackage
+}
+
+func (r rpmPackageTemplater) Render(content []byte, out io.Writer) error {
+	t, err := template.New("").Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+
+	if err := t.Execute(out, extensionData{r.ext}); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package rpm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_rpmPackageTemplater(t *testing.T) {
+	assert := assert.New(t)
+
+	ext := pgxman.ExtensionPackage{
+		ExtensionCommon: pgxman.ExtensionCommon{
+			Name:        "pg_cron",
+			Maintainers: []pgxman.Maintainer{{Name: "Owen Ou", Email: "o@hydra.so"}},
+			Description: "Job scheduler for PostgreSQL\n\nRuns periodic jobs in PostgreSQL.",
+		},
+		ExtensionOverridable: pgxman.ExtensionOverridable{
+			Version:           "1.6.0-rc1",
+			BuildDependencies: []string{"libxml2-devel", "pgxman/multicorn"},
+			RunDependencies:   []string{"libxml2", "pgxman/multicorn"},
+		},
+		PGVersion: pgxman.PGVersion14,
+	}
+
+	cases := []struct {
+		Name        string
+		Content     string
+		WantContent string
+	}{
+		{
+			Name:        "package name",
+			Content:     `{{ .PackageName }}`,
+			WantContent: "pgxman_pg_cron_14",
+		},
+		{
+			Name:        "package version",
+			Content:     `{{ .PackageVersion }}`,
+			WantContent: "1.6.0~rc1",
+		},
+		{
+			Name:        "default license",
+			Content:     `{{ .PackageLicense }}`,
+			WantContent: "NOASSERTION",
+		},
+		{
+			Name:        "summary",
+			Content:     `{{ .Summary }}`,
+			WantContent: "Job scheduler for PostgreSQL",
+		},
+		{
+			Name:        "source file",
+			Content:     `{{ .SourceFile }}`,
+			WantContent: "pg_cron-1.6.0-rc1.tar.gz",
+		},
+		{
+			Name:        "build deps",
+			Content:     `{{ .BuildDeps }}`,
+			WantContent: "postgresql14-devel, libxml2-devel, pgxman_multicorn_14",
+		},
+		{
+			Name:        "deps",
+			Content:     `{{ .Deps }}`,
+			WantContent: "postgresql14-server, libxml2, pgxman_multicorn_14",
+		},
+		{
+			Name:        "maintainers",
+			Content:     `{{ .Maintainers }}`,
+			WantContent: "Owen Ou <o@hydra.so>",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.Name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBuffer(nil)
+
+			err := rpmPackageTemplater{ext}.Render([]byte(c.Content), buf)
+			assert.NoError(err)
+			assert.Equal(c.WantContent, buf.String())
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package rpm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+)
+
+// extensionRpmPkg returns the rpm package name of an extension following the PGDG convention
+// of suffixing the PostgreSQL version, e.g. pgxman_pgvector_16.
+func extensionRpmPkg(pgversion, extName string) string {
+	return fmt.Sprintf("pgxman_%s_%s", rpmNormalizedName(extName), pgversion)
+}
+
+func rpmNormalizedName(name string) string {
+	return strings.ToLower(name)
+}
+
+func rpmSourceFile(ext pgxman.ExtensionPackage) string {
+	return fmt.Sprintf("%s-%s.tar.gz", rpmNormalizedName(ext.Name), ext.Version)
+}
+
+func checkRootAccess() error {
+	if os.Getuid() != 0 {
+		return pgxman.ErrRootAccessRequired
+	}
+	return nil
+}
@@ -0,0 +1,511 @@
+package tarball
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/eiannone/keyboard"
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/iostreams"
+	"github.com/pgxman/pgxman/internal/log"
+)
+
+const (
+	// recordDir is the directory under the sharedir in which the installed tarballs are tracked
+	recordDir = "pgxman"
+)
+
+// TarballInstaller installs relocatable tarballs into the PostgreSQL installation of a pg_config.
+// The installed files are tracked so that they are removed when the extension is upgraded or uninstalled.
+type TarballInstaller struct {
+	PGConfig string
+	Logger   *log.Logger
+}
+
+// record is the installed tarball of an extension.
+type record struct {
+	Manifest
+	// Package is the file name of the installed tarball
+	Package string `json:"package"`
+	// InstalledFiles are the absolute paths of the installed files
+	InstalledFiles []string `json:"installedFiles"`
+}
+
+// tarballPlan is a tarball to install and the destination of each file in it.
+type tarballPlan struct {
+	Ext      pgxman.InstallExtension
+	Manifest Manifest
+	Files    map[string]string
+}
+
+func (i *TarballInstaller) Install(ctx context.Context, exts []pgxman.InstallExtension) error {
+	return i.installOrUpgrade(ctx, exts)
+}
+
+func (i *TarballInstaller) Upgrade(ctx context.Context, exts []pgxman.InstallExtension) error {
+	return i.installOrUpgrade(ctx, exts)
+}
+
+func (i *TarballInstaller) PreInstallCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams) error {
+	return i.installOrUpgradeCheck(ctx, exts, io, false)
+}
+
+func (i *TarballInstaller) PreUpgradeCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams) error {
+	return i.installOrUpgradeCheck(ctx, exts, io, true)
+}
+
+// Uninstall removes the files of the extensions. The records of all extensions are read first
+// so that nothing is removed if an extension is not installed.
+func (i *TarballInstaller) Uninstall(ctx context.Context, exts []pgxman.InstallExtension) error {
+	i.Logger.Debug("Uninstalling extensions", "extensions", exts)
+
+	dirs, err := readPGConfig(ctx, i.PGConfig)
+	if err != nil {
+		return err
+	}
+
+	var records []record
+	for _, ext := range exts {
+		r, err := readRecord(dirs, ext.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ext.Name, err)
+		}
+
+		records = append(records, r)
+	}
+
+	for _, r := range records {
+		for _, file := range r.InstalledFiles {
+			if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("remove %s: %w", file, err)
+			}
+		}
+
+		if err := os.Remove(recordFile(dirs, r.Name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *TarballInstaller) PreUninstallCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams) error {
+	dirs, err := readPGConfig(ctx, i.PGConfig)
+	if err != nil {
+		return err
+	}
+
+	var records []record
+	for _, ext := range exts {
+		r, err := readRecord(dirs, ext.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ext.Name, err)
+		}
+
+		records = append(records, r)
+	}
+
+	return promptUninstall(io, records)
+}
+
+func (i *TarballInstaller) List(ctx context.Context) ([]pgxman.InstalledExtension, error) {
+	dirs, err := readPGConfig(ctx, i.PGConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := readRecords(dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []pgxman.InstalledExtension
+	for _, r := range records {
+		result = append(result, pgxman.InstalledExtension{
+			Name:      r.Name,
+			Version:   r.Version,
+			PGVersion: r.PGVersion,
+			Arch:      r.Arch,
+			Package:   r.Package,
+		})
+	}
+
+	return result, nil
+}
+
+// SQLExtensions returns the SQL extensions of the control files in the tarball or in the installed tarball of the extension.
+func (i *TarballInstaller) SQLExtensions(ctx context.Context, ext pgxman.InstallExtension) ([]string, error) {
+	var files []string
+	if ext.Path != "" {
+		m, err := readManifest(ext.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		files = m.Files
+	} else {
+		dirs, err := readPGConfig(ctx, i.PGConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := readRecord(dirs, ext.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		files = r.Files
+	}
+
+	var names []string
+	for _, file := range files {
+		name, ok := strings.CutPrefix(file, shareDir+"/extension/")
+		if !ok || strings.Contains(name, "/") || filepath.Ext(name) != ".control" {
+			continue
+		}
+
+		names = append(names, strings.TrimSuffix(name, ".control"))
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (i *TarballInstaller) Plan(ctx context.Context, exts []pgxman.InstallExtension) (*pgxman.InstallPlan, error) {
+	_, plans, err := i.plan(ctx, exts)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &pgxman.InstallPlan{}
+	for _, p := range plans {
+		plan.Packages = append(plan.Packages, pgxman.PlannedPackage{
+			Name:       p.Manifest.Name,
+			Version:    p.Manifest.Version,
+			Path:       p.Ext.Path,
+			PGVersion:  p.Manifest.PGVersion,
+			Package:    filepath.Base(p.Ext.Path),
+			RequiredBy: p.Ext.RequiredBy,
+		})
+	}
+
+	return plan, nil
+}
+
+func (i *TarballInstaller) installOrUpgradeCheck(ctx context.Context, exts []pgxman.InstallExtension, io *iostreams.IOStreams, upgrade bool) error {
+	dirs, plans, err := i.plan(ctx, exts)
+	if err != nil {
+		return err
+	}
+
+	if len(plans) == 0 {
+		return nil
+	}
+
+	return promptInstallOrUpgrade(io, dirs, plans, upgrade)
+}
+
+// plan reads the manifests of the tarballs and checks that their files can be installed without changing the system.
+func (i *TarballInstaller) plan(ctx context.Context, exts []pgxman.InstallExtension) (pgDirs, []tarballPlan, error) {
+	dirs, err := readPGConfig(ctx, i.PGConfig)
+	if err != nil {
+		return dirs, nil, err
+	}
+
+	records, err := readRecords(dirs)
+	if err != nil {
+		return dirs, nil, err
+	}
+
+	owners := make(map[string]string)
+	for _, r := range records {
+		for _, file := range r.InstalledFiles {
+			owners[file] = normalizeName(r.Name)
+		}
+	}
+
+	var plans []tarballPlan
+	for _, ext := range exts {
+		if ext.Path == "" {
+			return dirs, nil, fmt.Errorf("%s: only local tarballs can be installed with pg_config %s", ext, i.PGConfig)
+		}
+
+		m, err := readManifest(ext.Path)
+		if err != nil {
+			return dirs, nil, err
+		}
+
+		if m.PGVersion != dirs.PGVersion {
+			return dirs, nil, fmt.Errorf("%s is built for PostgreSQL %s but pg_config %s is PostgreSQL %s", filepath.Base(ext.Path), m.PGVersion, i.PGConfig, dirs.PGVersion)
+		}
+
+		if m.Arch != runtime.GOARCH {
+			return dirs, nil, fmt.Errorf("%s is built for %s but the host is %s", filepath.Base(ext.Path), m.Arch, runtime.GOARCH)
+		}
+
+		p := tarballPlan{
+			Ext:      ext,
+			Manifest: m,
+			Files:    make(map[string]string),
+		}
+		for _, file := range m.Files {
+			top, rel, _ := splitFile(file)
+			dst := filepath.Join(dirs.dir(top), filepath.FromSlash(rel))
+
+			if owner := owners[dst]; owner != "" {
+				if owner != normalizeName(m.Name) {
+					return dirs, nil, fmt.Errorf("%s: %s is installed by extension %s", m.Name, dst, owner)
+				}
+			} else if _, err := os.Lstat(dst); err == nil && !ext.Overwrite {
+				return dirs, nil, fmt.Errorf("%s: %s is installed outside of pgxman: %w", m.Name, dst, pgxman.ErrConflictExtension)
+			}
+
+			p.Files[file] = dst
+		}
+
+		plans = append(plans, p)
+	}
+
+	return dirs, plans, nil
+}
+
+// installOrUpgrade installs the files of each tarball and removes the files of the previously installed version
+// that aren't in the tarball.
+func (i *TarballInstaller) installOrUpgrade(ctx context.Context, exts []pgxman.InstallExtension) error {
+	i.Logger.Debug("Installing extensions", "extensions", exts)
+
+	dirs, plans, err := i.plan(ctx, exts)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range plans {
+		if err := i.install(dirs, p); err != nil {
+			return fmt.Errorf("install %s: %w", p.Ext.Path, err)
+		}
+	}
+
+	return nil
+}
+
+func (i *TarballInstaller) install(dirs pgDirs, p tarballPlan) error {
+	logger := i.Logger.With("name", p.Manifest.Name, "version", p.Manifest.Version)
+
+	prev, err := readRecord(dirs, p.Manifest.Name)
+	if err != nil && !errors.Is(err, pgxman.ErrExtensionNotInstalled) {
+		return err
+	}
+
+	r := record{
+		Manifest: p.Manifest,
+		Package:  filepath.Base(p.Ext.Path),
+	}
+	err = walkTarball(p.Ext.Path, func(hdr *tar.Header, rd io.Reader) error {
+		dst, ok := p.Files[hdr.Name]
+		if !ok {
+			return nil
+		}
+
+		logger.Debug("Installing file", "file", dst)
+		if err := installFile(hdr, rd, dst); err != nil {
+			return fmt.Errorf("install %s: %w", dst, err)
+		}
+		r.InstalledFiles = append(r.InstalledFiles, dst)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeRecord(dirs, r); err != nil {
+		return err
+	}
+
+	for _, file := range prev.InstalledFiles {
+		if slices.Contains(r.InstalledFiles, file) {
+			continue
+		}
+
+		logger.Debug("Removing file of the previous version", "file", file)
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// installFile writes a file to a temporary file and renames it so that a loaded library isn't changed in place.
+func installFile(hdr *tar.Header, r io.Reader, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	tmp := dst + ".pgxman-tmp"
+	_ = os.Remove(tmp)
+
+	switch hdr.Typeflag {
+	case tar.TypeSymlink:
+		if err := os.Symlink(hdr.Linkname, tmp); err != nil {
+			return err
+		}
+	case tar.TypeReg:
+		f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, hdr.FileInfo().Mode().Perm())
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+
+		if err := f.Close(); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported file type: %c", hdr.Typeflag)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(name)
+}
+
+func recordFile(dirs pgDirs, name string) string {
+	return filepath.Join(dirs.ShareDir, recordDir, normalizeName(name)+".json")
+}
+
+func readRecord(dirs pgDirs, name string) (record, error) {
+	var r record
+
+	b, err := os.ReadFile(recordFile(dirs, name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return r, pgxman.ErrExtensionNotInstalled
+		}
+
+		return r, err
+	}
+
+	if err := json.Unmarshal(b, &r); err != nil {
+		return r, fmt.Errorf("decode %s: %w", recordFile(dirs, name), err)
+	}
+
+	return r, nil
+}
+
+func readRecords(dirs pgDirs) ([]record, error) {
+	files, err := filepath.Glob(filepath.Join(dirs.ShareDir, recordDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []record
+	for _, file := range files {
+		r, err := readRecord(dirs, strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+func writeRecord(dirs pgDirs, r record) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file := recordFile(dirs, r.Name)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, b, 0644)
+}
+
+func promptInstallOrUpgrade(io *iostreams.IOStreams, dirs pgDirs, plans []tarballPlan, upgrade bool) error {
+	if !io.IsTerminal() {
+		return nil
+	}
+
+	var (
+		action   = "installed"
+		abortMsg = "installation aborted"
+	)
+	if upgrade {
+		action = "upgraded"
+		abortMsg = "upgrade aborted"
+	}
+
+	out := []string{
+		fmt.Sprintf("The following tarballs will be %s into %s and %s:", action, dirs.PkgLibDir, dirs.ShareDir),
+	}
+	for _, p := range plans {
+		out = append(out, "  "+filepath.Base(p.Ext.Path))
+	}
+
+	out = append(out, "Do you want to continue? [Y/n]")
+
+	err := io.Prompt(strings.Join(out, "\n"), []rune{'y', 'Y'}, []keyboard.Key{keyboard.KeyEnter})
+	if err != nil {
+		if errors.Is(err, iostreams.ErrAbortPrompt) {
+			return fmt.Errorf(abortMsg)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func promptUninstall(io *iostreams.IOStreams, records []record) error {
+	if !io.IsTerminal() || len(records) == 0 {
+		return nil
+	}
+
+	out := []string{
+		"The following tarballs will be removed:",
+	}
+	for _, r := range records {
+		out = append(out, "  "+r.Package)
+	}
+
+	out = append(out, "Do you want to continue? [Y/n]")
+
+	err := io.Prompt(strings.Join(out, "\n"), []rune{'y', 'Y'}, []keyboard.Key{keyboard.KeyEnter})
+	if err != nil {
+		if errors.Is(err, iostreams.ErrAbortPrompt) {
+			return fmt.Errorf("uninstallation aborted")
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,384 @@
+package tarball
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/pg"
+)
+
+const (
+	DefaultManifestAPIVersion = "v1"
+
+	manifestFileName = "manifest.json"
+	// files under lib/ are installed in the pkglibdir, including the bitcode directory
+	libDir = "lib"
+	// files under share/ are installed in the sharedir, e.g. share/extension/vector.control
+	shareDir = "share"
+)
+
+// Manifest describes the extension in a tarball and the files that it contains.
+type Manifest struct {
+	APIVersion string           `json:"apiVersion"`
+	Name       string           `json:"name"`
+	Version    string           `json:"version"`
+	PGVersion  pgxman.PGVersion `json:"pgVersion"`
+	Arch       string           `json:"arch"`
+	// Platform is the platform that the tarball is built on. The tarball is installable on platforms with a compatible libc.
+	Platform pgxman.Platform `json:"platform"`
+	// Files are the paths of the files in the tarball relative to the lib and share directories, e.g. lib/vector.so
+	Files []string `json:"files"`
+}
+
+func (m Manifest) Validate() error {
+	if m.APIVersion != DefaultManifestAPIVersion {
+		return fmt.Errorf("invalid manifest api version: %s", m.APIVersion)
+	}
+
+	if m.Name == "" {
+		return fmt.Errorf("manifest name is required")
+	}
+
+	if m.Version == "" {
+		return fmt.Errorf("manifest version is required")
+	}
+
+	if err := m.PGVersion.Validate(); err != nil {
+		return err
+	}
+
+	files := make(map[string]bool)
+	for _, f := range m.Files {
+		if _, _, err := splitFile(f); err != nil {
+			return err
+		}
+		files[f] = true
+	}
+
+	// a file under another file would be installed through it if the other file is a symlink,
+	// e.g. lib/evil/x through lib/evil -> /etc
+	for _, f := range m.Files {
+		for dir := path.Dir(f); dir != "."; dir = path.Dir(dir) {
+			if files[dir] {
+				return fmt.Errorf("invalid file in tarball: %s is under file %s", f, dir)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateLink checks that a symlink in a tarball points to a file in the same installation directory.
+func validateLink(name, target string) error {
+	if target == "" || path.IsAbs(target) || slices.Contains(strings.Split(path.Clean(target), "/"), "..") {
+		return fmt.Errorf("invalid symlink in tarball: %s -> %s", name, target)
+	}
+
+	return nil
+}
+
+// FileName returns the versioned name of the tarball of an extension, e.g. pgxman-pgvector_0.5.0_pg16_amd64.tar.gz.
+func FileName(name, version string, pgVer pgxman.PGVersion, arch string) string {
+	return fmt.Sprintf("pgxman-%s_%s_pg%s_%s.tar.gz", strings.ToLower(name), version, pgVer, arch)
+}
+
+type PackOptions struct {
+	// DestDir is the directory in which the main build script installs the extension, i.e. $DESTDIR
+	DestDir string
+	// PGConfig is the pg_config that the extension is built with
+	PGConfig string
+	Platform pgxman.Platform
+	// OutDir is the directory in which the tarball is written
+	OutDir string
+}
+
+// Pack captures the files that are installed in the pkglibdir and the sharedir of pg_config under DestDir
+// into a tarball with a manifest and returns the path of the tarball.
+func Pack(ctx context.Context, ext pgxman.ExtensionPackage, opts PackOptions) (string, error) {
+	dirs, err := readPGConfig(ctx, opts.PGConfig)
+	if err != nil {
+		return "", err
+	}
+
+	manifest := Manifest{
+		APIVersion: DefaultManifestAPIVersion,
+		Name:       ext.Name,
+		Version:    ext.Version,
+		PGVersion:  ext.PGVersion,
+		Arch:       runtime.GOARCH,
+		Platform:   opts.Platform,
+	}
+
+	type packFile struct {
+		name string
+		src  string
+	}
+
+	var files []packFile
+	for _, d := range []struct {
+		name string
+		dir  string
+	}{
+		{name: libDir, dir: dirs.PkgLibDir},
+		{name: shareDir, dir: dirs.ShareDir},
+	} {
+		root := filepath.Join(opts.DestDir, d.dir)
+		err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) && p == root {
+					return fs.SkipDir
+				}
+
+				return err
+			}
+
+			if entry.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+
+			files = append(files, packFile{
+				name: path.Join(d.name, filepath.ToSlash(rel)),
+				src:  p,
+			})
+
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("walk %s: %w", root, err)
+		}
+	}
+
+	if len(files) == 0 {
+		return "", fmt.Errorf("no files are installed in %s or %s under %s", dirs.PkgLibDir, dirs.ShareDir, opts.DestDir)
+	}
+
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, f.name)
+	}
+
+	mb, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	dst := filepath.Join(opts.OutDir, FileName(ext.Name, ext.Version, ext.PGVersion, manifest.Arch))
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name: manifestFileName,
+		Mode: 0644,
+		Size: int64(len(mb)),
+	}); err != nil {
+		return "", err
+	}
+	if _, err := tw.Write(mb); err != nil {
+		return "", err
+	}
+
+	for _, f := range files {
+		if err := writeTarFile(tw, f.name, f.src); err != nil {
+			return "", fmt.Errorf("add %s: %w", f.src, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	if err := gw.Close(); err != nil {
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+
+	return dst, nil
+}
+
+func writeTarFile(tw *tar.Writer, name, src string) error {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	var link string
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		link, err = os.Readlink(src)
+		if err != nil {
+			return err
+		}
+	}
+
+	hdr, err := tar.FileInfoHeader(fi, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	// ownership is decided by the installer
+	hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// readManifest returns the manifest of a tarball. It checks the symlinks of the tarball as well
+// since their targets aren't in the manifest.
+func readManifest(file string) (Manifest, error) {
+	var (
+		manifest Manifest
+		found    bool
+	)
+	err := walkTarball(file, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Typeflag == tar.TypeSymlink {
+			return validateLink(hdr.Name, hdr.Linkname)
+		}
+
+		if hdr.Name != manifestFileName {
+			return nil
+		}
+
+		if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+			return fmt.Errorf("decode manifest: %w", err)
+		}
+		found = true
+
+		return nil
+	})
+	if err != nil {
+		return manifest, err
+	}
+
+	if !found {
+		return manifest, fmt.Errorf("%s is not a pgxman tarball: %s is not found", file, manifestFileName)
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return manifest, fmt.Errorf("invalid manifest of %s: %w", file, err)
+	}
+
+	return manifest, nil
+}
+
+// walkTarball calls fn with each entry of a tar.gz file until fn returns an error.
+func walkTarball(file string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", file, err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", file, err)
+		}
+
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// splitFile returns the top-level directory of a file in a tarball and the path relative to it.
+func splitFile(name string) (string, string, error) {
+	dir, rel, ok := strings.Cut(name, "/")
+	if !ok || (dir != libDir && dir != shareDir) || rel == "" || path.Clean(rel) != rel || !fs.ValidPath(rel) {
+		return "", "", fmt.Errorf("invalid file in tarball: %s", name)
+	}
+
+	return dir, rel, nil
+}
+
+// pgDirs are the directories of a PostgreSQL installation that are reported by pg_config.
+type pgDirs struct {
+	PGVersion pgxman.PGVersion
+	PkgLibDir string
+	ShareDir  string
+}
+
+// dir returns the directory in which the files under the top-level directory of a tarball are installed.
+func (d pgDirs) dir(top string) string {
+	if top == libDir {
+		return d.PkgLibDir
+	}
+
+	return d.ShareDir
+}
+
+func readPGConfig(ctx context.Context, pgConfig string) (pgDirs, error) {
+	var dirs pgDirs
+
+	pgVer, err := pg.ConfigVersion(ctx, pgConfig)
+	if err != nil {
+		return dirs, fmt.Errorf("pg_config %s: %w", pgConfig, err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, pgConfig, "--pkglibdir", "--sharedir")
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return dirs, fmt.Errorf("pg_config %s: %w: %s", pgConfig, err, strings.TrimSpace(stderr.String()))
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || slices.Contains(lines, "") {
+		return dirs, fmt.Errorf("pg_config %s: unexpected output: %s", pgConfig, b)
+	}
+
+	return pgDirs{
+		PGVersion: pgVer,
+		PkgLibDir: strings.TrimSpace(lines[0]),
+		ShareDir:  strings.TrimSpace(lines[1]),
+	}, nil
+}
@@ -0,0 +1,386 @@
+package tarball
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/pgxman/pgxman"
+	"github.com/pgxman/pgxman/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePGConfig writes a pg_config script that reports the directories of a PostgreSQL installation under root.
+func fakePGConfig(t *testing.T, root string) (string, pgDirs) {
+	t.Helper()
+
+	dirs := pgDirs{
+		PGVersion: pgxman.PGVersion16,
+		PkgLibDir: filepath.Join(root, "lib", "postgresql"),
+		ShareDir:  filepath.Join(root, "share", "postgresql"),
+	}
+
+	script := fmt.Sprintf(`#!/bin/sh
+if [ "$1" = "--version" ]; then
+  echo "PostgreSQL 16.2"
+  exit 0
+fi
+echo "%s"
+echo "%s"
+`, dirs.PkgLibDir, dirs.ShareDir)
+
+	file := filepath.Join(root, "pg_config")
+	if err := os.WriteFile(file, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return file, dirs
+}
+
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	for file, content := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func packExtension(t *testing.T, pgConfig string, dirs pgDirs, version string, files map[string]string) string {
+	t.Helper()
+
+	destDir := t.TempDir()
+	destFiles := make(map[string]string)
+	for file, content := range files {
+		destFiles[filepath.Join(destDir, file)] = content
+	}
+	writeFiles(t, destFiles)
+
+	ext := pgxman.ExtensionPackage{
+		ExtensionCommon: pgxman.ExtensionCommon{
+			Name: "pgvector",
+		},
+		ExtensionOverridable: pgxman.ExtensionOverridable{
+			Version: version,
+		},
+		PGVersion: dirs.PGVersion,
+	}
+
+	file, err := Pack(context.Background(), ext, PackOptions{
+		DestDir:  destDir,
+		PGConfig: pgConfig,
+		Platform: pgxman.PlatformDebianBookworm,
+		OutDir:   t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func TestTarballInstaller(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	pgConfig, dirs := fakePGConfig(t, t.TempDir())
+	i := &TarballInstaller{
+		PGConfig: pgConfig,
+		Logger:   log.NewTextLogger(),
+	}
+
+	v1 := packExtension(t, pgConfig, dirs, "0.5.0", map[string]string{
+		filepath.Join(dirs.PkgLibDir, "vector.so"):                     "v1",
+		filepath.Join(dirs.PkgLibDir, "bitcode", "vector", "src.bc"):   "v1",
+		filepath.Join(dirs.ShareDir, "extension", "vector.control"):    "v1",
+		filepath.Join(dirs.ShareDir, "extension", "vector--0.5.0.sql"): "v1",
+	})
+	assert.Equal("pgxman-pgvector_0.5.0_pg16_"+runtime.GOARCH+".tar.gz", filepath.Base(v1))
+
+	m, err := readManifest(v1)
+	assert.NoError(err)
+	assert.Equal(
+		[]string{
+			"lib/bitcode/vector/src.bc",
+			"lib/vector.so",
+			"share/extension/vector--0.5.0.sql",
+			"share/extension/vector.control",
+		},
+		m.Files,
+	)
+
+	exts := []pgxman.InstallExtension{
+		{
+			PackExtension: pgxman.PackExtension{Path: v1},
+			PGVersion:     dirs.PGVersion,
+		},
+	}
+	assert.NoError(i.Install(ctx, exts))
+
+	b, err := os.ReadFile(filepath.Join(dirs.PkgLibDir, "vector.so"))
+	assert.NoError(err)
+	assert.Equal("v1", string(b))
+
+	installed, err := i.List(ctx)
+	assert.NoError(err)
+	assert.Equal(
+		[]pgxman.InstalledExtension{
+			{
+				Name:      "pgvector",
+				Version:   "0.5.0",
+				PGVersion: dirs.PGVersion,
+				Arch:      m.Arch,
+				Package:   filepath.Base(v1),
+			},
+		},
+		installed,
+	)
+
+	names, err := i.SQLExtensions(ctx, pgxman.InstallExtension{PackExtension: pgxman.PackExtension{Name: "pgvector"}})
+	assert.NoError(err)
+	assert.Equal([]string{"vector"}, names)
+
+	// files of the previous version that aren't in the new tarball are removed
+	v2 := packExtension(t, pgConfig, dirs, "0.6.0", map[string]string{
+		filepath.Join(dirs.PkgLibDir, "vector.so"):                     "v2",
+		filepath.Join(dirs.ShareDir, "extension", "vector.control"):    "v2",
+		filepath.Join(dirs.ShareDir, "extension", "vector--0.6.0.sql"): "v2",
+	})
+	assert.NoError(i.Upgrade(ctx, []pgxman.InstallExtension{
+		{
+			PackExtension: pgxman.PackExtension{Path: v2},
+			PGVersion:     dirs.PGVersion,
+		},
+	}))
+
+	b, err = os.ReadFile(filepath.Join(dirs.PkgLibDir, "vector.so"))
+	assert.NoError(err)
+	assert.Equal("v2", string(b))
+	assert.NoFileExists(filepath.Join(dirs.ShareDir, "extension", "vector--0.5.0.sql"))
+	assert.NoFileExists(filepath.Join(dirs.PkgLibDir, "bitcode", "vector", "src.bc"))
+
+	assert.NoError(i.Uninstall(ctx, []pgxman.InstallExtension{{PackExtension: pgxman.PackExtension{Name: "pgvector"}}}))
+	assert.NoFileExists(filepath.Join(dirs.PkgLibDir, "vector.so"))
+	assert.NoFileExists(filepath.Join(dirs.ShareDir, "extension", "vector.control"))
+
+	installed, err = i.List(ctx)
+	assert.NoError(err)
+	assert.Empty(installed)
+
+	err = i.Uninstall(ctx, []pgxman.InstallExtension{{PackExtension: pgxman.PackExtension{Name: "pgvector"}}})
+	assert.True(errors.Is(err, pgxman.ErrExtensionNotInstalled))
+}
+
+func TestTarballInstaller_Conflict(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	pgConfig, dirs := fakePGConfig(t, t.TempDir())
+	i := &TarballInstaller{
+		PGConfig: pgConfig,
+		Logger:   log.NewTextLogger(),
+	}
+
+	file := packExtension(t, pgConfig, dirs, "0.5.0", map[string]string{
+		filepath.Join(dirs.PkgLibDir, "vector.so"):                  "pgxman",
+		filepath.Join(dirs.ShareDir, "extension", "vector.control"): "pgxman",
+	})
+
+	// vector.so is installed outside of pgxman
+	writeFiles(t, map[string]string{
+		filepath.Join(dirs.PkgLibDir, "vector.so"): "other",
+	})
+
+	ext := pgxman.InstallExtension{
+		PackExtension: pgxman.PackExtension{Path: file},
+		PGVersion:     dirs.PGVersion,
+	}
+	err := i.Install(ctx, []pgxman.InstallExtension{ext})
+	assert.True(errors.Is(err, pgxman.ErrConflictExtension))
+
+	ext.Overwrite = true
+	assert.NoError(i.Install(ctx, []pgxman.InstallExtension{ext}))
+
+	b, err := os.ReadFile(filepath.Join(dirs.PkgLibDir, "vector.so"))
+	assert.NoError(err)
+	assert.Equal("pgxman", string(b))
+}
+
+// writeTarball writes a tarball of the manifest and the entries as is, e.g. a tarball that isn't made by Pack.
+func writeTarball(t *testing.T, m Manifest, entries []tar.Header) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "ext.tar.gz")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	mb, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries = append([]tar.Header{{Name: manifestFileName, Mode: 0644, Size: int64(len(mb))}}, entries...)
+	for _, hdr := range entries {
+		hdr := hdr
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+
+		var content []byte
+		if hdr.Name == manifestFileName {
+			content = mb
+		} else if hdr.Typeflag == tar.TypeReg {
+			content = make([]byte, hdr.Size)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func TestTarballInstaller_Malicious(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Files   []string
+		Entries []tar.Header
+		WantErr string
+	}{
+		{
+			Name:  "file under symlink",
+			Files: []string{"lib/evil", "lib/evil/x"},
+			Entries: []tar.Header{
+				{Name: "lib/evil", Typeflag: tar.TypeSymlink, Linkname: "subdir", Mode: 0777},
+				{Name: "lib/evil/x", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+			},
+			WantErr: "lib/evil/x is under file lib/evil",
+		},
+		{
+			Name:  "absolute symlink",
+			Files: []string{"lib/evil"},
+			Entries: []tar.Header{
+				{Name: "lib/evil", Typeflag: tar.TypeSymlink, Linkname: "/etc", Mode: 0777},
+			},
+			WantErr: "invalid symlink in tarball: lib/evil -> /etc",
+		},
+		{
+			Name:  "symlink out of dir",
+			Files: []string{"lib/evil"},
+			Entries: []tar.Header{
+				{Name: "lib/evil", Typeflag: tar.TypeSymlink, Linkname: "bitcode/../../../etc", Mode: 0777},
+			},
+			WantErr: "invalid symlink in tarball",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			root := t.TempDir()
+			pgConfig, dirs := fakePGConfig(t, root)
+			i := &TarballInstaller{
+				PGConfig: pgConfig,
+				Logger:   log.NewTextLogger(),
+			}
+
+			file := writeTarball(t, Manifest{
+				APIVersion: DefaultManifestAPIVersion,
+				Name:       "evil",
+				Version:    "1.0.0",
+				PGVersion:  dirs.PGVersion,
+				Arch:       runtime.GOARCH,
+				Files:      c.Files,
+			}, c.Entries)
+
+			err := i.Install(context.Background(), []pgxman.InstallExtension{
+				{
+					PackExtension: pgxman.PackExtension{Path: file},
+					PGVersion:     dirs.PGVersion,
+				},
+			})
+			assert.ErrorContains(err, c.WantErr)
+			assert.NoDirExists(dirs.PkgLibDir)
+		})
+	}
+}
+
+func Test_splitFile(t *testing.T) {
+	cases := []struct {
+		Name    string
+		File    string
+		WantDir string
+		WantRel string
+		WantErr bool
+	}{
+		{
+			Name:    "lib",
+			File:    "lib/bitcode/vector/src.bc",
+			WantDir: "lib",
+			WantRel: "bitcode/vector/src.bc",
+		},
+		{
+			Name:    "share",
+			File:    "share/extension/vector.control",
+			WantDir: "share",
+			WantRel: "extension/vector.control",
+		},
+		{
+			Name:    "unknown dir",
+			File:    "bin/vector",
+			WantErr: true,
+		},
+		{
+			Name:    "path traversal",
+			File:    "lib/../../etc/passwd",
+			WantErr: true,
+		},
+		{
+			Name:    "dir only",
+			File:    "lib/",
+			WantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			dir, rel, err := splitFile(c.File)
+			if c.WantErr {
+				assert.Error(err)
+				return
+			}
+
+			assert.NoError(err)
+			assert.Equal(c.WantDir, dir)
+			assert.Equal(c.WantRel, rel)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNotCached is returned in offline mode when a request isn't in the cache.
+	ErrNotCached = errors.New("not available offline, run the command online first to cache it")
+)
+
+// Cache is an on-disk cache of the extension metadata of the registry.
+// Requests always go to the registry when online, and cached responses are revalidated
+// with their ETag or Last-Modified header so that unchanged metadata isn't downloaded again.
+type Cache struct {
+	Dir string
+	// Offline answers requests from the cache without the network.
+	Offline bool
+}
+
+type cacheEntry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	StatusCode   int         `json:"statusCode"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// Transport returns a round tripper that caches the responses of next.
+func (c *Cache) Transport(next http.RoundTripper) http.RoundTripper {
+	return &cacheTransport{cache: c, next: next}
+}
+
+type cacheTransport struct {
+	cache *Cache
+	next  http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isCacheable(req) {
+		if t.cache.Offline {
+			return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, ErrNotCached)
+		}
+
+		return t.next.RoundTrip(req)
+	}
+
+	entry := t.cache.read(req.URL.String())
+	if t.cache.Offline {
+		if entry == nil {
+			return nil, fmt.Errorf("%s: %w", req.URL.Path, ErrNotCached)
+		}
+
+		return entry.response(req), nil
+	}
+
+	if entry != nil {
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		return entry.response(req), nil
+	}
+
+	// not found is cached so that offline lookups fall through to the next registry as they do online
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// the cache is best effort, e.g. the cache dir of a read-only home doesn't fail the request
+	_ = t.cache.write(cacheEntry{
+		URL:          req.URL.String(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header,
+		Body:         body,
+	})
+
+	return resp, nil
+}
+
+// isCacheable returns whether the request reads extension metadata.
+// Requests of the user, e.g. auth status, are never cached.
+func isCacheable(req *http.Request) bool {
+	return req.Method == http.MethodGet && strings.Contains(req.URL.Path, "/extensions")
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// read returns the cached entry of the URL or nil if there is none. An entry that can't be read
+// is treated as a miss and overwritten by the next response.
+func (c *Cache) read(u string) *cacheEntry {
+	b, err := os.ReadFile(c.file(u))
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil || entry.URL != u {
+		return nil
+	}
+
+	return &entry
+}
+
+// write writes the entry to a temporary file and renames it so that concurrent commands never read a partial entry.
+func (c *Cache) write(entry cacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal registry cache: %w", err)
+	}
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("create registry cache dir: %w", err)
+	}
+
+	f, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write registry cache: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("write registry cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write registry cache: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), c.file(entry.URL)); err != nil {
+		return fmt.Errorf("write registry cache: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Cache) file(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pgxman/pgxman/oapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var (
+		requests    int
+		notModified int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/extensions/pgvector" {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(oapi.Error{Message: "not found"})
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(oapi.Extension{Name: "pgvector", Description: "vector"})
+	}))
+	defer srv.Close()
+
+	cache := &Cache{Dir: t.TempDir()}
+	client, err := NewClient(srv.URL, "", WithCache(cache))
+	assert.NoError(err)
+
+	for i := 0; i < 2; i++ {
+		ext, err := client.GetExtension(ctx, "pgvector")
+		assert.NoError(err)
+		assert.Equal("vector", ext.Description)
+	}
+	assert.Equal(2, requests)
+	assert.Equal(1, notModified)
+
+	_, err = client.GetExtension(ctx, "missing")
+	assert.True(errors.Is(err, ErrExtensionNotFound))
+
+	srv.Close()
+	requests = 0
+
+	offline, err := NewClient(srv.URL, "", WithCache(&Cache{Dir: cache.Dir, Offline: true}))
+	assert.NoError(err)
+
+	ext, err := offline.GetExtension(ctx, "pgvector")
+	assert.NoError(err)
+	assert.Equal("vector", ext.Description)
+
+	_, err = offline.GetExtension(ctx, "missing")
+	assert.True(errors.Is(err, ErrExtensionNotFound))
+
+	_, err = offline.GetVersion(ctx, "pgvector", "0.5.0")
+	assert.True(errors.Is(err, ErrNotCached))
+
+	_, err = offline.GetUser(ctx)
+	assert.True(errors.Is(err, ErrNotCached))
+
+	assert.Equal(0, requests)
+}
@@ -20,20 +20,38 @@ var (
 	ErrExtensionNotFound = errors.New("extension not found")
 )
 
-func NewClient(baseURL, token string) (Client, error) {
+// ClientOption configures the HTTP client of the registry client.
+type ClientOption func(c *http.Client)
+
+// WithCache caches the extension metadata of the registry in the cache.
+func WithCache(cache *Cache) ClientOption {
+	return func(c *http.Client) {
+		next := c.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+
+		c.Transport = cache.Transport(next)
+	}
+}
+
+func NewClient(baseURL, token string, opts ...ClientOption) (Client, error) {
 	u, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, err
 	}
 
+	hc := &http.Client{
+		Timeout: defaultHTTPTimeout,
+	}
+	for _, opt := range opts {
+		opt(hc)
+	}
+
 	user := u.User
 	c, err := oapi.NewClientWithResponses(
 		baseURL,
-		oapi.WithHTTPClient(
-			&http.Client{
-				Timeout: defaultHTTPTimeout,
-			},
-		),
+		oapi.WithHTTPClient(hc),
 		oapi.WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
 			// use jwt auth when basic auth creds are not in the base URL
 			if user == nil && token != "" {
@@ -57,6 +75,8 @@ type Client interface {
 	FindExtension(ctx context.Context, args []string) ([]oapi.SimpleExtension, error)
 	PublishExtension(ctx context.Context, ext oapi.PublishExtension) error
 	GetVersion(ctx context.Context, name, version string) (*oapi.Extension, error)
+	// ListVersions returns the packages of all versions of an extension by PostgreSQL version.
+	ListVersions(ctx context.Context, name string) (oapi.Versions, error)
 	GetUser(ctx context.Context) (*oapi.User, error)
 }
 
@@ -181,3 +201,31 @@ func (c *client) GetVersion(ctx context.Context, name, version string) (*oapi.Ex
 
 	return resp.JSON200, nil
 }
+
+func (c *client) ListVersions(ctx context.Context, name string) (oapi.Versions, error) {
+	resp, err := c.ClientWithResponsesInterface.ListVersionsWithResponse(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.JSON404 != nil {
+		return nil, ErrExtensionNotFound
+	}
+
+	var errMsg string
+	if resp.JSON500 != nil {
+		errMsg = resp.JSON500.Message
+	} else if resp.HTTPResponse.StatusCode >= 300 {
+		errMsg = strings.TrimSpace(string(resp.Body))
+	}
+
+	if errMsg != "" {
+		return nil, fmt.Errorf("error listing extension versions %s: %s", name, errMsg)
+	}
+
+	if resp.JSON200 == nil {
+		return oapi.Versions{}, nil
+	}
+
+	return *resp.JSON200, nil
+}
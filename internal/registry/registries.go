@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pgxman/pgxman/oapi"
+)
+
+// Registry is a registry client with the name that extension names can be prefixed with, e.g. internal/pgvector.
+type Registry struct {
+	Name   string
+	URL    string
+	Client Client
+}
+
+// Registries are registries in the order of priority. An extension is looked up in each registry in order
+// unless its name is prefixed with the name of a registry. The first registry that has the extension owns it
+// so that an extension of a registry is never shadowed by the extension of the same name in a later one.
+//
+// Extensions are published to and users are read from the first registry.
+type Registries []Registry
+
+var _ Client = Registries{}
+
+// SplitName splits an extension name into the registry prefix and the name without the prefix.
+// The registry is empty if the name isn't prefixed.
+func SplitName(name string) (string, string) {
+	if reg, n, ok := strings.Cut(name, "/"); ok {
+		return reg, n
+	}
+
+	return "", name
+}
+
+// lookup returns the registries to look up the extension in and the name of the extension without the registry prefix.
+func (rs Registries) lookup(name string) (Registries, string, error) {
+	reg, n := SplitName(name)
+	if reg == "" {
+		return rs, n, nil
+	}
+
+	for _, r := range rs {
+		if r.Name == reg {
+			return Registries{r}, n, nil
+		}
+	}
+
+	return nil, "", fmt.Errorf("unknown registry %q of extension %q", reg, name)
+}
+
+// owner returns the first registry that has the extension and the extension from it.
+func (rs Registries) owner(ctx context.Context, name string) (Registry, *oapi.Extension, error) {
+	regs, n, err := rs.lookup(name)
+	if err != nil {
+		return Registry{}, nil, err
+	}
+
+	for _, r := range regs {
+		ext, err := r.Client.GetExtension(ctx, n)
+		if err != nil {
+			if errors.Is(err, ErrExtensionNotFound) {
+				continue
+			}
+
+			return Registry{}, nil, fmt.Errorf("registry %s: %w", r.Name, err)
+		}
+
+		return r, ext, nil
+	}
+
+	return Registry{}, nil, ErrExtensionNotFound
+}
+
+func (rs Registries) GetExtension(ctx context.Context, name string) (*oapi.Extension, error) {
+	_, ext, err := rs.owner(ctx, name)
+	return ext, err
+}
+
+func (rs Registries) GetVersion(ctx context.Context, name, version string) (*oapi.Extension, error) {
+	r, _, err := rs.owner(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	_, n := SplitName(name)
+	return r.Client.GetVersion(ctx, n, version)
+}
+
+func (rs Registries) ListVersions(ctx context.Context, name string) (oapi.Versions, error) {
+	regs, n, err := rs.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range regs {
+		versions, err := r.Client.ListVersions(ctx, n)
+		if err != nil {
+			if errors.Is(err, ErrExtensionNotFound) {
+				continue
+			}
+
+			return nil, fmt.Errorf("registry %s: %w", r.Name, err)
+		}
+
+		return versions, nil
+	}
+
+	return nil, ErrExtensionNotFound
+}
+
+// FindExtension returns the matching extensions of all registries. An extension of a registry
+// hides the extensions of the same name in later registries.
+func (rs Registries) FindExtension(ctx context.Context, args []string) ([]oapi.SimpleExtension, error) {
+	var (
+		result []oapi.SimpleExtension
+		seen   = make(map[string]bool)
+	)
+	for _, r := range rs {
+		exts, err := r.Client.FindExtension(ctx, args)
+		if err != nil {
+			return nil, fmt.Errorf("registry %s: %w", r.Name, err)
+		}
+
+		for _, ext := range exts {
+			if seen[ext.Name] {
+				continue
+			}
+
+			seen[ext.Name] = true
+			result = append(result, ext)
+		}
+	}
+
+	return result, nil
+}
+
+func (rs Registries) PublishExtension(ctx context.Context, ext oapi.PublishExtension) error {
+	if len(rs) == 0 {
+		return fmt.Errorf("no registry is configured")
+	}
+
+	return rs[0].Client.PublishExtension(ctx, ext)
+}
+
+func (rs Registries) GetUser(ctx context.Context) (*oapi.User, error) {
+	if len(rs) == 0 {
+		return nil, fmt.Errorf("no registry is configured")
+	}
+
+	return rs[0].Client.GetUser(ctx)
+}
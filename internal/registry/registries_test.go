@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pgxman/pgxman/oapi"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubbedClient is a registry of the extensions by name. Each extension has the versions of the Packages key.
+type stubbedClient map[string]*oapi.Extension
+
+func (s stubbedClient) GetExtension(ctx context.Context, name string) (*oapi.Extension, error) {
+	if ext, ok := s[name]; ok {
+		return ext, nil
+	}
+
+	return nil, ErrExtensionNotFound
+}
+
+func (s stubbedClient) GetVersion(ctx context.Context, name, version string) (*oapi.Extension, error) {
+	ext, err := s.GetExtension(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := ext.Packages[version]; !ok {
+		return nil, ErrExtensionNotFound
+	}
+
+	return ext, nil
+}
+
+func (s stubbedClient) ListVersions(ctx context.Context, name string) (oapi.Versions, error) {
+	if _, err := s.GetExtension(ctx, name); err != nil {
+		return nil, err
+	}
+
+	return oapi.Versions{}, nil
+}
+
+func (s stubbedClient) FindExtension(ctx context.Context, args []string) ([]oapi.SimpleExtension, error) {
+	var result []oapi.SimpleExtension
+	for _, ext := range s {
+		result = append(result, oapi.SimpleExtension{Name: ext.Name, Description: ext.Description})
+	}
+
+	return result, nil
+}
+
+func (s stubbedClient) PublishExtension(ctx context.Context, ext oapi.PublishExtension) error {
+	return errors.New("not implemented")
+}
+
+func (s stubbedClient) GetUser(ctx context.Context) (*oapi.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestRegistries(t *testing.T) {
+	newExt := func(name, description string, versions ...string) *oapi.Extension {
+		ext := &oapi.Extension{
+			Name:        name,
+			Description: description,
+			Packages:    make(oapi.Packages),
+		}
+		for _, v := range versions {
+			ext.Packages[v] = oapi.Package{Version: v}
+		}
+
+		return ext
+	}
+
+	regs := Registries{
+		{
+			Name: "internal",
+			Client: stubbedClient{
+				"pgvector": newExt("pgvector", "internal", "0.5.0"),
+				"secret":   newExt("secret", "internal", "1.0.0"),
+			},
+		},
+		{
+			Name: "pgxman",
+			Client: stubbedClient{
+				"pgvector":     newExt("pgvector", "public", "0.5.0", "0.6.0"),
+				"pg_hint_plan": newExt("pg_hint_plan", "public", "1.6.0"),
+			},
+		},
+	}
+
+	cases := []struct {
+		Name            string
+		ExtName         string
+		Version         string
+		WantDescription string
+		WantErr         string
+	}{
+		{
+			Name:            "first registry wins",
+			ExtName:         "pgvector",
+			WantDescription: "internal",
+		},
+		{
+			Name:            "falls back to later registry",
+			ExtName:         "pg_hint_plan",
+			WantDescription: "public",
+		},
+		{
+			Name:            "registry prefix",
+			ExtName:         "pgxman/pgvector",
+			WantDescription: "public",
+		},
+		{
+			Name:    "prefixed extension not in registry",
+			ExtName: "pgxman/secret",
+			WantErr: ErrExtensionNotFound.Error(),
+		},
+		{
+			Name:    "unknown registry",
+			ExtName: "other/pgvector",
+			WantErr: `unknown registry "other"`,
+		},
+		{
+			Name:            "version of owner registry",
+			ExtName:         "pgvector",
+			Version:         "0.5.0",
+			WantDescription: "internal",
+		},
+		{
+			Name:    "version not shadowed by later registry",
+			ExtName: "pgvector",
+			Version: "0.6.0",
+			WantErr: ErrExtensionNotFound.Error(),
+		},
+		{
+			Name:            "prefixed version",
+			ExtName:         "pgxman/pgvector",
+			Version:         "0.6.0",
+			WantDescription: "public",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var (
+				ext *oapi.Extension
+				err error
+			)
+			if c.Version == "" {
+				ext, err = regs.GetExtension(context.TODO(), c.ExtName)
+			} else {
+				ext, err = regs.GetVersion(context.TODO(), c.ExtName, c.Version)
+			}
+
+			if c.WantErr != "" {
+				assert.ErrorContains(err, c.WantErr)
+				return
+			}
+
+			assert.NoError(err)
+			assert.Equal(c.WantDescription, ext.Description)
+		})
+	}
+}
+
+func TestRegistries_FindExtension(t *testing.T) {
+	assert := assert.New(t)
+
+	regs := Registries{
+		{
+			Name: "internal",
+			Client: stubbedClient{
+				"pgvector": &oapi.Extension{Name: "pgvector", Description: "internal"},
+			},
+		},
+		{
+			Name: "pgxman",
+			Client: stubbedClient{
+				"pgvector": &oapi.Extension{Name: "pgvector", Description: "public"},
+			},
+		},
+	}
+
+	exts, err := regs.FindExtension(context.TODO(), nil)
+	assert.NoError(err)
+	assert.Equal([]oapi.SimpleExtension{{Name: "pgvector", Description: "internal"}}, exts)
+}
+
+func TestSplitName(t *testing.T) {
+	assert := assert.New(t)
+
+	reg, name := SplitName("internal/pgvector")
+	assert.Equal("internal", reg)
+	assert.Equal("pgvector", name)
+
+	reg, name = SplitName("pgvector")
+	assert.Equal("", reg)
+	assert.Equal("pgvector", name)
+}
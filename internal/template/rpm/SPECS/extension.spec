@@ -0,0 +1,41 @@
+%global debug_package %{nil}
+%global pginstdir /usr/pgsql-{{ .PGVersion }}
+
+Name: {{ .PackageName }}
+Version: {{ .PackageVersion }}
+Release: 1%{?dist}
+Summary: {{ .Summary }}
+License: {{ .PackageLicense }}
+{{- if .Homepage }}
+URL: {{ .Homepage }}
+{{- end }}
+{{- if .Maintainers }}
+Packager: {{ .Maintainers }}
+{{- end }}
+Source0: {{ .SourceFile }}
+Source1: main
+BuildRequires: {{ .BuildDeps }}
+Requires: {{ .Deps }}
+
+%description
+{{ .Description }}
+
+%prep
+%setup -q -c -T
+tar -xzf %{SOURCE0} --strip-components=1
+
+%build
+# nothing to do here, the main build script builds and installs the extension
+
+%install
+WORKDIR=$(pwd) \
+DESTDIR=%{buildroot} \
+PG_CONFIG=%{pginstdir}/bin/pg_config \
+USE_PGXS=1 \
+PG_VERSION={{ .PGVersion }} \
+PATH=%{pginstdir}/bin:$PATH \
+bash %{SOURCE1}
+# the files are only known after the main build script installs them
+find %{buildroot} \( -type f -o -type l \) | sed -e "s|^%{buildroot}||" -e 's|^|"|' -e 's|$|"|' > %{_builddir}/files.list
+
+%files -f %{_builddir}/files.list
@@ -0,0 +1,8 @@
+package rpm
+
+import (
+	"embed"
+)
+
+//go:embed all:*
+var FS embed.FS
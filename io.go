@@ -1,6 +1,7 @@
 package pgxman
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -42,6 +43,55 @@ func ReadPackFile(path string) (*Pack, error) {
 	return &pack, nil
 }
 
+// PackLockFilePath returns the path of the lock file for a pack file.
+// The lock file of a pack read from STDIN is in the current directory.
+func PackLockFilePath(packPath string) (string, error) {
+	if packPath == "-" {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join(pwd, PackLockFileName), nil
+	}
+
+	return filepath.Join(filepath.Dir(packPath), PackLockFileName), nil
+}
+
+// ReadPackLockFile reads a lock file. It returns nil if the lock file doesn't exist.
+func ReadPackLockFile(path string) (*PackLock, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var lock PackLock
+	if err := yaml.Unmarshal(b, &lock); err != nil {
+		return nil, err
+	}
+
+	if err := lock.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid lock file %s: %w", path, err)
+	}
+
+	return &lock, nil
+}
+
+func WritePackLockFile(path string, lock PackLock) error {
+	b, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	header := []byte("# This file is generated by pgxman. Do not edit it manually.\n")
+
+	return os.WriteFile(path, append(header, b...), 0644)
+}
+
 func overrideExtension(ext Extension, overrides map[string]any) (Extension, error) {
 	bb, err := yaml.Marshal(overrides)
 	if err != nil {
@@ -109,16 +159,10 @@ func ReadExtension(path string, overrides map[string]any) (Extension, error) {
 	// Remove default builders that aren't declared
 	// so that mergo only merges those that are declared
 	if builders := ext.Builders; builders != nil {
-		if !builders.HasBuilder(PlatformDebianBookworm) {
-			defExt.Builders.DebianBookworm = nil
-		}
-
-		if !builders.HasBuilder(PlatformUbuntuJammy) {
-			defExt.Builders.UbuntuJammy = nil
-		}
-
-		if !builders.HasBuilder(PlatformUbuntuNoble) {
-			defExt.Builders.UbuntuNoble = nil
+		for _, info := range Platforms {
+			if !builders.HasBuilder(info.Platform) {
+				defExt.Builders.RemoveBuilder(info.Platform)
+			}
 		}
 	}
 
@@ -47,6 +47,9 @@ const (
 // Defines values for PlatformOs.
 const (
 	DebianBookworm PlatformOs = "debian_bookworm"
+	DebianBullseye PlatformOs = "debian_bullseye"
+	Rockylinux9    PlatformOs = "rockylinux_9"
+	UbuntuFocal    PlatformOs = "ubuntu_focal"
 	UbuntuJammy    PlatformOs = "ubuntu_jammy"
 	UbuntuNoble    PlatformOs = "ubuntu_noble"
 )
@@ -172,8 +175,11 @@ type Package struct {
 	PublishedAt Timestamp   `json:"published_at"`
 	Readme      Readme      `json:"readme,omitempty"`
 	Repository  Repository  `json:"repository" validate:"required,url"`
-	Source      Source      `json:"source" validate:"required,url,extension_source"`
-	Version     VersionCode `json:"version" validate:"semver"`
+
+	// SharedPreloadLibraries Libraries that must be added to shared_preload_libraries for the extension to work.
+	SharedPreloadLibraries SharedPreloadLibraries `json:"shared_preload_libraries,omitempty"`
+	Source                 Source                 `json:"source" validate:"required,url,extension_source"`
+	Version                VersionCode            `json:"version" validate:"semver"`
 }
 
 // Packages defines model for Packages.
@@ -190,7 +196,7 @@ type Platform struct {
 	AptRepositories   AptRepositories `json:"apt_repositories,omitempty" validate:"gte=0,dive"`
 	Architectures     Architectures   `json:"architectures" validate:"required,dive,oneof=amd64 arm64"`
 	BuildDependencies Dependencies    `json:"build_dependencies"`
-	Os                PlatformOs      `json:"os" validate:"required,oneof=debian_bookworm ubuntu_jammy ubuntu_noble"`
+	Os                PlatformOs      `json:"os" validate:"required,oneof=debian_bullseye debian_bookworm ubuntu_focal ubuntu_jammy ubuntu_noble rockylinux_9"`
 	PgVersions        PgVersions      `json:"pg_versions" validate:"required,dive,oneof=13 14 15 16"`
 	RunDependencies   Dependencies    `json:"run_dependencies"`
 }
@@ -234,10 +240,15 @@ type Readme = string
 // Repository defines model for Repository.
 type Repository = string
 
+// SharedPreloadLibraries Libraries that must be added to shared_preload_libraries for the extension to work.
+type SharedPreloadLibraries = []string
+
 // SignedKey defines model for SignedKey.
 type SignedKey struct {
-	Format SignedKeyFormat `json:"format" validate:"required,oneof=gpg asc"`
-	Url    string          `json:"url" validate:"required,url"`
+	// Fingerprint OpenPGP fingerprint of the key in upper case hex without spaces.
+	Fingerprint string          `json:"fingerprint,omitempty" validate:"omitempty,hexadecimal"`
+	Format      SignedKeyFormat `json:"format" validate:"required,oneof=gpg asc"`
+	Url         string          `json:"url" validate:"required,url"`
 }
 
 // SignedKeyFormat defines model for SignedKey.Format.
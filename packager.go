@@ -5,9 +5,9 @@ import (
 )
 
 type PackagerOptions struct {
-	WorkDir  string
-	Parallel int
-	Debug    bool
+	WorkDir  string `json:"workDir"`
+	Parallel int    `json:"parallel"`
+	Debug    bool   `json:"debug"`
 }
 
 type Packager interface {
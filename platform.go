@@ -0,0 +1,203 @@
+package pgxman
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/pgxman/pgxman/internal/osx"
+	"golang.org/x/exp/slices"
+)
+
+type Platform string
+
+const (
+	PlatformUnsupported    Platform = "unsupported"
+	PlatformDebianBullseye Platform = "debian_bullseye"
+	PlatformDebianBookworm Platform = "debian_bookworm"
+	PlatformUbuntuFocal    Platform = "ubuntu_focal"
+	PlatformUbuntuJammy    Platform = "ubuntu_jammy"
+	PlatformUbuntuNoble    Platform = "ubuntu_noble"
+	PlatformRockyLinux9    Platform = "rockylinux_9"
+	PlatformDarwin         Platform = "darwin"
+)
+
+// PlatformInfo describes how extensions are built for and installed on a platform.
+type PlatformInfo struct {
+	Platform Platform
+	// Builder is the key of the builder of the platform in a buildkit, e.g. debian:bookworm
+	Builder string
+	// OSVendors are the os vendors that run the platform as reported by /etc/os-release
+	OSVendors []string
+	// OSVersion is the os version of the platform. Point releases of it are also matched, e.g. 9.4 for 9.
+	OSVersion string
+	// Codename is the codename of the os release. It is the suite of the apt repositories of Debian platforms.
+	Codename string
+	// AptPrefix is the path of the core apt repository of the platform. It is empty for platforms that don't use apt.
+	AptPrefix string
+	// Format is the package format that is built and installed on the platform
+	Format Format
+	// BuilderImage is the default image of the builder without a tag
+	BuilderImage string
+	// BakeTarget is the docker bake target that builds extensions for the platform
+	BakeTarget string
+	// Default is whether the builder of the platform is enabled when a buildkit doesn't declare builders
+	Default bool
+
+	// aptBuilder returns the field of the builder of a deb platform
+	aptBuilder func(ebs *ExtensionBuilders) **AptExtensionBuilder
+	// rpmBuilder returns the field of the builder of an rpm platform
+	rpmBuilder func(ebs *ExtensionBuilders) **RpmExtensionBuilder
+}
+
+// ArtifactDir returns the directory in which the built packages of the platform are exported, e.g. debian/bookworm.
+func (info PlatformInfo) ArtifactDir() string {
+	return strings.ReplaceAll(info.Builder, ":", "/")
+}
+
+func (info PlatformInfo) matchOS(vendor, version string) bool {
+	if !slices.Contains(info.OSVendors, vendor) {
+		return false
+	}
+
+	return version == info.OSVersion || strings.HasPrefix(version, info.OSVersion+".")
+}
+
+// Platforms are the platforms that extensions are built for. Adding a platform requires
+// a field in ExtensionBuilders, an entry here, the platform in the registry API and a builder image.
+var Platforms = []PlatformInfo{
+	{
+		Platform:     PlatformDebianBullseye,
+		Builder:      "debian:bullseye",
+		OSVendors:    []string{"debian"},
+		OSVersion:    "11",
+		Codename:     "bullseye",
+		AptPrefix:    "debian",
+		Format:       FormatDeb,
+		BuilderImage: "ghcr.io/pgxman/builder/debian/bullseye",
+		BakeTarget:   "debian-bullseye",
+		aptBuilder:   func(ebs *ExtensionBuilders) **AptExtensionBuilder { return &ebs.DebianBullseye },
+	},
+	{
+		Platform:     PlatformDebianBookworm,
+		Builder:      "debian:bookworm",
+		OSVendors:    []string{"debian"},
+		OSVersion:    "12",
+		Codename:     "bookworm",
+		AptPrefix:    "debian",
+		Format:       FormatDeb,
+		BuilderImage: "ghcr.io/pgxman/builder/debian/bookworm",
+		BakeTarget:   "debian-bookworm",
+		Default:      true,
+		aptBuilder:   func(ebs *ExtensionBuilders) **AptExtensionBuilder { return &ebs.DebianBookworm },
+	},
+	{
+		Platform:     PlatformUbuntuFocal,
+		Builder:      "ubuntu:focal",
+		OSVendors:    []string{"ubuntu"},
+		OSVersion:    "20.04",
+		Codename:     "focal",
+		AptPrefix:    "ubuntu",
+		Format:       FormatDeb,
+		BuilderImage: "ghcr.io/pgxman/builder/ubuntu/focal",
+		BakeTarget:   "ubuntu-focal",
+		aptBuilder:   func(ebs *ExtensionBuilders) **AptExtensionBuilder { return &ebs.UbuntuFocal },
+	},
+	{
+		Platform:     PlatformUbuntuJammy,
+		Builder:      "ubuntu:jammy",
+		OSVendors:    []string{"ubuntu"},
+		OSVersion:    "22.04",
+		Codename:     "jammy",
+		AptPrefix:    "ubuntu",
+		Format:       FormatDeb,
+		BuilderImage: "ghcr.io/pgxman/builder/ubuntu/jammy",
+		BakeTarget:   "ubuntu-jammy",
+		Default:      true,
+		aptBuilder:   func(ebs *ExtensionBuilders) **AptExtensionBuilder { return &ebs.UbuntuJammy },
+	},
+	{
+		Platform:     PlatformUbuntuNoble,
+		Builder:      "ubuntu:noble",
+		OSVendors:    []string{"ubuntu"},
+		OSVersion:    "24.04",
+		Codename:     "noble",
+		AptPrefix:    "ubuntu",
+		Format:       FormatDeb,
+		BuilderImage: "ghcr.io/pgxman/builder/ubuntu/noble",
+		BakeTarget:   "ubuntu-noble",
+		Default:      true,
+		aptBuilder:   func(ebs *ExtensionBuilders) **AptExtensionBuilder { return &ebs.UbuntuNoble },
+	},
+	{
+		Platform: PlatformRockyLinux9,
+		Builder:  "rockylinux:9",
+		// RHEL-compatible releases are binary compatible within a major version
+		OSVendors:    []string{"rocky", "rhel", "almalinux"},
+		OSVersion:    "9",
+		Codename:     "9",
+		Format:       FormatRpm,
+		BuilderImage: "ghcr.io/pgxman/builder/rockylinux/9",
+		BakeTarget:   "rockylinux-9",
+		rpmBuilder:   func(ebs *ExtensionBuilders) **RpmExtensionBuilder { return &ebs.RockyLinux9 },
+	},
+}
+
+// Info returns the platform info of the platform.
+func (p Platform) Info() (PlatformInfo, bool) {
+	for _, info := range Platforms {
+		if info.Platform == p {
+			return info, true
+		}
+	}
+
+	return PlatformInfo{}, false
+}
+
+// Format returns the package format that is built and installed on the platform.
+func (p Platform) Format() Format {
+	if info, ok := p.Info(); ok {
+		return info.Format
+	}
+
+	return FormatDeb
+}
+
+func DetectPlatform() (Platform, error) {
+	info := osx.Sysinfo()
+
+	var (
+		vendor  = info.OS.Vendor
+		version = info.OS.Version
+	)
+
+	if vendor == "" {
+		vendor = runtime.GOOS
+	}
+
+	for _, p := range Platforms {
+		if p.matchOS(vendor, version) {
+			return p.Platform, nil
+		}
+	}
+
+	if vendor == "darwin" {
+		return PlatformDarwin, nil
+	}
+
+	return PlatformUnsupported, &ErrUnsupportedPlatform{osVendor: vendor, osVersion: version}
+}
+
+type ErrUnsupportedPlatform struct {
+	osVendor  string
+	osVersion string
+}
+
+func (e *ErrUnsupportedPlatform) Error() string {
+	builder := e.osVendor
+	if e.osVersion != "" {
+		builder += ":" + e.osVersion
+	}
+
+	return fmt.Sprintf("Unsupported platform: %s", builder)
+}
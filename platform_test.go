@@ -0,0 +1,96 @@
+package pgxman
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformInfo_matchOS(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Vendor  string
+		Version string
+		Want    Platform
+	}{
+		{
+			Name:    "debian 11",
+			Vendor:  "debian",
+			Version: "11",
+			Want:    PlatformDebianBullseye,
+		},
+		{
+			Name:    "debian 12",
+			Vendor:  "debian",
+			Version: "12",
+			Want:    PlatformDebianBookworm,
+		},
+		{
+			Name:    "ubuntu 20.04",
+			Vendor:  "ubuntu",
+			Version: "20.04",
+			Want:    PlatformUbuntuFocal,
+		},
+		{
+			Name:    "ubuntu 24.04",
+			Vendor:  "ubuntu",
+			Version: "24.04",
+			Want:    PlatformUbuntuNoble,
+		},
+		{
+			Name:    "rhel point release",
+			Vendor:  "rhel",
+			Version: "9.4",
+			Want:    PlatformRockyLinux9,
+		},
+		{
+			Name:    "prefix of another version",
+			Vendor:  "rocky",
+			Version: "90",
+			Want:    PlatformUnsupported,
+		},
+		{
+			Name:    "unsupported version",
+			Vendor:  "ubuntu",
+			Version: "18.04",
+			Want:    PlatformUnsupported,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			got := PlatformUnsupported
+			for _, info := range Platforms {
+				if info.matchOS(c.Vendor, c.Version) {
+					got = info.Platform
+					break
+				}
+			}
+
+			assert.Equal(t, c.Want, got)
+		})
+	}
+}
+
+func TestPlatforms(t *testing.T) {
+	assert := assert.New(t)
+
+	// every platform has a builder field in ExtensionBuilders
+	for _, info := range Platforms {
+		var ebs ExtensionBuilders
+		ebs.setBuilder(info.Platform, ExtensionBuilder{RunDependencies: []string{"libc6"}})
+		assert.Equal([]string{"libc6"}, ebs.Builder(info.Platform).RunDependencies, info.Platform)
+		assert.Equal(info.Format == FormatDeb, ebs.AptBuilder(info.Platform) != nil, info.Platform)
+		assert.Len(ebs.Available(), 1, info.Platform)
+		assert.Equal(info.Platform, ebs.Available()[0].Type)
+		assert.Equal(info.BuilderImage, ebs.Available()[0].Image)
+
+		ebs.RemoveBuilder(info.Platform)
+		assert.False(ebs.HasBuilder(info.Platform), info.Platform)
+	}
+
+	assert.Equal(FormatRpm, PlatformRockyLinux9.Format())
+	assert.Equal(FormatDeb, PlatformUbuntuFocal.Format())
+	assert.Equal("debian/bullseye", PlatformInfo{Builder: "debian:bullseye"}.ArtifactDir())
+}